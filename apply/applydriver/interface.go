@@ -16,5 +16,11 @@ package applydriver
 
 type Interface interface {
 	Apply() error
+	// Plan compute what Apply would do without touching any host.
+	Plan() (*Plan, error)
 	Delete() error
+	// Rollback roll back the upgrade to the previous ClusterImage, and restore etcd if restoreEtcd is true.
+	Rollback(restoreEtcd bool) error
+	// Repair re-provision the broken master or worker in place with the same role, labels and taints.
+	Repair(host string) error
 }
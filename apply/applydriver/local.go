@@ -21,6 +21,7 @@ import (
 	"github.com/alibaba/sealer/common"
 	"github.com/alibaba/sealer/logger"
 	"github.com/alibaba/sealer/pkg/clusterfile"
+	"github.com/alibaba/sealer/pkg/env"
 	"github.com/alibaba/sealer/pkg/filesystem/cloudimage"
 	"github.com/alibaba/sealer/pkg/image/store"
 	"github.com/alibaba/sealer/pkg/runtime"
@@ -48,6 +49,9 @@ type Applier struct {
 }
 
 func (c *Applier) Delete() (err error) {
+	if err = env.Validate(c.ClusterDesired); err != nil {
+		return err
+	}
 	t := metav1.Now()
 	c.ClusterDesired.DeletionTimestamp = &t
 	return c.deleteCluster()
@@ -55,16 +59,17 @@ func (c *Applier) Delete() (err error) {
 
 // Apply different actions between ClusterDesired and ClusterCurrent.
 func (c *Applier) Apply() (err error) {
-	// first time to init cluster
-	if c.ClusterFile == nil {
-		c.ClusterFile = clusterfile.NewClusterFile(c.ClusterDesired.GetAnnotationsByKey(common.ClusterfileName))
-		if path := c.ClusterDesired.GetAnnotationsByKey(common.ClusterfileName); path != "" {
-			if err = c.ClusterFile.Process(); err != nil {
-				return err
-			}
-		}
+	if err = c.initClusterFile(); err != nil {
+		return err
+	}
+	c.fillClusterStatus()
+	if err = env.Validate(c.ClusterDesired); err != nil {
+		return err
+	}
+	if err = c.checkResume(); err != nil {
+		return err
 	}
-	if !utils.IsFileExist(common.DefaultKubeConfigFile()) {
+	if c.needInit() {
 		if err = c.initCluster(); err != nil {
 			return err
 		}
@@ -77,6 +82,39 @@ func (c *Applier) Apply() (err error) {
 	return utils.SaveClusterInfoToFile(c.ClusterDesired, c.ClusterDesired.Name)
 }
 
+func (c *Applier) initClusterFile() error {
+	// first time to init cluster
+	if c.ClusterFile == nil {
+		c.ClusterFile = clusterfile.NewClusterFile(c.ClusterDesired.GetAnnotationsByKey(common.ClusterfileName))
+		if path := c.ClusterDesired.GetAnnotationsByKey(common.ClusterfileName); path != "" {
+			if err := c.ClusterFile.Process(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// needInit return true if the cluster has not been created,
+// an unfinished cluster creation has checkpoint, resume it even the kubeconfig has been fetched.
+func (c *Applier) needInit() bool {
+	return !utils.IsFileExist(common.DefaultKubeConfigFile()) || processor.HasCheckpoint(c.ClusterDesired.Name)
+}
+
+// checkResume return error if --from-phase is set but there is no unfinished cluster creation to resume.
+func (c *Applier) checkResume() error {
+	if processor.HasCheckpoint(c.ClusterDesired.Name) {
+		return nil
+	}
+	if processor.FromPhase != "" {
+		return fmt.Errorf("no unfinished creation of cluster %s to resume from phase %s", c.ClusterDesired.Name, processor.FromPhase)
+	}
+	if processor.Restart {
+		logger.Warn("no unfinished creation of cluster %s to restart, --restart is ignored", c.ClusterDesired.Name)
+	}
+	return nil
+}
+
 func (c *Applier) fillClusterCurrent() error {
 	currentCluster, err := GetCurrentCluster(c.Client)
 	if err != nil {
@@ -84,11 +122,52 @@ func (c *Applier) fillClusterCurrent() error {
 	}
 	if currentCluster != nil {
 		c.ClusterCurrent = c.ClusterDesired.DeepCopy()
-		c.ClusterCurrent.Spec.Hosts = currentCluster.Spec.Hosts
+		c.ClusterCurrent.Spec.Hosts = append(currentCluster.Spec.Hosts, c.currentEtcdHosts()...)
 	}
 	return nil
 }
 
+// currentEtcdHosts return the external etcd hosts saved by last apply, they are not kubernetes nodes.
+func (c *Applier) currentEtcdHosts() []v2.Host {
+	workClusterfile := common.GetClusterWorkClusterfile(c.ClusterDesired.Name)
+	cluster := c.ClusterDesired
+	if utils.IsFileExist(workClusterfile) {
+		saved, err := clusterfile.GetClusterFromFile(workClusterfile)
+		if err != nil {
+			logger.Warn("failed to load the saved etcd hosts, keep the desired ones: %v", err)
+		} else {
+			cluster = saved
+		}
+	}
+	var hosts []v2.Host
+	for _, ip := range cluster.GetEtcdIPList() {
+		hosts = append(hosts, v2.Host{IPS: []string{ip}, Roles: []string{common.ETCD}})
+	}
+	return hosts
+}
+
+// fillClusterStatus inherit the status saved by last apply, the desired cluster decoded from user Clusterfile has no status.
+func (c *Applier) fillClusterStatus() {
+	workClusterfile := common.GetClusterWorkClusterfile(c.ClusterDesired.Name)
+	if !utils.IsFileExist(workClusterfile) {
+		return
+	}
+	cluster, err := clusterfile.GetClusterFromFile(workClusterfile)
+	if err != nil {
+		logger.Warn("failed to load cluster status: %v", err)
+		return
+	}
+	// the ClusterImage of app does not carry the cluster runtime, keep the recorded one.
+	if clusterRuntime := cluster.GetAnnotationsByKey(common.ClusterRuntime); clusterRuntime != "" &&
+		c.ClusterDesired.GetAnnotationsByKey(common.ClusterRuntime) == "" {
+		c.ClusterDesired.SetAnnotations(common.ClusterRuntime, clusterRuntime)
+	}
+	if len(c.ClusterDesired.Status.Hosts) != 0 || len(c.ClusterDesired.Status.Conditions) != 0 {
+		return
+	}
+	c.ClusterDesired.Status = cluster.Status
+}
+
 func (c *Applier) mountClusterImage() error {
 	imageName := c.ClusterDesired.Spec.Image
 	err := c.ImageManager.PullIfNotExist(imageName)
@@ -142,8 +221,9 @@ func (c *Applier) reconcileCluster() error {
 
 	mj, md := utils.GetDiffHosts(c.ClusterCurrent.GetMasterIPList(), c.ClusterDesired.GetMasterIPList())
 	nj, nd := utils.GetDiffHosts(c.ClusterCurrent.GetNodeIPList(), c.ClusterDesired.GetNodeIPList())
+	ej, ed := utils.GetDiffHosts(c.ClusterCurrent.GetEtcdIPList(), c.ClusterDesired.GetEtcdIPList())
 
-	if err := c.scaleCluster(mj, md, nj, nd); err != nil {
+	if err := c.scaleCluster(mj, md, nj, nd, ej, ed); err != nil {
 		return err
 	}
 
@@ -153,15 +233,15 @@ func (c *Applier) reconcileCluster() error {
 	return nil
 }
 
-func (c *Applier) scaleCluster(mj, md, nj, nd []string) error {
-	if len(mj) == 0 && len(md) == 0 && len(nj) == 0 && len(nd) == 0 {
+func (c *Applier) scaleCluster(mj, md, nj, nd, ej, ed []string) error {
+	if len(mj) == 0 && len(md) == 0 && len(nj) == 0 && len(nd) == 0 && len(ej) == 0 && len(ed) == 0 {
 		return nil
 	}
 
 	logger.Info("Start to scale this cluster")
-	logger.Debug("current cluster: master %s, worker %s", c.ClusterCurrent.GetMasterIPList(), c.ClusterCurrent.GetNodeIPList())
+	logger.Debug("current cluster: master %s, worker %s, etcd %s", c.ClusterCurrent.GetMasterIPList(), c.ClusterCurrent.GetNodeIPList(), c.ClusterCurrent.GetEtcdIPList())
 
-	scaleProcessor, err := processor.NewScaleProcessor(c.ClusterFile.GetKubeadmConfig(), common.DefaultTheClusterRootfsDir(c.ClusterDesired.Name), mj, md, nj, nd)
+	scaleProcessor, err := processor.NewScaleProcessor(c.ClusterFile.GetKubeadmConfig(), common.DefaultTheClusterRootfsDir(c.ClusterDesired.Name), mj, md, nj, nd, ej, ed)
 	if err != nil {
 		return err
 	}
@@ -177,6 +257,8 @@ func (c *Applier) scaleCluster(mj, md, nj, nd []string) error {
 		cluster = c.ClusterDesired
 	}
 	err = scaleProcessor.Execute(cluster)
+	// scale down is executed on the saved cluster, keep the status it recorded.
+	c.ClusterDesired.Status = cluster.Status
 	if err != nil {
 		return err
 	}
@@ -187,8 +269,6 @@ func (c *Applier) scaleCluster(mj, md, nj, nd []string) error {
 }
 
 func (c *Applier) upgradeCluster(mj, nj []string) error {
-	// use k8sClient to fetch current cluster version.
-	info := c.CurrentClusterInfo
 	// fetch form exec machine
 	runtimeInterface, err := runtime.NewDefaultRuntime(c.ClusterDesired, c.ClusterFile.GetKubeadmConfig())
 	if err != nil {
@@ -199,11 +279,20 @@ func (c *Applier) upgradeCluster(mj, nj []string) error {
 		return fmt.Errorf("failed to get cluster metadata: %v", err)
 	}
 
-	if info.GitVersion == clusterMetadata.Version {
+	// the apiserver of master0 is upgraded first, the nodes left by a failed upgrade still run the previous kubelet.
+	current, err := runtime.GetClusterKubeletVersion(c.Client)
+	if err != nil {
+		return err
+	}
+	if current == clusterMetadata.Version && !c.upgradeUnfinished() {
 		return nil
 	}
+	if err := runtime.ValidateUpgradeVersion(current, clusterMetadata.Version); err != nil {
+		return err
+	}
+	c.setPreviousClusterImage()
 
-	logger.Info("Start to upgrade this cluster from version(%s) to version(%s)", info.GitVersion, clusterMetadata.Version)
+	logger.Info("Start to upgrade this cluster from version(%s) to version(%s)", current, clusterMetadata.Version)
 	upgradeProcessor, err := processor.NewUpgradeProcessor(common.DefaultMountCloudImageDir(c.ClusterDesired.Name), runtimeInterface, mj, nj)
 	if err != nil {
 		return err
@@ -213,8 +302,105 @@ func (c *Applier) upgradeCluster(mj, nj []string) error {
 		return err
 	}
 
-	logger.Info("Succeeded in upgrading current cluster from version(%s) to version(%s)", info.GitVersion, clusterMetadata.Version)
+	logger.Info("Succeeded in upgrading current cluster from version(%s) to version(%s)", current, clusterMetadata.Version)
+
+	return nil
+}
+
+// upgradeUnfinished check the saved status, the last upgrade failed if its condition is false.
+func (c *Applier) upgradeUnfinished() bool {
+	cond := c.ClusterDesired.Status.GetCondition(v2.ConditionUpgraded)
+	return cond != nil && cond.Status == v2.ConditionFalse
+}
+
+// setPreviousClusterImage record the image of the saved cluster before upgrade, the rerun of a failed upgrade keeps the recorded one.
+func (c *Applier) setPreviousClusterImage() {
+	workClusterfile := common.GetClusterWorkClusterfile(c.ClusterDesired.Name)
+	if !utils.IsFileExist(workClusterfile) {
+		return
+	}
+	saved, err := clusterfile.GetClusterFromFile(workClusterfile)
+	if err != nil {
+		logger.Warn("failed to load the saved cluster, the upgrade could not be rolled back: %v", err)
+		return
+	}
+	previous := saved.Spec.Image
+	if previous == c.ClusterDesired.Spec.Image {
+		previous = saved.GetAnnotationsByKey(common.PreviousClusterImage)
+	}
+	if previous != "" {
+		c.ClusterDesired.SetAnnotations(common.PreviousClusterImage, previous)
+	}
+}
+
+// Rollback roll back the upgrade to the previous ClusterImage recorded by upgrade, the image of cluster is set back after it succeeded.
+func (c *Applier) Rollback(restoreEtcd bool) error {
+	if err := c.initClusterFile(); err != nil {
+		return err
+	}
+	previous := c.ClusterDesired.GetAnnotationsByKey(common.PreviousClusterImage)
+	if previous == "" {
+		return fmt.Errorf("cluster %s has not been upgraded, no ClusterImage to roll back to", c.ClusterDesired.Name)
+	}
+	current := c.ClusterDesired.Spec.Image
+	c.ClusterDesired.Spec.Image = previous
+	if err := c.mountClusterImage(); err != nil {
+		return err
+	}
+	defer func() {
+		if err := c.unMountClusterImage(); err != nil {
+			logger.Warn("failed to umount image %s, %v", c.ClusterDesired.ClusterName, err)
+		}
+	}()
+
+	runtimeInterface, err := runtime.NewDefaultRuntime(c.ClusterDesired, c.ClusterFile.GetKubeadmConfig())
+	if err != nil {
+		return fmt.Errorf("failed to init runtime, %v", err)
+	}
+	logger.Info("Start to roll back this cluster from %s to %s", current, previous)
+	rollbackProcessor, err := processor.NewRollbackProcessor(common.DefaultMountCloudImageDir(c.ClusterDesired.Name), runtimeInterface, restoreEtcd)
+	if err != nil {
+		return err
+	}
+	if err := rollbackProcessor.Execute(c.ClusterDesired); err != nil {
+		return err
+	}
+	delete(c.ClusterDesired.Annotations, common.PreviousClusterImage)
+	logger.Info("Succeeded in rolling back this cluster to %s", previous)
+	return utils.SaveClusterInfoToFile(c.ClusterDesired, c.ClusterDesired.Name)
+}
+
+// Repair remove the broken host from the cluster, reset it if reachable, then mount rootfs and join it again.
+func (c *Applier) Repair(host string) error {
+	if err := c.initClusterFile(); err != nil {
+		return err
+	}
+	if err := c.mountClusterImage(); err != nil {
+		return err
+	}
+	defer func() {
+		if err := c.unMountClusterImage(); err != nil {
+			logger.Warn("failed to umount image %s, %v", c.ClusterDesired.ClusterName, err)
+		}
+	}()
 
+	runtimeInterface, err := runtime.NewDefaultRuntime(c.ClusterDesired, c.ClusterFile.GetKubeadmConfig())
+	if err != nil {
+		return fmt.Errorf("failed to init runtime, %v", err)
+	}
+	repairProcessor, err := processor.NewRepairProcessor(common.DefaultMountCloudImageDir(c.ClusterDesired.Name), runtimeInterface, host)
+	if err != nil {
+		return err
+	}
+	err = repairProcessor.Execute(c.ClusterDesired)
+	// master0 may be moved off the repaired host, the cluster is saved even if the repair failed.
+	if saveErr := utils.SaveClusterInfoToFile(c.ClusterDesired, c.ClusterDesired.Name); saveErr != nil {
+		logger.Warn("failed to save cluster: %v", saveErr)
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("Succeeded in repairing %s", host)
 	return nil
 }
 
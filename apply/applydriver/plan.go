@@ -0,0 +1,328 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applydriver
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/client/k8s"
+	"github.com/alibaba/sealer/pkg/config"
+	"github.com/alibaba/sealer/pkg/plugin"
+	"github.com/alibaba/sealer/pkg/runtime"
+	v1 "github.com/alibaba/sealer/types/api/v1"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+	"github.com/alibaba/sealer/utils"
+)
+
+const (
+	ConfigActionAdd    = "add"
+	ConfigActionModify = "modify"
+)
+
+var (
+	// phases of plugins run by the create pipeline.
+	createPluginPhases = []plugin.Phase{plugin.PhaseOriginally, plugin.PhasePreInit, plugin.PhasePreGuest, plugin.PhasePostInstall}
+	// phases of plugins run by the install pipeline of app image.
+	installPluginPhases = []plugin.Phase{plugin.PhasePreGuest, plugin.PhasePostInstall}
+)
+
+// Plan is the actions Apply would take to make the current cluster to be the desired one.
+type Plan struct {
+	ClusterName string
+	// Create is true if the cluster will be created (or the unfinished creation will be resumed).
+	Create bool
+	// InstallApp is true if the image is an app image, which will be installed on the current cluster.
+	InstallApp      bool
+	MastersToJoin   []string
+	MastersToDelete []string
+	NodesToJoin     []string
+	NodesToDelete   []string
+	EtcdsToJoin     []string
+	EtcdsToDelete   []string
+	CurrentVersion  string
+	DesiredVersion  string
+	// UpgradeMasters and UpgradeNodes are upgraded one by one in order, after etcd is snapshotted on EtcdSnapshotHost.
+	UpgradeMasters   []string
+	UpgradeNodes     []string
+	EtcdSnapshotHost string
+	Configs          []ConfigChange
+	Plugins          []PluginRun
+}
+
+// ConfigChange is a Config file of Clusterfile which will be written to the cluster rootfs.
+type ConfigChange struct {
+	Name   string
+	Path   string
+	Action string
+}
+
+// PluginRun is a plugin which will be run in the phase.
+type PluginRun struct {
+	Phase plugin.Phase
+	Name  string
+	Type  string
+	On    string
+}
+
+func (p *Plan) IsUpgrade() bool {
+	return !p.Create && !p.InstallApp && p.DesiredVersion != "" && p.CurrentVersion != p.DesiredVersion
+}
+
+func (p *Plan) IsScale() bool {
+	return len(p.MastersToJoin) != 0 || len(p.MastersToDelete) != 0 || len(p.NodesToJoin) != 0 || len(p.NodesToDelete) != 0 ||
+		len(p.EtcdsToJoin) != 0 || len(p.EtcdsToDelete) != 0
+}
+
+// Print the plan as a table of actions.
+func (p *Plan) Print(w io.Writer) {
+	if !p.Create && !p.InstallApp && !p.IsScale() && !p.IsUpgrade() {
+		fmt.Fprintf(w, "cluster %s is up to date, no changes to hosts\n", p.ClusterName)
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"ACTION", "TARGET", "DETAIL"})
+	if p.Create {
+		table.Append([]string{"create cluster", p.ClusterName, p.DesiredVersion})
+	}
+	if p.InstallApp {
+		table.Append([]string{"install app", p.ClusterName, ""})
+	}
+	appendHosts := func(action string, hosts []string) {
+		for _, host := range hosts {
+			table.Append([]string{action, host, ""})
+		}
+	}
+	appendHosts("join etcd", p.EtcdsToJoin)
+	appendHosts("join master", p.MastersToJoin)
+	appendHosts("join node", p.NodesToJoin)
+	appendHosts("delete master", p.MastersToDelete)
+	appendHosts("delete node", p.NodesToDelete)
+	appendHosts("delete etcd", p.EtcdsToDelete)
+	if p.IsUpgrade() {
+		table.Append([]string{"upgrade cluster", p.ClusterName, fmt.Sprintf("%s -> %s", p.CurrentVersion, p.DesiredVersion)})
+		table.Append([]string{"snapshot etcd", p.EtcdSnapshotHost, "before upgrade"})
+		drain := "drain" + runtime.Drain.Args(p.DesiredVersion)
+		for i, host := range p.UpgradeMasters {
+			upgrade := "kubeadm upgrade node"
+			if i == 0 {
+				upgrade = fmt.Sprintf("kubeadm upgrade apply %s", p.DesiredVersion)
+			}
+			table.Append([]string{"upgrade master", host, fmt.Sprintf("%s, %s, wait Ready, uncordon", drain, upgrade)})
+		}
+		for _, host := range p.UpgradeNodes {
+			table.Append([]string{"upgrade node", host, fmt.Sprintf("%s, kubeadm upgrade node, wait Ready, uncordon", drain)})
+		}
+	}
+	for _, cfg := range p.Configs {
+		table.Append([]string{cfg.Action + " config", cfg.Path, cfg.Name})
+	}
+	for _, pr := range p.Plugins {
+		detail := fmt.Sprintf("%s(%s)", pr.Name, pr.Type)
+		if pr.On != "" {
+			detail = fmt.Sprintf("%s on %s", detail, pr.On)
+		}
+		table.Append([]string{"run plugin", string(pr.Phase), detail})
+	}
+	table.Render()
+}
+
+// diffHosts fill the hosts to join and delete, the same diff as reconcileCluster.
+func (p *Plan) diffHosts(current, desired *v2.Cluster) {
+	if current == nil {
+		p.MastersToJoin, p.NodesToJoin, p.EtcdsToJoin = desired.GetMasterIPList(), desired.GetNodeIPList(), desired.GetEtcdIPList()
+		return
+	}
+	p.MastersToJoin, p.MastersToDelete = utils.GetDiffHosts(current.GetMasterIPList(), desired.GetMasterIPList())
+	p.NodesToJoin, p.NodesToDelete = utils.GetDiffHosts(current.GetNodeIPList(), desired.GetNodeIPList())
+	p.EtcdsToJoin, p.EtcdsToDelete = utils.GetDiffHosts(current.GetEtcdIPList(), desired.GetEtcdIPList())
+}
+
+// planUpgrade fill the hosts upgraded in order and the host etcd is snapshotted on, like the upgrade of runtime does.
+func (p *Plan) planUpgrade(desired *v2.Cluster) {
+	p.UpgradeMasters, p.UpgradeNodes = desired.GetMasterIPList(), desired.GetNodeIPList()
+	p.EtcdSnapshotHost = desired.GetMaster0IP()
+	if etcds := desired.GetEtcdIPList(); len(etcds) != 0 {
+		p.EtcdSnapshotHost = etcds[0]
+	}
+}
+
+// Plan compute the actions of Apply against the saved cluster and the live kube client, no host is touched.
+// The cluster image is mounted on local host to read its metadata, Config files and plugins.
+func (c *Applier) Plan() (*Plan, error) {
+	if err := c.initClusterFile(); err != nil {
+		return nil, err
+	}
+	plan := &Plan{ClusterName: c.ClusterDesired.Name}
+
+	if c.needInit() {
+		plan.Create = true
+		plan.diffHosts(nil, c.ClusterDesired)
+	} else {
+		client, err := k8s.Newk8sClient()
+		if err != nil {
+			return nil, err
+		}
+		c.Client = client
+		current, err := runtime.GetClusterKubeletVersion(c.Client)
+		if err != nil {
+			return nil, err
+		}
+		plan.CurrentVersion = current
+		if err := c.fillClusterCurrent(); err != nil {
+			return nil, err
+		}
+		plan.diffHosts(c.ClusterCurrent, c.ClusterDesired)
+	}
+
+	if err := c.mountClusterImage(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := c.unMountClusterImage(); err != nil {
+			logger.Warn("failed to umount image %s, %v", c.ClusterDesired.ClusterName, err)
+		}
+	}()
+
+	baseImage, err := c.ImageStore.GetByName(c.ClusterDesired.Spec.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base image err: %s", err)
+	}
+	phases := createPluginPhases
+	if !plan.Create && baseImage.Spec.ImageConfig.ImageType == common.AppImage {
+		// app image is installed directly, hosts are not reconciled.
+		plan.InstallApp = true
+		plan.MastersToJoin, plan.MastersToDelete, plan.NodesToJoin, plan.NodesToDelete = nil, nil, nil, nil
+		plan.EtcdsToJoin, plan.EtcdsToDelete = nil, nil
+		phases = installPluginPhases
+	} else {
+		runtimeInterface, err := runtime.NewDefaultRuntime(c.ClusterDesired, c.ClusterFile.GetKubeadmConfig())
+		if err != nil {
+			return nil, fmt.Errorf("failed to init runtime, %v", err)
+		}
+		clusterMetadata, err := runtimeInterface.GetClusterMetadata()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cluster metadata: %v", err)
+		}
+		plan.DesiredVersion = clusterMetadata.Version
+		if plan.IsUpgrade() {
+			if err := runtime.ValidateUpgradeVersion(plan.CurrentVersion, plan.DesiredVersion); err != nil {
+				return nil, err
+			}
+			plan.planUpgrade(c.ClusterDesired)
+		}
+	}
+
+	if plan.Configs, err = c.planConfigs(); err != nil {
+		return nil, err
+	}
+	// scale and upgrade do not run any plugin.
+	if plan.Create || plan.InstallApp {
+		if plan.Plugins, err = c.planPlugins(phases); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// planConfigs compare the Config files with the ones in the cluster rootfs of local host,
+// fall back to the ones in the cluster image if the rootfs not exist.
+func (c *Applier) planConfigs() ([]ConfigChange, error) {
+	var changes []ConfigChange
+	for _, cfg := range c.ClusterFile.GetConfigs() {
+		imagePath, data, err := config.RenderConfig(c.ClusterDesired.Name, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render config %s: %v", cfg.Name, err)
+		}
+		path := filepath.Join(common.DefaultTheClusterRootfsDir(c.ClusterDesired.Name), cfg.Spec.Path)
+		if !utils.IsFileExist(path) {
+			path = imagePath
+		}
+		change := ConfigChange{Name: cfg.Name, Path: cfg.Spec.Path}
+		if !utils.IsFileExist(path) {
+			change.Action = ConfigActionAdd
+			changes = append(changes, change)
+			continue
+		}
+		current, err := ioutil.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(current, data) {
+			change.Action = ConfigActionModify
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// planPlugins list the plugins of cluster image and Clusterfile in the order of phases,
+// the plugin of Clusterfile overwrite the one of cluster image with the same name, like Dump does.
+func (c *Applier) planPlugins(phases []plugin.Phase) ([]PluginRun, error) {
+	var plugins []v1.Plugin
+	pluginDir := filepath.Join(common.DefaultMountCloudImageDir(c.ClusterDesired.Name), "plugins")
+	if utils.IsExist(pluginDir) {
+		files, err := ioutil.ReadDir(pluginDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load plugin dir %v", err)
+		}
+		for _, f := range files {
+			if !utils.YamlMatcher(f.Name()) {
+				continue
+			}
+			ps, err := utils.DecodePlugins(filepath.Join(pluginDir, f.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to load plugin %v", err)
+			}
+			plugins = append(plugins, ps...)
+		}
+	}
+	plugins = mergePlugins(plugins, c.ClusterFile.GetPlugins())
+
+	var runs []PluginRun
+	for _, phase := range phases {
+		for _, p := range plugins {
+			if p.Spec.Action != string(phase) {
+				continue
+			}
+			runs = append(runs, PluginRun{Phase: phase, Name: p.Name, Type: p.Spec.Type, On: p.Spec.On})
+		}
+	}
+	return runs, nil
+}
+
+func mergePlugins(base, overlay []v1.Plugin) []v1.Plugin {
+	var merged []v1.Plugin
+	for _, p := range base {
+		overwritten := false
+		for _, o := range overlay {
+			if o.Name == p.Name {
+				overwritten = true
+				break
+			}
+		}
+		if !overwritten {
+			merged = append(merged, p)
+		}
+	}
+	return append(merged, overlay...)
+}
@@ -0,0 +1,122 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applydriver
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alibaba/sealer/common"
+	v1 "github.com/alibaba/sealer/types/api/v1"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+func newTestCluster(masters, nodes []string) *v2.Cluster {
+	cluster := &v2.Cluster{}
+	cluster.Name = "my-cluster"
+	cluster.Spec.Hosts = []v2.Host{
+		{IPS: masters, Roles: []string{common.MASTER}},
+		{IPS: nodes, Roles: []string{common.NODE}},
+	}
+	return cluster
+}
+
+func TestPlan_diffHosts(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     *v2.Cluster
+		desired     *v2.Cluster
+		wantMJ      []string
+		wantMD      []string
+		wantNJ      []string
+		wantND      []string
+		wantChanged bool
+	}{
+		{
+			name:        "create",
+			current:     nil,
+			desired:     newTestCluster([]string{"192.168.0.2"}, []string{"192.168.0.3"}),
+			wantMJ:      []string{"192.168.0.2"},
+			wantNJ:      []string{"192.168.0.3"},
+			wantChanged: true,
+		},
+		{
+			name:        "scale",
+			current:     newTestCluster([]string{"192.168.0.2"}, []string{"192.168.0.3", "192.168.0.4"}),
+			desired:     newTestCluster([]string{"192.168.0.2", "192.168.0.5"}, []string{"192.168.0.3"}),
+			wantMJ:      []string{"192.168.0.5"},
+			wantND:      []string{"192.168.0.4"},
+			wantChanged: true,
+		},
+		{
+			name:        "no change",
+			current:     newTestCluster([]string{"192.168.0.2"}, []string{"192.168.0.3"}),
+			desired:     newTestCluster([]string{"192.168.0.2"}, []string{"192.168.0.3"}),
+			wantChanged: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plan{}
+			p.diffHosts(tt.current, tt.desired)
+			if !reflect.DeepEqual(p.MastersToJoin, tt.wantMJ) || !reflect.DeepEqual(p.MastersToDelete, tt.wantMD) ||
+				!reflect.DeepEqual(p.NodesToJoin, tt.wantNJ) || !reflect.DeepEqual(p.NodesToDelete, tt.wantND) {
+				t.Errorf("diffHosts() got %v %v %v %v, want %v %v %v %v", p.MastersToJoin, p.MastersToDelete, p.NodesToJoin,
+					p.NodesToDelete, tt.wantMJ, tt.wantMD, tt.wantNJ, tt.wantND)
+			}
+			if p.IsScale() != tt.wantChanged {
+				t.Errorf("IsScale() = %v, want %v", p.IsScale(), tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestPlan_Print(t *testing.T) {
+	p := &Plan{
+		ClusterName:      "my-cluster",
+		NodesToJoin:      []string{"192.168.0.5"},
+		CurrentVersion:   "v1.19.8",
+		DesiredVersion:   "v1.20.4",
+		UpgradeMasters:   []string{"192.168.0.2"},
+		UpgradeNodes:     []string{"192.168.0.3"},
+		EtcdSnapshotHost: "192.168.0.2",
+		Configs:          []ConfigChange{{Name: "redis-config", Path: "etc/redis.yaml", Action: ConfigActionModify}},
+	}
+	buf := &bytes.Buffer{}
+	p.Print(buf)
+	for _, want := range []string{"join node", "192.168.0.5", "v1.19.8 -> v1.20.4", "snapshot etcd", "upgrade apply v1.20.4", "upgrade node", "modify config", "etc/redis.yaml"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Print() output %s, want contains %s", buf.String(), want)
+		}
+	}
+}
+
+func Test_mergePlugins(t *testing.T) {
+	newPlugin := func(name, action string) v1.Plugin {
+		p := v1.Plugin{}
+		p.Name = name
+		p.Spec.Action = action
+		return p
+	}
+	base := []v1.Plugin{newPlugin("hostname", "PreInit"), newPlugin("label", "PreGuest")}
+	overlay := []v1.Plugin{newPlugin("hostname", "PostInstall")}
+	got := mergePlugins(base, overlay)
+	want := []v1.Plugin{newPlugin("label", "PreGuest"), newPlugin("hostname", "PostInstall")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergePlugins() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/utils"
+)
+
+const checkpointFileName = "checkpoint"
+
+var (
+	// FromPhase re-run the create pipeline from the given phase, the checkpoints of it and later phases are dropped.
+	FromPhase string
+	// Restart drop all the checkpoints and re-run the create pipeline from the beginning.
+	Restart bool
+)
+
+// Checkpoint records the finished steps of a pipeline, so a failed apply can be resumed from the first unfinished step.
+type Checkpoint struct {
+	path string
+	// Steps is the finished steps of the pipeline.
+	Steps []string `json:"steps,omitempty"`
+	// Hosts is the finished hosts of steps which can be resumed per host, like MountRootfs and Join.
+	Hosts map[string][]string `json:"hosts,omitempty"`
+}
+
+func getCheckpointFile(clusterName string) string {
+	return filepath.Join(common.GetClusterWorkDir(clusterName), checkpointFileName)
+}
+
+// HasCheckpoint return true if the cluster has an unfinished create pipeline.
+func HasCheckpoint(clusterName string) bool {
+	return utils.IsFileExist(getCheckpointFile(clusterName))
+}
+
+// LoadCheckpoint load the checkpoint of cluster, return an empty one if not exist.
+func LoadCheckpoint(clusterName string) (*Checkpoint, error) {
+	cp := &Checkpoint{path: getCheckpointFile(clusterName)}
+	if !utils.IsFileExist(cp.path) {
+		return cp, nil
+	}
+	if err := utils.UnmarshalYamlFile(cp.path, cp); err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %v", err)
+	}
+	return cp, nil
+}
+
+func (c *Checkpoint) Save() error {
+	return utils.MarshalYamlToFile(c.path, c)
+}
+
+// Clean remove the checkpoint file, it should be called when the pipeline finished.
+func (c *Checkpoint) Clean() error {
+	c.Steps = nil
+	c.Hosts = nil
+	return utils.CleanFiles(c.path)
+}
+
+func (c *Checkpoint) IsStepDone(step string) bool {
+	return utils.InList(step, c.Steps)
+}
+
+func (c *Checkpoint) StepDone(step string) error {
+	if !c.IsStepDone(step) {
+		c.Steps = append(c.Steps, step)
+	}
+	return c.Save()
+}
+
+func (c *Checkpoint) HostsDone(step string, hosts []string) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+	if c.Hosts == nil {
+		c.Hosts = map[string][]string{}
+	}
+	c.Hosts[step] = utils.RemoveDuplicate(append(c.Hosts[step], hosts...))
+	return c.Save()
+}
+
+// PendingHosts return the hosts which have not finished the step.
+func (c *Checkpoint) PendingHosts(step string, hosts []string) []string {
+	var pending []string
+	for _, host := range hosts {
+		if utils.NotIn(host, c.Hosts[step]) {
+			pending = append(pending, host)
+		}
+	}
+	return pending
+}
+
+// ResetFrom drop the checkpoints of the given step and the steps after it.
+func (c *Checkpoint) ResetFrom(step string, steps []string) error {
+	index := -1
+	for i, s := range steps {
+		if s == step {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("phase %s not found, available phases: %v", step, steps)
+	}
+	c.Steps = utils.RemoveStrSlice(c.Steps, steps[index:])
+	for _, s := range steps[index:] {
+		delete(c.Hosts, s)
+	}
+	return c.Save()
+}
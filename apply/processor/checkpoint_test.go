@@ -0,0 +1,73 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/alibaba/sealer/utils"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sealer-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cp := &Checkpoint{path: filepath.Join(dir, checkpointFileName)}
+	if err := cp.StepDone(StepMountRootfs); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.StepDone(StepInit); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.HostsDone(StepJoin, []string{"192.168.0.3", "192.168.0.4"}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := &Checkpoint{path: cp.path}
+	if err := utils.UnmarshalYamlFile(cp.path, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.IsStepDone(StepInit) || loaded.IsStepDone(StepJoin) {
+		t.Errorf("loaded steps %v, want %v", loaded.Steps, []string{StepMountRootfs, StepInit})
+	}
+	pending := loaded.PendingHosts(StepJoin, []string{"192.168.0.3", "192.168.0.4", "192.168.0.5"})
+	if !reflect.DeepEqual(pending, []string{"192.168.0.5"}) {
+		t.Errorf("PendingHosts() = %v, want [192.168.0.5]", pending)
+	}
+
+	if err := loaded.ResetFrom(StepInit, createSteps); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Steps, []string{StepMountRootfs}) || len(loaded.Hosts[StepJoin]) != 0 {
+		t.Errorf("ResetFrom() got steps %v hosts %v, want only %s left", loaded.Steps, loaded.Hosts, StepMountRootfs)
+	}
+	if err := loaded.ResetFrom("NotExist", createSteps); err == nil {
+		t.Errorf("ResetFrom() with unknown phase should return error")
+	}
+
+	if err := loaded.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if utils.IsFileExist(cp.path) {
+		t.Errorf("checkpoint file should be removed after Clean()")
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/logger"
 	"github.com/alibaba/sealer/pkg/clusterfile"
 	"github.com/alibaba/sealer/pkg/config"
 	"github.com/alibaba/sealer/pkg/filesystem"
@@ -30,6 +31,25 @@ import (
 	"github.com/alibaba/sealer/utils"
 )
 
+const (
+	StepMountRootfs = "MountRootfs"
+	StepInit        = "Init"
+	StepJoin        = "Join"
+	StepRunGuest    = "RunGuest"
+)
+
+// createSteps is the steps of create pipeline recorded in checkpoint, the local steps like MountImage are always executed.
+var createSteps = []string{
+	string(plugin.PhaseOriginally),
+	StepMountRootfs,
+	string(plugin.PhasePreInit),
+	StepInit,
+	StepJoin,
+	string(plugin.PhasePreGuest),
+	StepRunGuest,
+	string(plugin.PhasePostInstall),
+}
+
 type CreateProcessor struct {
 	ClusterFile       clusterfile.Interface
 	ImageManager      image.Service
@@ -38,19 +58,19 @@ type CreateProcessor struct {
 	Guest             guest.Interface
 	Config            config.Interface
 	Plugins           plugin.Plugins
+	checkpoint        *Checkpoint
+	pluginsLoaded     bool
 }
 
 func (c *CreateProcessor) Execute(cluster *v2.Cluster) error {
-	runTime, err := runtime.NewDefaultRuntime(cluster, c.ClusterFile.GetKubeadmConfig())
-	if err != nil {
-		return fmt.Errorf("failed to init runtime, %v", err)
-	}
-	c.Runtime = runTime
 	c.Config = config.NewConfiguration(cluster.Name)
 	if err := c.initPlugin(cluster); err != nil {
 		return err
 	}
-	err = utils.SaveClusterInfoToFile(cluster, cluster.Name)
+	if err := c.initCheckpoint(cluster); err != nil {
+		return err
+	}
+	err := utils.SaveClusterInfoToFile(cluster, cluster.Name)
 	if err != nil {
 		return err
 	}
@@ -65,72 +85,128 @@ func (c *CreateProcessor) Execute(cluster *v2.Cluster) error {
 		}
 	}
 
-	return nil
+	return c.checkpoint.Clean()
 }
 func (c *CreateProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, error) {
 	var todoList []func(cluster *v2.Cluster) error
 	todoList = append(todoList,
-		c.GetPhasePluginFunc(plugin.PhaseOriginally),
+		c.withCheckpoint(string(plugin.PhaseOriginally), c.GetPhasePluginFunc(plugin.PhaseOriginally)),
 		c.MountImage,
 		c.RunConfig,
-		c.MountRootfs,
-		c.GetPhasePluginFunc(plugin.PhasePreInit),
-		c.Init,
-		c.Join,
-		c.GetPhasePluginFunc(plugin.PhasePreGuest),
-		c.RunGuest,
+		c.withCheckpoint(StepMountRootfs, c.MountRootfs),
+		c.withCheckpoint(string(plugin.PhasePreInit), c.GetPhasePluginFunc(plugin.PhasePreInit)),
+		c.withCheckpoint(StepInit, c.Init),
+		c.withCheckpoint(StepJoin, c.Join),
+		c.withCheckpoint(string(plugin.PhasePreGuest), c.GetPhasePluginFunc(plugin.PhasePreGuest)),
+		c.withCheckpoint(StepRunGuest, c.RunGuest),
 		c.UnMountImage,
-		c.GetPhasePluginFunc(plugin.PhasePostInstall),
+		c.withCheckpoint(string(plugin.PhasePostInstall), c.GetPhasePluginFunc(plugin.PhasePostInstall)),
 	)
 	return todoList, nil
 }
 
-func (c *CreateProcessor) MountImage(cluster *v2.Cluster) error {
-	err := c.ImageManager.PullIfNotExist(cluster.Spec.Image)
+func (c *CreateProcessor) initCheckpoint(cluster *v2.Cluster) error {
+	cp, err := LoadCheckpoint(cluster.Name)
 	if err != nil {
 		return err
 	}
+	c.checkpoint = cp
+	if Restart {
+		return c.checkpoint.Clean()
+	}
+	if FromPhase != "" {
+		return c.checkpoint.ResetFrom(FromPhase, createSteps)
+	}
+	if len(c.checkpoint.Steps) != 0 {
+		logger.Info("resume creating cluster, finished phases: %v", c.checkpoint.Steps)
+	}
+	return nil
+}
 
-	return c.cloudImageMounter.MountImage(cluster)
+// withCheckpoint skip the step if it has been finished, otherwise record it as finished after executed successfully.
+func (c *CreateProcessor) withCheckpoint(step string, f func(cluster *v2.Cluster) error) func(cluster *v2.Cluster) error {
+	return func(cluster *v2.Cluster) error {
+		if c.checkpoint.IsStepDone(step) {
+			logger.Info("skip phase %s, it has been finished", step)
+			return nil
+		}
+		if err := f(cluster); err != nil {
+			return err
+		}
+		return c.checkpoint.StepDone(step)
+	}
+}
+
+// MountImage mount the ClusterImage and create the runtime chosen by its Metadata.
+func (c *CreateProcessor) MountImage(cluster *v2.Cluster) error {
+	err := c.ImageManager.PullIfNotExist(cluster.Spec.Image)
+	if err == nil {
+		err = c.cloudImageMounter.MountImage(cluster)
+	}
+	if err := recordStatus(cluster, v2.ConditionImageMounted, nil, "", err); err != nil {
+		return err
+	}
+	runTime, err := runtime.NewDefaultRuntime(cluster, c.ClusterFile.GetKubeadmConfig())
+	if err != nil {
+		return fmt.Errorf("failed to init runtime, %v", err)
+	}
+	c.Runtime = runTime
+	return nil
 }
 
 func (c *CreateProcessor) RunConfig(cluster *v2.Cluster) error {
-	return c.Config.Dump(c.ClusterFile.GetConfigs())
+	err := c.Config.Dump(c.ClusterFile.GetConfigs())
+	return recordStatus(cluster, v2.ConditionConfigApplied, nil, "", err)
 }
 
 func (c *CreateProcessor) MountRootfs(cluster *v2.Cluster) error {
-	hosts := append(cluster.GetMasterIPList(), cluster.GetNodeIPList()...)
+	hosts := append(append(cluster.GetMasterIPList(), cluster.GetNodeIPList()...), cluster.GetEtcdIPList()...)
 	regConfig := runtime.GetRegistryConfig(common.DefaultTheClusterRootfsDir(cluster.Name), cluster.GetMaster0IP())
 	if utils.NotInIPList(regConfig.IP, hosts) {
 		hosts = append(hosts, regConfig.IP)
 	}
+	hosts = c.checkpoint.PendingHosts(StepMountRootfs, hosts)
 
 	fs, err := filesystem.NewFilesystem(common.DefaultMountCloudImageDir(cluster.Name))
-	if err != nil {
-		return err
+	if err == nil {
+		err = fs.MountRootfs(cluster, hosts, true)
 	}
-
-	return fs.MountRootfs(cluster, hosts, true)
+	if err == nil {
+		err = c.checkpoint.HostsDone(StepMountRootfs, hosts)
+	}
+	return recordStatus(cluster, v2.ConditionRootfsMounted, hosts, v2.HostPhaseRootfsMounted, err)
 }
 
 func (c *CreateProcessor) Init(cluster *v2.Cluster) error {
-	return c.Runtime.Init(cluster)
+	err := c.Runtime.Init(cluster)
+	// the external etcd members are started before master0.
+	hosts := append([]string{cluster.GetMaster0IP()}, cluster.GetEtcdIPList()...)
+	return recordStatus(cluster, v2.ConditionInitialized, hosts, v2.HostPhaseInitialized, err)
 }
 
+// Join only join the hosts which are not joined by the last apply.
 func (c *CreateProcessor) Join(cluster *v2.Cluster) error {
-	err := c.Runtime.JoinMasters(cluster.GetMasterIPList()[1:])
-	if err != nil {
-		return err
+	masters := c.checkpoint.PendingHosts(StepJoin, cluster.GetMasterIPList()[1:])
+	err := c.Runtime.JoinMasters(masters)
+	if cpErr := c.checkpoint.HostsDone(StepJoin, succeededHosts(masters, err)); cpErr != nil && err == nil {
+		err = cpErr
 	}
-	err = c.Runtime.JoinNodes(cluster.GetNodeIPList())
 	if err != nil {
-		return err
+		return recordStatus(cluster, v2.ConditionJoined, masters, v2.HostPhaseJoined, err)
+	}
+	cluster.Status.SetHostsPhase(masters, v2.HostPhaseJoined)
+	nodes := c.checkpoint.PendingHosts(StepJoin, cluster.GetNodeIPList())
+	err = c.Runtime.JoinNodes(nodes)
+	if cpErr := c.checkpoint.HostsDone(StepJoin, succeededHosts(nodes, err)); cpErr != nil && err == nil {
+		err = cpErr
 	}
-	return utils.SaveClusterInfoToFile(cluster, cluster.Name)
+	return recordStatus(cluster, v2.ConditionJoined, nodes, v2.HostPhaseJoined, err)
 }
 
 func (c *CreateProcessor) RunGuest(cluster *v2.Cluster) error {
-	return c.Guest.Apply(cluster)
+	// guest commands are only executed on master0.
+	err := c.Guest.Apply(cluster)
+	return recordStatus(cluster, v2.ConditionGuestApplied, []string{cluster.GetMaster0IP()}, v2.HostPhaseGuestApplied, err)
 }
 func (c *CreateProcessor) UnMountImage(cluster *v2.Cluster) error {
 	return c.cloudImageMounter.UnMountImage(cluster)
@@ -143,10 +219,12 @@ func (c *CreateProcessor) initPlugin(cluster *v2.Cluster) error {
 
 func (c *CreateProcessor) GetPhasePluginFunc(phase plugin.Phase) func(cluster *v2.Cluster) error {
 	return func(cluster *v2.Cluster) error {
-		if phase == plugin.PhasePreInit {
+		// plugins in rootfs are loaded after rootfs mounted, PreInit may be skipped when resuming.
+		if phase != plugin.PhaseOriginally && !c.pluginsLoaded {
 			if err := c.Plugins.Load(); err != nil {
 				return err
 			}
+			c.pluginsLoaded = true
 		}
 		return c.Plugins.Run(cluster, phase)
 	}
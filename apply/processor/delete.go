@@ -45,9 +45,10 @@ func (d DeleteProcessor) Execute(cluster *v2.Cluster) (err error) {
 		return fmt.Errorf("failed to init runtime, %v", err)
 	}
 
+	// on success the cluster work dir will be cleaned, so only failure is recorded.
 	err = runTime.Reset()
 	if err != nil {
-		return err
+		return recordStatus(cluster, v2.ConditionDeleted, nil, "", err)
 	}
 
 	pipLine, err := d.GetPipeLine()
@@ -57,7 +58,7 @@ func (d DeleteProcessor) Execute(cluster *v2.Cluster) (err error) {
 
 	for _, f := range pipLine {
 		if err = f(cluster); err != nil {
-			return err
+			return recordStatus(cluster, v2.ConditionDeleted, nil, "", err)
 		}
 	}
 
@@ -75,7 +76,7 @@ func (d DeleteProcessor) GetPipeLine() ([]func(cluster *v2.Cluster) error, error
 }
 
 func (d DeleteProcessor) UnMountRootfs(cluster *v2.Cluster) error {
-	hosts := append(cluster.GetMasterIPList(), cluster.GetNodeIPList()...)
+	hosts := append(append(cluster.GetMasterIPList(), cluster.GetNodeIPList()...), cluster.GetEtcdIPList()...)
 	config := runtime.GetRegistryConfig(common.DefaultTheClusterRootfsDir(cluster.Name), runtime.GetMaster0Ip(cluster))
 	if utils.NotIn(config.IP, hosts) {
 		hosts = append(hosts, config.IP)
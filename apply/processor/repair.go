@@ -0,0 +1,143 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/filesystem"
+	"github.com/alibaba/sealer/pkg/filesystem/cloudfilesystem"
+	"github.com/alibaba/sealer/pkg/runtime"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+	"github.com/alibaba/sealer/utils"
+)
+
+// nodeMetaAnnotation is the Clusterfile annotation keeping the labels and taints of the hosts being repaired by ip,
+// so they are not lost if the repair fails after the nodes are deleted.
+const nodeMetaAnnotation = "sealer.cloud/repair-node-meta"
+
+// RepairProcessor re-provision a broken master or worker in place, it is removed from the cluster and joined again
+// with the same role, labels and taints. The host may be a new machine with the same ip.
+type RepairProcessor struct {
+	fileSystem cloudfilesystem.Interface
+	Runtime    runtime.Repairer
+	Host       string
+}
+
+func (r RepairProcessor) Execute(cluster *v2.Cluster) error {
+	hosts := []string{r.Host}
+	meta, err := r.saveNodeMeta(cluster)
+	if err != nil {
+		return recordStatus(cluster, v2.ConditionRepaired, hosts, "", err)
+	}
+	if err := r.Runtime.RemoveBrokenHost(r.Host); err != nil {
+		return recordStatus(cluster, v2.ConditionRepaired, hosts, "", err)
+	}
+	cluster.Status.SetHostsPhase(hosts, v2.HostPhasePending)
+	// an unreachable host is expected to be replaced, its rootfs is mounted after it is back.
+	if err := r.fileSystem.UnMountRootfs(cluster, hosts); err != nil {
+		logger.Warn("failed to unmount rootfs of %s: %v", r.Host, err)
+	}
+	if err := r.fileSystem.MountRootfs(cluster, hosts, true); err != nil {
+		return recordStatus(cluster, v2.ConditionRepaired, hosts, "", err)
+	}
+	cluster.Status.SetHostsPhase(hosts, v2.HostPhaseRootfsMounted)
+	if utils.InList(r.Host, cluster.GetMasterIPList()) {
+		err = r.Runtime.JoinMasters(hosts)
+	} else {
+		err = r.Runtime.JoinNodes(hosts)
+	}
+	if err != nil {
+		return recordStatus(cluster, v2.ConditionRepaired, hosts, "", err)
+	}
+	if err := r.Runtime.RestoreNodeMeta(r.Host, meta); err != nil {
+		logger.Warn("failed to restore the labels %v and taints %v of %s, they are kept in the annotation %s of Clusterfile, add them manually: %v",
+			meta.Labels, meta.Taints, r.Host, nodeMetaAnnotation, err)
+	} else if err := setNodeMeta(cluster, r.Host, nil); err != nil {
+		logger.Warn("failed to clear the labels and taints of %s in Clusterfile: %v", r.Host, err)
+	}
+	return recordStatus(cluster, v2.ConditionRepaired, hosts, v2.HostPhaseJoined, nil)
+}
+
+// saveNodeMeta save the labels and taints of the node of host to Clusterfile before it is deleted,
+// the saved ones are returned if the repair is run again, since the node may have been deleted.
+func (r RepairProcessor) saveNodeMeta(cluster *v2.Cluster) (*runtime.NodeMeta, error) {
+	metas, err := getNodeMetas(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if meta, ok := metas[r.Host]; ok {
+		logger.Info("the labels and taints of %s saved in Clusterfile are used", r.Host)
+		return meta, nil
+	}
+	meta, err := r.Runtime.GetNodeMeta(r.Host)
+	if err != nil {
+		return nil, err
+	}
+	if err := setNodeMeta(cluster, r.Host, meta); err != nil {
+		return nil, err
+	}
+	return meta, utils.SaveClusterInfoToFile(cluster, cluster.Name)
+}
+
+func getNodeMetas(cluster *v2.Cluster) (map[string]*runtime.NodeMeta, error) {
+	metas := map[string]*runtime.NodeMeta{}
+	if data, ok := cluster.Annotations[nodeMetaAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), &metas); err != nil {
+			return nil, fmt.Errorf("failed to parse the annotation %s of Clusterfile: %v", nodeMetaAnnotation, err)
+		}
+	}
+	return metas, nil
+}
+
+// setNodeMeta set the labels and taints of host in the annotation of cluster, they are removed if meta is nil.
+func setNodeMeta(cluster *v2.Cluster, host string, meta *runtime.NodeMeta) error {
+	metas, err := getNodeMetas(cluster)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		delete(metas, host)
+	} else {
+		metas[host] = meta
+	}
+	if len(metas) == 0 {
+		delete(cluster.Annotations, nodeMetaAnnotation)
+		return nil
+	}
+	data, err := json.Marshal(metas)
+	if err != nil {
+		return err
+	}
+	if cluster.Annotations == nil {
+		cluster.Annotations = map[string]string{}
+	}
+	cluster.Annotations[nodeMetaAnnotation] = string(data)
+	return nil
+}
+
+func NewRepairProcessor(rootfs string, rt runtime.Interface, host string) (Interface, error) {
+	repairer, ok := rt.(runtime.Repairer)
+	if !ok {
+		return nil, fmt.Errorf("repair is not supported by the runtime of cluster, delete and join %s instead", host)
+	}
+	fs, err := filesystem.NewFilesystem(rootfs)
+	if err != nil {
+		return nil, err
+	}
+	return RepairProcessor{fileSystem: fs, Runtime: repairer, Host: host}, nil
+}
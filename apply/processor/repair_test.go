@@ -0,0 +1,58 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/alibaba/sealer/pkg/runtime"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+func TestSetNodeMeta(t *testing.T) {
+	cluster := &v2.Cluster{}
+	meta := &runtime.NodeMeta{
+		Labels: map[string]string{"app": "db"},
+		Taints: []v1.Taint{{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoSchedule}},
+	}
+	if err := setNodeMeta(cluster, "192.168.0.3", meta); err != nil {
+		t.Fatalf("setNodeMeta() error = %v", err)
+	}
+	if err := setNodeMeta(cluster, "192.168.0.4", &runtime.NodeMeta{}); err != nil {
+		t.Fatalf("setNodeMeta() error = %v", err)
+	}
+	metas, err := getNodeMetas(cluster)
+	if err != nil {
+		t.Fatalf("getNodeMetas() error = %v", err)
+	}
+	if got := metas["192.168.0.3"]; !reflect.DeepEqual(got, meta) {
+		t.Errorf("getNodeMetas() = %v, want %v", got, meta)
+	}
+	if _, ok := metas["192.168.0.4"]; !ok {
+		t.Errorf("getNodeMetas() should keep the empty meta of 192.168.0.4")
+	}
+
+	for _, host := range []string{"192.168.0.3", "192.168.0.4"} {
+		if err := setNodeMeta(cluster, host, nil); err != nil {
+			t.Fatalf("setNodeMeta() error = %v", err)
+		}
+	}
+	if _, ok := cluster.Annotations[nodeMetaAnnotation]; ok {
+		t.Errorf("annotation %s should be removed after all the metas are cleared", nodeMetaAnnotation)
+	}
+}
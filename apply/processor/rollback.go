@@ -0,0 +1,47 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"github.com/alibaba/sealer/pkg/filesystem"
+	"github.com/alibaba/sealer/pkg/runtime"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+// RollbackProcessor roll back the upgrade, the rootfs of the previous ClusterImage is mounted on hosts again.
+type RollbackProcessor struct {
+	UpgradeProcessor
+	RestoreEtcd bool
+}
+
+func (r RollbackProcessor) Execute(cluster *v2.Cluster) error {
+	if err := r.MountRootfs(cluster); err != nil {
+		return recordStatus(cluster, v2.ConditionRolledBack, nil, "", err)
+	}
+	err := r.Runtime.RollbackUpgrade(r.RestoreEtcd)
+	hosts := append(cluster.GetMasterIPList(), cluster.GetNodeIPList()...)
+	return recordStatus(cluster, v2.ConditionRolledBack, hosts, v2.HostPhaseRolledBack, err)
+}
+
+func NewRollbackProcessor(rootfs string, rt runtime.Interface, restoreEtcd bool) (Interface, error) {
+	fs, err := filesystem.NewFilesystem(rootfs)
+	if err != nil {
+		return nil, err
+	}
+	return RollbackProcessor{
+		UpgradeProcessor: UpgradeProcessor{fileSystem: fs, Runtime: rt},
+		RestoreEtcd:      restoreEtcd,
+	}, nil
+}
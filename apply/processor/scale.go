@@ -16,6 +16,10 @@ package processor
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alibaba/sealer/logger"
 
 	"github.com/alibaba/sealer/pkg/filesystem/cloudfilesystem"
 
@@ -34,6 +38,8 @@ type ScaleProcessor struct {
 	MastersToDelete []string
 	NodesToJoin     []string
 	NodesToDelete   []string
+	EtcdsToJoin     []string
+	EtcdsToDelete   []string
 	IsScaleUp       bool
 }
 
@@ -61,39 +67,206 @@ func (s ScaleProcessor) Execute(cluster *v2.Cluster) error {
 	return s.ScaleDown(cluster)
 }
 
+var (
+	// NoRollback keep the hosts of a failed scale up as they are for debugging, instead of rolling them back.
+	NoRollback bool
+	// MaxFailedNodes is the number like 3 or the percentage like 10% of joining nodes which are allowed to fail,
+	// the failed nodes are skipped and the scale up succeeds with the rest.
+	MaxFailedNodes string
+)
+
+// scaleChanges is what a scale up has done on hosts, the hosts failed in a step may be half done.
+type scaleChanges struct {
+	mounted []string
+	etcds   []string
+	masters []string
+	nodes   []string
+}
+
+// ScaleUp join the hosts, if it failed the changes on hosts are rolled back unless NoRollback is set,
+// so the saved Clusterfile matches the cluster again.
 func (s ScaleProcessor) ScaleUp(cluster *v2.Cluster) error {
-	hosts := append(s.MastersToJoin, s.NodesToJoin...)
-	err := s.fileSystem.MountRootfs(cluster, hosts, true)
+	changes := &scaleChanges{}
+	err := s.scaleUp(cluster, changes)
+	if err == nil || NoRollback {
+		return err
+	}
+	logger.Error("failed to scale up cluster: %v, start to roll back", err)
+	if rollbackErr := s.rollback(cluster, changes); rollbackErr != nil {
+		return fmt.Errorf("%v, and failed to roll back: %v", err, rollbackErr)
+	}
+	logger.Info("Succeeded in rolling back hosts %s", changes.mounted)
+	return err
+}
+
+func (s ScaleProcessor) scaleUp(cluster *v2.Cluster, changes *scaleChanges) error {
+	maxFailed, err := getMaxFailedNodes(MaxFailedNodes, len(s.NodesToJoin))
 	if err != nil {
 		return err
 	}
+	hosts := append(append(s.MastersToJoin, s.NodesToJoin...), s.EtcdsToJoin...)
+	changes.mounted = hosts
+	err = s.fileSystem.MountRootfs(cluster, hosts, true)
+	// the nodes failed to mount rootfs are counted in the failed nodes, the others go on joining.
+	failed, ok := s.nodesFailed(err)
+	if !ok || len(failed) > maxFailed {
+		return recordStatus(cluster, v2.ConditionScaled, hosts, "", err)
+	}
+	nodes := utils.RemoveStrSlice(s.NodesToJoin, failedHosts(failed))
+	cluster.Status.SetHostsPhase(utils.RemoveStrSlice(hosts, failedHosts(failed)), v2.HostPhaseRootfsMounted)
+	// the new masters are joined with all the etcd members.
+	err = s.Runtime.JoinEtcds(s.EtcdsToJoin)
+	changes.etcds = attemptedHosts(s.EtcdsToJoin, err)
+	if err != nil {
+		return recordStatus(cluster, v2.ConditionScaled, s.EtcdsToJoin, v2.HostPhaseJoined, err)
+	}
+	cluster.Status.SetHostsPhase(s.EtcdsToJoin, v2.HostPhaseJoined)
 	err = s.Runtime.JoinMasters(s.MastersToJoin)
+	changes.masters = attemptedHosts(s.MastersToJoin, err)
 	if err != nil {
+		return recordStatus(cluster, v2.ConditionScaled, s.MastersToJoin, v2.HostPhaseJoined, err)
+	}
+	cluster.Status.SetHostsPhase(s.MastersToJoin, v2.HostPhaseJoined)
+	err = s.Runtime.JoinNodes(nodes)
+	changes.nodes = attemptedHosts(nodes, err)
+	joinFailed, ok := s.nodesFailed(err)
+	if !ok {
+		return recordStatus(cluster, v2.ConditionScaled, nodes, v2.HostPhaseJoined, err)
+	}
+	for host, hostErr := range joinFailed {
+		failed[host] = hostErr
+	}
+	if len(failed) == 0 {
+		return recordStatus(cluster, v2.ConditionScaled, nodes, v2.HostPhaseJoined, nil)
+	}
+	hostsErr := &runtime.HostsError{Succeeded: utils.RemoveStrSlice(nodes, failedHosts(joinFailed)), Failed: failed}
+	if len(failed) > maxFailed {
+		return recordStatus(cluster, v2.ConditionScaled, nodes, v2.HostPhaseJoined, hostsErr)
+	}
+	return s.skipFailedNodes(cluster, hostsErr)
+}
+
+// nodesFailed return the nodes to join failed in err, ok is false if err is not only about them.
+func (s ScaleProcessor) nodesFailed(err error) (map[string]error, bool) {
+	failed := map[string]error{}
+	if err == nil {
+		return failed, true
+	}
+	hostsErr, ok := err.(*runtime.HostsError)
+	if !ok {
+		return nil, false
+	}
+	for host, hostErr := range hostsErr.Failed {
+		if utils.NotIn(host, s.NodesToJoin) {
+			return nil, false
+		}
+		failed[host] = hostErr
+	}
+	return failed, true
+}
+
+func failedHosts(failed map[string]error) []string {
+	return (&runtime.HostsError{Failed: failed}).FailedHosts()
+}
+
+// skipFailedNodes leave the nodes failed to join out of the cluster, they are reset as much as possible
+// since a broken host may be unreachable.
+func (s ScaleProcessor) skipFailedNodes(cluster *v2.Cluster, hostsErr *runtime.HostsError) error {
+	failed := hostsErr.FailedHosts()
+	for _, host := range failed {
+		logger.Warn("node %s failed to join and is skipped: %v", host, hostsErr.Failed[host])
+	}
+	if !NoRollback {
+		if err := s.Runtime.RollbackJoin(nil, failed, nil); err != nil {
+			logger.Warn("failed to reset the skipped nodes %s: %v", failed, err)
+		}
+		if err := s.fileSystem.UnMountRootfs(cluster, failed); err != nil {
+			logger.Warn("failed to unmount rootfs of the skipped nodes %s: %v", failed, err)
+		}
+	}
+	removeHosts(cluster, failed)
+	cluster.Status.RemoveHosts(failed)
+	logger.Warn("%d of %d nodes failed to join and are left out of the cluster: %s", len(failed), len(s.NodesToJoin), failed)
+	return recordStatus(cluster, v2.ConditionScaled, hostsErr.Succeeded, v2.HostPhaseJoined, nil)
+}
+
+// getMaxFailedNodes parse the failure budget, a percentage is rounded down.
+func getMaxFailedNodes(value string, total int) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number := strings.TrimSuffix(value, "%")
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid max failed nodes %q, must be a number like 3 or a percentage like 10%%", value)
+	}
+	if number != value {
+		if n > 100 {
+			return 0, fmt.Errorf("invalid max failed nodes %q, the percentage must not be greater than 100%%", value)
+		}
+		return total * n / 100, nil
+	}
+	return n, nil
+}
+
+// rollback reset the joined hosts, unmount their rootfs and remove them from the saved Clusterfile.
+func (s ScaleProcessor) rollback(cluster *v2.Cluster, changes *scaleChanges) error {
+	if err := s.Runtime.RollbackJoin(changes.masters, changes.nodes, changes.etcds); err != nil {
 		return err
 	}
-	err = s.Runtime.JoinNodes(s.NodesToJoin)
-	if err != nil {
+	if err := s.fileSystem.UnMountRootfs(cluster, changes.mounted); err != nil {
 		return err
 	}
-	return nil
+	removeHosts(cluster, changes.mounted)
+	cluster.Status.RemoveHosts(changes.mounted)
+	return utils.SaveClusterInfoToFile(cluster, cluster.Name)
+}
+
+// removeHosts remove the ips from the hosts of cluster spec, the host without any ip left is removed.
+func removeHosts(cluster *v2.Cluster, ips []string) {
+	var hosts []v2.Host
+	for _, host := range cluster.Spec.Hosts {
+		var left []string
+		for _, ip := range host.IPS {
+			if utils.NotIn(ip, ips) {
+				left = append(left, ip)
+			}
+		}
+		if len(left) == 0 {
+			continue
+		}
+		host.IPS = left
+		hosts = append(hosts, host)
+	}
+	cluster.Spec.Hosts = hosts
 }
 
 func (s ScaleProcessor) ScaleDown(cluster *v2.Cluster) error {
 	err := s.Runtime.DeleteMasters(s.MastersToDelete)
 	if err != nil {
-		return err
+		return recordStatus(cluster, v2.ConditionScaled, s.MastersToDelete, "", err)
 	}
 	err = s.Runtime.DeleteNodes(s.NodesToDelete)
 	if err != nil {
-		return err
+		return recordStatus(cluster, v2.ConditionScaled, s.NodesToDelete, "", err)
+	}
+	err = s.Runtime.DeleteEtcds(s.EtcdsToDelete)
+	if err != nil {
+		return recordStatus(cluster, v2.ConditionScaled, s.EtcdsToDelete, "", err)
+	}
+	hosts := append(append(s.MastersToDelete, s.NodesToDelete...), s.EtcdsToDelete...)
+	err = s.fileSystem.UnMountRootfs(cluster, hosts)
+	if err != nil {
+		return recordStatus(cluster, v2.ConditionScaled, hosts, "", err)
 	}
-	return s.fileSystem.UnMountRootfs(cluster, append(s.MastersToDelete, s.NodesToDelete...))
+	cluster.Status.RemoveHosts(hosts)
+	return recordStatus(cluster, v2.ConditionScaled, nil, "", nil)
 }
 
-func NewScaleProcessor(kubeadmConfig *runtime.KubeadmConfig, rootfs string, masterToJoin, masterToDelete, nodeToJoin, nodeToDelete []string) (Interface, error) {
+func NewScaleProcessor(kubeadmConfig *runtime.KubeadmConfig, rootfs string, masterToJoin, masterToDelete, nodeToJoin, nodeToDelete, etcdToJoin, etcdToDelete []string) (Interface, error) {
 	var up bool
 	// only scale up or scale down at a time
-	if len(masterToJoin) > 0 || len(nodeToJoin) > 0 {
+	if len(masterToJoin) > 0 || len(nodeToJoin) > 0 || len(etcdToJoin) > 0 {
 		up = true
 	}
 	fs, err := filesystem.NewFilesystem(rootfs)
@@ -105,6 +278,8 @@ func NewScaleProcessor(kubeadmConfig *runtime.KubeadmConfig, rootfs string, mast
 		MastersToJoin:   masterToJoin,
 		NodesToDelete:   nodeToDelete,
 		NodesToJoin:     nodeToJoin,
+		EtcdsToDelete:   etcdToDelete,
+		EtcdsToJoin:     etcdToJoin,
 		KubeadmConfig:   kubeadmConfig,
 		IsScaleUp:       up,
 		fileSystem:      fs,
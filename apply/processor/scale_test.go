@@ -0,0 +1,129 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/runtime"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+func TestRemoveHosts(t *testing.T) {
+	cluster := &v2.Cluster{}
+	cluster.Spec.Hosts = []v2.Host{
+		{IPS: []string{"192.168.0.2", "192.168.0.3"}, Roles: []string{common.MASTER}},
+		{IPS: []string{"192.168.0.4"}, Roles: []string{common.NODE}},
+		{IPS: []string{"192.168.0.5"}, Roles: []string{common.NODE}, Env: []string{"key=value"}},
+	}
+	removeHosts(cluster, []string{"192.168.0.3", "192.168.0.4"})
+	want := []v2.Host{
+		{IPS: []string{"192.168.0.2"}, Roles: []string{common.MASTER}},
+		{IPS: []string{"192.168.0.5"}, Roles: []string{common.NODE}, Env: []string{"key=value"}},
+	}
+	if !reflect.DeepEqual(cluster.Spec.Hosts, want) {
+		t.Errorf("removeHosts() = %v, want %v", cluster.Spec.Hosts, want)
+	}
+}
+
+func TestAttemptedHosts(t *testing.T) {
+	hosts := []string{"192.168.0.2", "192.168.0.3", "192.168.0.4"}
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{"succeeded", nil, hosts},
+		{"failed before join", fmt.Errorf("ssh timeout"), hosts},
+		{
+			"failed on host",
+			&runtime.HostsError{Succeeded: hosts[:1], Failed: map[string]error{"192.168.0.3": fmt.Errorf("join failed")}},
+			[]string{"192.168.0.2", "192.168.0.3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := attemptedHosts(hosts, tt.err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("attemptedHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMaxFailedNodes(t *testing.T) {
+	tests := []struct {
+		value   string
+		total   int
+		want    int
+		wantErr bool
+	}{
+		{"", 200, 0, false},
+		{"3", 200, 3, false},
+		{"10%", 200, 20, false},
+		{"10%", 15, 1, false},
+		{"100%", 15, 15, false},
+		{"101%", 15, 0, true},
+		{"-1", 15, 0, true},
+		{"abc", 15, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := getMaxFailedNodes(tt.value, tt.total)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getMaxFailedNodes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getMaxFailedNodes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodesFailed(t *testing.T) {
+	s := ScaleProcessor{MastersToJoin: []string{"192.168.0.2"}, NodesToJoin: []string{"192.168.0.4", "192.168.0.5"}}
+	sshErr := fmt.Errorf("ssh timeout")
+	tests := []struct {
+		name   string
+		err    error
+		want   map[string]error
+		wantOK bool
+	}{
+		{"succeeded", nil, map[string]error{}, true},
+		{"failed before hosts", sshErr, nil, false},
+		{
+			"node failed",
+			&runtime.HostsError{Succeeded: []string{"192.168.0.2", "192.168.0.5"}, Failed: map[string]error{"192.168.0.4": sshErr}},
+			map[string]error{"192.168.0.4": sshErr},
+			true,
+		},
+		{
+			"master failed",
+			&runtime.HostsError{Succeeded: []string{"192.168.0.5"}, Failed: map[string]error{"192.168.0.2": sshErr, "192.168.0.4": sshErr}},
+			nil,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := s.nodesFailed(tt.err)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("nodesFailed() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
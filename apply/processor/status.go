@@ -0,0 +1,68 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package processor
+
+import (
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/runtime"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+	"github.com/alibaba/sealer/utils"
+)
+
+// recordStatus update the condition of cluster and the phase of hosts according to the result of a step,
+// then save it to the cluster work dir, so we can tell where the apply stopped. err is returned as it is.
+func recordStatus(cluster *v2.Cluster, condType v2.ConditionType, hosts []string, phase v2.HostPhase, err error) error {
+	cluster.Status.SetCondition(condType, err)
+	if hostsErr, ok := err.(*runtime.HostsError); ok {
+		if phase != "" {
+			cluster.Status.SetHostsPhase(hostsErr.Succeeded, phase)
+		}
+		for host, hostErr := range hostsErr.Failed {
+			cluster.Status.SetHostsFailed([]string{host}, hostErr)
+		}
+	} else if err != nil {
+		cluster.Status.SetHostsFailed(hosts, err)
+	} else if phase != "" {
+		cluster.Status.SetHostsPhase(hosts, phase)
+	}
+	if saveErr := utils.SaveClusterInfoToFile(cluster, cluster.Name); saveErr != nil {
+		if err != nil {
+			logger.Warn("failed to save cluster status: %v", saveErr)
+			return err
+		}
+		return saveErr
+	}
+	return err
+}
+
+// succeededHosts return the hosts finished an action according to the error it returned.
+func succeededHosts(hosts []string, err error) []string {
+	if err == nil {
+		return hosts
+	}
+	if hostsErr, ok := err.(*runtime.HostsError); ok {
+		return hostsErr.Succeeded
+	}
+	return nil
+}
+
+// attemptedHosts return the hosts which an action has been executed on, including the failed ones.
+// All the hosts are returned if the action failed before executing on any host.
+func attemptedHosts(hosts []string, err error) []string {
+	if hostsErr, ok := err.(*runtime.HostsError); ok {
+		return append(append([]string{}, hostsErr.Succeeded...), hostsErr.FailedHosts()...)
+	}
+	return hosts
+}
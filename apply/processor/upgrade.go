@@ -35,14 +35,11 @@ type UpgradeProcessor struct {
 func (u UpgradeProcessor) Execute(cluster *v2.Cluster) error {
 	err := u.MountRootfs(cluster)
 	if err != nil {
-		return err
+		return recordStatus(cluster, v2.ConditionUpgraded, nil, "", err)
 	}
 	err = u.Upgrade()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	hosts := append(cluster.GetMasterIPList(), cluster.GetNodeIPList()...)
+	return recordStatus(cluster, v2.ConditionUpgraded, hosts, v2.HostPhaseUpgraded, err)
 }
 
 func (u UpgradeProcessor) MountRootfs(cluster *v2.Cluster) error {
@@ -54,7 +51,8 @@ func (u UpgradeProcessor) MountRootfs(cluster *v2.Cluster) error {
 	if utils.NotInIPList(regConfig.IP, hosts) {
 		hosts = append(hosts, regConfig.IP)
 	}
-	return u.fileSystem.MountRootfs(cluster, hosts, false)
+	err := u.fileSystem.MountRootfs(cluster, hosts, false)
+	return recordStatus(cluster, v2.ConditionRootfsMounted, hosts, v2.HostPhaseRootfsMounted, err)
 }
 
 func (u UpgradeProcessor) Upgrade() error {
@@ -132,16 +132,23 @@ func deleteBaremetalNodes(cluster *v2.Cluster, scaleArgs *common.RunArgs) error
 	if (!IsIPList(scaleArgs.Nodes) && scaleArgs.Nodes != "") || (!IsIPList(scaleArgs.Masters) && scaleArgs.Masters != "") {
 		return fmt.Errorf(" Parameter error: The current mode should submit iplist！")
 	}
-	//master0 machine cannot be deleted
-	if utils.InList(cluster.GetMaster0IP(), strings.Split(scaleArgs.Masters, ",")) {
-		return fmt.Errorf("master0 machine cannot be deleted")
+	//master0 could be deleted, the next master takes its place, but at least one master is left
+	if scaleArgs.Masters != "" && len(utils.RemoveStrSlice(cluster.GetMasterIPList(), strings.Split(scaleArgs.Masters, ","))) == 0 {
+		return fmt.Errorf("all the masters cannot be deleted")
 	}
 	if scaleArgs.Masters != "" && IsIPList(scaleArgs.Masters) {
+		var hosts []v2.Host
 		for i := range cluster.Spec.Hosts {
 			if utils.InList(common.MASTER, cluster.Spec.Hosts[i].Roles) {
 				cluster.Spec.Hosts[i].IPS = returnFilteredIPList(cluster.Spec.Hosts[i].IPS, strings.Split(scaleArgs.Masters, ","))
+				// the first master left is master0, so the masters without any ip are removed.
+				if len(cluster.Spec.Hosts[i].IPS) == 0 {
+					continue
+				}
 			}
+			hosts = append(hosts, cluster.Spec.Hosts[i])
 		}
+		cluster.Spec.Hosts = hosts
 	}
 	if scaleArgs.Nodes != "" && IsIPList(scaleArgs.Nodes) {
 		for i := range cluster.Spec.Hosts {
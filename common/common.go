@@ -24,6 +24,7 @@ const (
 	MASTER  = "master"
 	NODE    = "node"
 	MASTER0 = "master0"
+	ETCD    = "etcd"
 )
 
 const (
@@ -69,6 +70,14 @@ const (
 	AppImage                      = "application"
 )
 
+// annotations of cluster
+const (
+	// PreviousClusterImage is the ClusterImage before upgrade, which the cluster could roll back to.
+	PreviousClusterImage = "PreviousClusterImage"
+	// ClusterRuntime is the runtime the cluster is installed by, recorded from the ClusterImage metadata.
+	ClusterRuntime = "ClusterRuntime"
+)
+
 // image module
 const (
 	DefaultImageRootDir          = "/var/lib/sealer/data"
@@ -153,6 +162,11 @@ func GetClusterWorkClusterfile(clusterName string) string {
 	return filepath.Join(GetClusterWorkDir(clusterName), "Clusterfile")
 }
 
+// GetSecretKeyFile returns the local key to encrypt the secrets of the saved Clusterfiles.
+func GetSecretKeyFile() string {
+	return filepath.Join(GetHomeDir(), ".sealer", "secret.key")
+}
+
 func DefaultRegistryAuthConfigDir() string {
 	return filepath.Join(GetHomeDir(), ".docker/config.json")
 }
@@ -110,7 +110,8 @@ build_binaries() {
   debug "output sha256sum: $THIS_PLATFORM_ASSETS/sealer-$tarFile.sha256sum"
 
   debug "!!! build $osarch seautil"
-  GOOS=${1-} GOARCH=${2-} go build -o $THIS_PLATFORM_BIN/seautil/$osarch/seautil -mod vendor -ldflags "$goldflags"  $SEALER_ROOT/seautil/main.go
+  # seautil is built statically, it runs in the lvscare static pod with the image of cluster.
+  CGO_ENABLED=0 GOOS=${1-} GOARCH=${2-} go build -o $THIS_PLATFORM_BIN/seautil/$osarch/seautil -mod vendor -ldflags "$goldflags"  $SEALER_ROOT/seautil/main.go
   check $? "build $osarch seautil"
   debug "output bin: $THIS_PLATFORM_BIN/seautil/$osarch/seautil"
   cd ${SEALER_ROOT}/_output/bin/seautil/$osarch/
@@ -57,7 +57,7 @@ type Config struct {
 	CAName       string // root ca map key
 	CommonName   string
 	Organization []string
-	Year         time.Duration
+	Validity     time.Duration
 	AltNames     AltNames
 	Usages       []x509.ExtKeyUsage
 }
@@ -80,7 +80,7 @@ func NewPrivateKey(keyType x509.PublicKeyAlgorithm) (crypto.Signer, error) {
 }
 
 // NewSelfSignedCACert creates a CA certificate
-func NewSelfSignedCACert(key crypto.Signer, commonName string, organization []string, year time.Duration) (*x509.Certificate, error) {
+func NewSelfSignedCACert(key crypto.Signer, commonName string, organization []string, validity time.Duration) (*x509.Certificate, error) {
 	now := time.Now()
 	tmpl := x509.Certificate{
 		SerialNumber: new(big.Int).SetInt64(0),
@@ -90,7 +90,7 @@ func NewSelfSignedCACert(key crypto.Signer, commonName string, organization []st
 		},
 		DNSNames:              []string{commonName},
 		NotBefore:             now.UTC(),
-		NotAfter:              now.Add(duration365d * year).UTC(),
+		NotAfter:              now.Add(validity).UTC(),
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
 		BasicConstraintsValid: true,
 		IsCA:                  true,
@@ -110,11 +110,11 @@ func NewCaCertAndKey(cfg Config) (*x509.Certificate, crypto.Signer, error) {
 		return LoadCaCertAndKeyFromDisk(cfg)
 	}
 
-	key, err := NewPrivateKey(x509.UnknownPublicKeyAlgorithm)
+	key, err := NewKey(CertKeyAlgorithm)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create private key while generating CA certificate %s", err)
 	}
-	cert, err := NewSelfSignedCACert(key, cfg.CommonName, cfg.Organization, cfg.Year)
+	cert, err := NewSelfSignedCACert(key, cfg.CommonName, cfg.Organization, cfg.Validity)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create ca cert %s", err)
 	}
@@ -160,7 +160,7 @@ func TryLoadKeyFromDisk(pkiPath string) (crypto.Signer, error) {
 
 //  NewCaCertAndKeyFromRoot cmd/kubeadm/app/util/pkiutil/pki_helpers.go NewCertAndKey
 func NewCaCertAndKeyFromRoot(cfg Config, caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
-	key, err := NewPrivateKey(x509.UnknownPublicKeyAlgorithm)
+	key, err := NewKey(CertKeyAlgorithm)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create private key while generating CA certificate %s", err)
 	}
@@ -194,6 +194,11 @@ func NewSignedCert(cfg Config, key crypto.Signer, caCert *x509.Certificate, caKe
 	for _, v := range cfg.AltNames.IPs {
 		ips = append(ips, v)
 	}
+	// the cert should not outlive the ca.
+	notAfter := time.Now().Add(cfg.Validity).UTC()
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
 	certTmpl := x509.Certificate{
 		Subject: pkix.Name{
 			CommonName:   cfg.CommonName,
@@ -203,7 +208,7 @@ func NewSignedCert(cfg Config, key crypto.Signer, caCert *x509.Certificate, caKe
 		IPAddresses:  ips,
 		SerialNumber: serial,
 		NotBefore:    caCert.NotBefore,
-		NotAfter:     time.Now().Add(duration365d * cfg.Year).UTC(),
+		NotAfter:     notAfter,
 		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:  cfg.Usages,
 	}
@@ -63,7 +63,7 @@ func GenerateRegistryCert(registryCertPath string, BaseName string) error {
 		BaseName:     BaseName,
 		CommonName:   BaseName,
 		Organization: []string{common.ExecBinaryFileName},
-		Year:         100,
+		Validity:     CertValidity,
 	}
 	cert, key, err := NewCaCertAndKey(regCertConfig)
 	if err != nil {
@@ -0,0 +1,61 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"fmt"
+	"os"
+)
+
+const etcdCAName = "etcd-ca"
+
+// GenerateEtcdCert generate the server, peer and healthcheck-client certs of an external etcd member to outPath.
+// They are signed by the etcd ca in certEtcdPath, which is created if not exist, and the ca cert is copied to outPath.
+func GenerateEtcdCert(certEtcdPath, outPath, hostIP, hostName string) error {
+	var etcdCA Config
+	for _, ca := range CaList("", certEtcdPath) {
+		if ca.CommonName == etcdCAName {
+			etcdCA = ca
+		}
+	}
+	_, err := os.Stat(pathForKey(etcdCA.Path, etcdCA.BaseName))
+	caExist := !os.IsNotExist(err)
+	caCert, caKey, err := NewCaCertAndKey(etcdCA)
+	if err != nil {
+		return fmt.Errorf("failed to load etcd ca: %v", err)
+	}
+	if !caExist {
+		if err := WriteCertAndKey(etcdCA.Path, etcdCA.BaseName, caCert, caKey); err != nil {
+			return err
+		}
+	}
+	if err := WriteCert(outPath, etcdCA.BaseName, caCert); err != nil {
+		return err
+	}
+
+	meta := &MetaData{NodeName: hostName, NodeIP: hostIP, CertEtcdPath: outPath}
+	certs := certList("", outPath)
+	meta.etcdAltAndCommonName(&certs)
+	for _, i := range []int{EtcdServerCert, EtcdPeerCert, EtcdHealthcheckClientCert} {
+		cert, key, err := NewCaCertAndKeyFromRoot(certs[i], caCert, caKey)
+		if err != nil {
+			return fmt.Errorf("failed to generate etcd %s cert: %v", certs[i].BaseName, err)
+		}
+		if err := WriteCertAndKey(certs[i].Path, certs[i].BaseName, cert, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
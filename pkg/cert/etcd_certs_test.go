@@ -0,0 +1,66 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func TestGenerateEtcdCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcd-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	etcdCAPath := filepath.Join(dir, "pki", "etcd")
+
+	for _, host := range []struct{ ip, name string }{{"192.168.0.10", "etcd-0"}, {"192.168.0.11", "etcd-1"}} {
+		outPath := filepath.Join(dir, host.ip)
+		if err := GenerateEtcdCert(etcdCAPath, outPath, host.ip, host.name); err != nil {
+			t.Fatalf("GenerateEtcdCert() error = %v", err)
+		}
+		ca, err := certutil.CertsFromFile(filepath.Join(outPath, "ca.crt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"server", "peer"} {
+			certs, err := certutil.CertsFromFile(filepath.Join(outPath, name+".crt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cert := certs[0]
+			if cert.Subject.CommonName != host.name {
+				t.Errorf("%s cert common name = %s, want %s", name, cert.Subject.CommonName, host.name)
+			}
+			if err := cert.CheckSignatureFrom(ca[0]); err != nil {
+				t.Errorf("%s cert is not signed by etcd ca: %v", name, err)
+			}
+			found := false
+			for _, ip := range cert.IPAddresses {
+				if ip.String() == host.ip {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s cert ip addresses %v do not contain %s", name, cert.IPAddresses, host.ip)
+			}
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"net"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/alibaba/sealer/logger"
 )
@@ -48,7 +49,7 @@ func CaList(CertPath, CertEtcdPath string) []Config {
 			BaseName:     "ca",
 			CommonName:   "kubernetes",
 			Organization: nil,
-			Year:         100,
+			Validity:     CAValidity,
 			AltNames:     AltNames{},
 			Usages:       nil,
 		},
@@ -58,7 +59,7 @@ func CaList(CertPath, CertEtcdPath string) []Config {
 			BaseName:     "front-proxy-ca",
 			CommonName:   "front-proxy-ca",
 			Organization: nil,
-			Year:         100,
+			Validity:     CAValidity,
 			AltNames:     AltNames{},
 			Usages:       nil,
 		},
@@ -68,7 +69,7 @@ func CaList(CertPath, CertEtcdPath string) []Config {
 			BaseName:     "ca",
 			CommonName:   "etcd-ca",
 			Organization: nil,
-			Year:         100,
+			Validity:     CAValidity,
 			AltNames:     AltNames{},
 			Usages:       nil,
 		},
@@ -84,7 +85,7 @@ func certList(CertPath, CertEtcdPath string) []Config {
 			CAName:       "kubernetes",
 			CommonName:   "kube-apiserver",
 			Organization: nil,
-			Year:         100,
+			Validity:     CertValidity,
 			AltNames: AltNames{
 				DNSNames: map[string]string{
 					"localhost":              "localhost",
@@ -105,7 +106,7 @@ func certList(CertPath, CertEtcdPath string) []Config {
 			CAName:       "kubernetes",
 			CommonName:   "kube-apiserver-kubelet-client",
 			Organization: []string{"system:masters"},
-			Year:         100,
+			Validity:     CertValidity,
 			AltNames:     AltNames{},
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		},
@@ -116,7 +117,7 @@ func certList(CertPath, CertEtcdPath string) []Config {
 			CAName:       "front-proxy-ca",
 			CommonName:   "front-proxy-client",
 			Organization: nil,
-			Year:         100,
+			Validity:     CertValidity,
 			AltNames:     AltNames{},
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		},
@@ -127,7 +128,7 @@ func certList(CertPath, CertEtcdPath string) []Config {
 			CAName:       "etcd-ca",
 			CommonName:   "kube-apiserver-etcd-client",
 			Organization: []string{"system:masters"},
-			Year:         100,
+			Validity:     CertValidity,
 			AltNames:     AltNames{},
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		},
@@ -138,7 +139,7 @@ func certList(CertPath, CertEtcdPath string) []Config {
 			CAName:       "etcd-ca",
 			CommonName:   "etcd", // kubeadm using node name as common name cc.CommonName = mc.NodeRegistration.Name
 			Organization: nil,
-			Year:         100,
+			Validity:     CertValidity,
 			AltNames:     AltNames{}, // need set altNames
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		},
@@ -149,7 +150,7 @@ func certList(CertPath, CertEtcdPath string) []Config {
 			CAName:       "etcd-ca",
 			CommonName:   "etcd-peer", // change this in filter
 			Organization: nil,
-			Year:         100,
+			Validity:     CertValidity,
 			AltNames:     AltNames{}, // change this in filter
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		},
@@ -160,7 +161,7 @@ func certList(CertPath, CertEtcdPath string) []Config {
 			CAName:       "etcd-ca",
 			CommonName:   "kube-etcd-healthcheck-client",
 			Organization: []string{"system:masters"},
-			Year:         100,
+			Validity:     CertValidity,
 			AltNames:     AltNames{},
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		},
@@ -194,12 +195,15 @@ func NewMetaData(certPATH, certEtcdPATH string, apiServerIPAndDomains []string,
 	data.DNSDomain = DNSDomain
 	data.APIServer.IPs = make(map[string]net.IP)
 	data.APIServer.DNSNames = make(map[string]string)
-	svcFirstIP, _, err := net.ParseCIDR(SvcCIDR)
-	if err != nil {
-		return nil, err
+	// the svc cidr of dual-stack cluster is like 10.96.0.0/22,fd00:10:96::/112.
+	for _, cidr := range strings.Split(SvcCIDR, ",") {
+		svcFirstIP, _, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, err
+		}
+		svcFirstIP[len(svcFirstIP)-1]++ //取svc第一个ip
+		data.APIServer.IPs[svcFirstIP.String()] = svcFirstIP
 	}
-	svcFirstIP[len(svcFirstIP)-1]++ //取svc第一个ip
-	data.APIServer.IPs[svcFirstIP.String()] = svcFirstIP
 
 	for _, altName := range apiServerIPAndDomains {
 		ip := net.ParseIP(altName)
@@ -241,9 +245,9 @@ func (meta *MetaData) etcdAltAndCommonName(certList *[]Config) {
 			meta.NodeName: meta.NodeName,
 		},
 		IPs: map[string]net.IP{
-			net.IPv4(127, 0, 0, 1).String():         net.IPv4(127, 0, 0, 1),
-			net.ParseIP(meta.NodeIP).To4().String(): net.ParseIP(meta.NodeIP).To4(),
-			net.IPv6loopback.String():               net.IPv6loopback,
+			net.IPv4(127, 0, 0, 1).String():   net.IPv4(127, 0, 0, 1),
+			net.ParseIP(meta.NodeIP).String(): net.ParseIP(meta.NodeIP),
+			net.IPv6loopback.String():         net.IPv6loopback,
 		},
 	}
 	(*certList)[EtcdServerCert].CommonName = meta.NodeName
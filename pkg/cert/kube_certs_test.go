@@ -43,3 +43,15 @@ func TestGenerateAll(t *testing.T) {
 		})
 	}
 }
+
+func TestNewMetaDataDualStack(t *testing.T) {
+	certMeta, err := NewMetaData("/tmp/kubernetes/pki", "/tmp/kubernetes/pki/etcd", []string{"apiserver.cluster.local", "fd00::2"}, "10.96.0.0/22,fd00:10:96::/112", "master1", "fd00::10", "cluster.local")
+	if err != nil {
+		t.Fatalf("NewMetaData() error = %v", err)
+	}
+	for _, ip := range []string{"10.96.0.1", "fd00:10:96::1", "fd00::2", "fd00::10"} {
+		if _, ok := certMeta.APIServer.IPs[ip]; !ok {
+			t.Errorf("apiserver ips %v do not contain %s", certMeta.APIServer.IPs, ip)
+		}
+	}
+}
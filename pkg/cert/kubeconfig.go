@@ -22,6 +22,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/client-go/tools/clientcmd"
@@ -35,6 +36,8 @@ import (
 type clientCertAuth struct {
 	CAKey         crypto.Signer
 	Organizations []string
+	// Validity of the client certificate, CertValidity is used if it is zero.
+	Validity time.Duration
 }
 
 // tokenAuth struct holds info required to use a token to provide authentication info in a kubeconfig object
@@ -182,7 +185,10 @@ func buildKubeConfigFromSpec(spec *kubeConfigSpec, clustername string) (*clientc
 		CommonName:   spec.ClientName,
 		Organization: spec.ClientCertAuth.Organizations,
 		Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
-		Year:         100,
+		Validity:     CertValidity,
+	}
+	if spec.ClientCertAuth.Validity != 0 {
+		clientCertConfig.Validity = spec.ClientCertAuth.Validity
 	}
 
 	clientCert, clientKey, err := NewCaCertAndKeyFromRoot(clientCertConfig, spec.CACert, spec.ClientCertAuth.CAKey)
@@ -304,6 +310,30 @@ func WriteKubeConfigWithClientCert(out io.Writer, cfg Config, clientName, contro
 	return writeKubeConfigFromSpec(out, spec, clusterName)
 }
 
+// WriteKubeConfigWithClientCertByCA writes a kubeconfig file with a client certificate signed by the given CA
+// and valid for validity to the given writer, it is used to issue kubeconfigs for users.
+func WriteKubeConfigWithClientCertByCA(out io.Writer, caCert *x509.Certificate, caKey crypto.Signer, clientName, controlPlaneEndpoint, clusterName string, organizations []string, validity time.Duration) error {
+	if len(clientName) == 0 {
+		return errors.New("clientName can not be empty")
+	}
+	if len(controlPlaneEndpoint) == 0 {
+		return errors.New("controlPlaneEndpoint  can not be empty")
+	}
+
+	spec := &kubeConfigSpec{
+		ClientName: clientName,
+		APIServer:  controlPlaneEndpoint,
+		CACert:     caCert,
+		ClientCertAuth: &clientCertAuth{
+			CAKey:         caKey,
+			Organizations: organizations,
+			Validity:      validity,
+		},
+	}
+
+	return writeKubeConfigFromSpec(out, spec, clusterName)
+}
+
 // WriteKubeConfigWithToken writes a kubeconfig file - with a token as client authentication info - to the given writer.
 func WriteKubeConfigWithToken(out io.Writer, cfg Config, clientName, controlPlaneEndpoint, clusterName, token string) error {
 	// creates the KubeConfigSpecs, actualized for the current InitConfiguration
@@ -0,0 +1,116 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyAlgorithm is the algorithm and size of the private keys of generated certs.
+type KeyAlgorithm string
+
+const (
+	RSA2048   KeyAlgorithm = "RSA-2048"
+	RSA4096   KeyAlgorithm = "RSA-4096"
+	ECDSAP256 KeyAlgorithm = "ECDSA-P256"
+)
+
+// The default lifetime and key algorithm of the generated cas and certs.
+const (
+	DefaultValidity     = 100 * duration365d
+	DefaultKeyAlgorithm = RSA2048
+)
+
+// The lifetimes and the key algorithm of the cas and certs generated by this package,
+// the existing cas and the keys of renewed certs are kept.
+var (
+	CAValidity       = DefaultValidity
+	CertValidity     = DefaultValidity
+	CertKeyAlgorithm = DefaultKeyAlgorithm
+)
+
+// NewKey creates a private key of the algorithm.
+func NewKey(algorithm KeyAlgorithm) (crypto.Signer, error) {
+	switch algorithm {
+	case RSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case RSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case ECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %s, it should be %s, %s or %s", algorithm, RSA2048, RSA4096, ECDSAP256)
+	}
+}
+
+// ParseValidity parse the validity like 10y, 365d or 8760h.
+func ParseValidity(s string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(s, "y"):
+		unit = duration365d
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid validity %q, it should be like 10y, 365d or 8760h", s)
+		}
+		return d, nil
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid validity %q, it should be like 10y, 365d or 8760h", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// SetOptions set the ca validity, cert validity and key algorithm of generated certs, the empty ones are not changed.
+func SetOptions(caValidity, certValidity, keyAlgorithm string) error {
+	ca, cert := CAValidity, CertValidity
+	var err error
+	if caValidity != "" {
+		if ca, err = ParseValidity(caValidity); err != nil {
+			return err
+		}
+	}
+	if certValidity != "" {
+		if cert, err = ParseValidity(certValidity); err != nil {
+			return err
+		}
+	}
+	if cert > ca {
+		return fmt.Errorf("cert validity %s should not be longer than ca validity %s", cert, ca)
+	}
+	algorithm := CertKeyAlgorithm
+	if keyAlgorithm != "" {
+		algorithm = KeyAlgorithm(strings.ToUpper(keyAlgorithm))
+		switch algorithm {
+		case RSA2048, RSA4096, ECDSAP256:
+		default:
+			return fmt.Errorf("unsupported key algorithm %s, it should be %s, %s or %s", keyAlgorithm, RSA2048, RSA4096, ECDSAP256)
+		}
+	}
+	CAValidity, CertValidity, CertKeyAlgorithm = ca, cert, algorithm
+	return nil
+}
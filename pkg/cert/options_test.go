@@ -0,0 +1,96 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestParseValidity(t *testing.T) {
+	tests := []struct {
+		validity string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{"10y", 10 * duration365d, false},
+		{"365d", duration365d, false},
+		{"8760h", 8760 * time.Hour, false},
+		{"0y", 0, true},
+		{"1w", 0, true},
+		{"-1h", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.validity, func(t *testing.T) {
+			got, err := ParseValidity(tt.validity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseValidity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseValidity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetOptions(t *testing.T) {
+	defer func(ca, cert time.Duration, algorithm KeyAlgorithm) {
+		CAValidity, CertValidity, CertKeyAlgorithm = ca, cert, algorithm
+	}(CAValidity, CertValidity, CertKeyAlgorithm)
+
+	tests := []struct {
+		name                                   string
+		caValidity, certValidity, keyAlgorithm string
+		wantErr                                bool
+	}{
+		{"invalid key algorithm", "", "", "DSA", true},
+		{"cert longer than ca", "1y", "2y", "", true},
+		{"valid", "10y", "1y", "ecdsa-p256", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetOptions(tt.caValidity, tt.certValidity, tt.keyAlgorithm); (err != nil) != tt.wantErr {
+				t.Fatalf("SetOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if CAValidity != 10*duration365d || CertValidity != duration365d || CertKeyAlgorithm != ECDSAP256 {
+		t.Fatalf("options = %v %v %v, want 10y 1y %s", CAValidity, CertValidity, CertKeyAlgorithm, ECDSAP256)
+	}
+
+	caCert, caKey, err := NewCaCertAndKey(Config{CommonName: "kubernetes", Validity: CAValidity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key, err := NewCaCertAndKeyFromRoot(Config{
+		CommonName: "kube-apiserver",
+		Validity:   CertValidity,
+		Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caCert, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := key.(*ecdsa.PrivateKey); !ok {
+		t.Errorf("key type = %T, want ECDSA", key)
+	}
+	if d := time.Until(cert.NotAfter); d > duration365d || d < duration365d-time.Hour {
+		t.Errorf("cert expires in %v, want 1y", d)
+	}
+	if d := time.Until(caCert.NotAfter); d > 10*duration365d || d < 10*duration365d-time.Hour {
+		t.Errorf("ca expires in %v, want 10y", d)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+// CertFile is a cert or a kubeconfig with embedded client cert on the masters.
+type CertFile struct {
+	// Name is the name used by sealer cert renew, like apiserver or admin.conf.
+	Name string
+	// Path is relative to KubernetesDir.
+	Path string
+	// CA is the path of the signing ca relative to KubernetesDir, it is empty for ca itself.
+	CA           string
+	IsKubeConfig bool
+}
+
+// KeyPath return the path of the key relative to KubernetesDir.
+func (f CertFile) KeyPath() string {
+	return strings.TrimSuffix(f.Path, ".crt") + ".key"
+}
+
+var (
+	CAFiles = []CertFile{
+		{Name: "ca", Path: "pki/ca.crt"},
+		{Name: "front-proxy-ca", Path: "pki/front-proxy-ca.crt"},
+		{Name: "etcd-ca", Path: "pki/etcd/ca.crt"},
+	}
+	// LeafCertFiles are the certs renewed by sealer cert renew, the kubelet.conf is skipped if its client cert is rotated by kubelet.
+	LeafCertFiles = []CertFile{
+		{Name: "apiserver", Path: "pki/apiserver.crt", CA: "pki/ca.crt"},
+		{Name: "apiserver-kubelet-client", Path: "pki/apiserver-kubelet-client.crt", CA: "pki/ca.crt"},
+		{Name: "front-proxy-client", Path: "pki/front-proxy-client.crt", CA: "pki/front-proxy-ca.crt"},
+		{Name: "apiserver-etcd-client", Path: "pki/apiserver-etcd-client.crt", CA: "pki/etcd/ca.crt"},
+		{Name: "etcd-server", Path: "pki/etcd/server.crt", CA: "pki/etcd/ca.crt"},
+		{Name: "etcd-peer", Path: "pki/etcd/peer.crt", CA: "pki/etcd/ca.crt"},
+		{Name: "etcd-healthcheck-client", Path: "pki/etcd/healthcheck-client.crt", CA: "pki/etcd/ca.crt"},
+		{Name: "admin.conf", Path: "admin.conf", CA: "pki/ca.crt", IsKubeConfig: true},
+		{Name: "controller-manager.conf", Path: "controller-manager.conf", CA: "pki/ca.crt", IsKubeConfig: true},
+		{Name: "scheduler.conf", Path: "scheduler.conf", CA: "pki/ca.crt", IsKubeConfig: true},
+		{Name: "kubelet.conf", Path: "kubelet.conf", CA: "pki/ca.crt", IsKubeConfig: true},
+	}
+)
+
+// ParseCertFile return the cert in the data of file, it is nil if the kubeconfig has no embedded client cert.
+func ParseCertFile(f CertFile, data []byte) (*x509.Certificate, error) {
+	if f.IsKubeConfig {
+		config, err := clientcmd.Load(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig %s: %v", f.Name, err)
+		}
+		auth, err := currentAuthInfo(f.Name, config)
+		if err != nil {
+			return nil, err
+		}
+		data = auth.ClientCertificateData
+		if len(data) == 0 {
+			return nil, nil
+		}
+	}
+	certs, err := certutil.ParseCertsPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cert %s: %v", f.Name, err)
+	}
+	return certs[0], nil
+}
+
+// ParseCertAndKey parse the PEM encoded cert and RSA or ECDSA key.
+func ParseCertAndKey(certData, keyData []byte) (*x509.Certificate, crypto.Signer, error) {
+	certs, err := certutil.ParseCertsPEM(certData)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := parseSigner(keyData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certs[0], key, nil
+}
+
+func parseSigner(keyData []byte) (crypto.Signer, error) {
+	privKey, err := keyutil.ParsePrivateKeyPEM(keyData)
+	if err != nil {
+		return nil, err
+	}
+	switch k := privKey.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privKey)
+	}
+}
+
+// RenewCert sign a new cert by the ca, it keeps the subject, alt names, usages and key of the old one.
+func RenewCert(old *x509.Certificate, key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, error) {
+	cfg := Config{
+		CommonName:   old.Subject.CommonName,
+		Organization: old.Subject.Organization,
+		Validity:     CertValidity,
+		AltNames:     AltNames{DNSNames: map[string]string{}, IPs: map[string]net.IP{}},
+		Usages:       old.ExtKeyUsage,
+	}
+	for _, dns := range old.DNSNames {
+		cfg.AltNames.DNSNames[dns] = dns
+	}
+	for _, ip := range old.IPAddresses {
+		cfg.AltNames.IPs[ip.String()] = ip
+	}
+	return NewSignedCert(cfg, key, caCert, caKey)
+}
+
+// RenewKubeConfig renew the embedded client cert of the current user of kubeconfig, the others are kept.
+func RenewKubeConfig(data []byte, caCert *x509.Certificate, caKey crypto.Signer) ([]byte, error) {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+	auth, err := currentAuthInfo("kubeconfig", config)
+	if err != nil {
+		return nil, err
+	}
+	old, key, err := ParseCertAndKey(auth.ClientCertificateData, auth.ClientKeyData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client cert of kubeconfig: %v", err)
+	}
+	renewed, err := RenewCert(old, key, caCert, caKey)
+	if err != nil {
+		return nil, err
+	}
+	auth.ClientCertificateData = EncodeCertPEM(renewed)
+	return clientcmd.Write(*config)
+}
+
+func currentAuthInfo(name string, config *clientcmdapi.Config) (*clientcmdapi.AuthInfo, error) {
+	ctx, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("current context %q of kubeconfig %s is not found", config.CurrentContext, name)
+	}
+	auth, ok := config.AuthInfos[ctx.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("user %q of kubeconfig %s is not found", ctx.AuthInfo, name)
+	}
+	return auth, nil
+}
+
+// ResidualTime return the time left before notAfter like kubeadm, 364d or 23h, it is <invalid> if expired.
+func ResidualTime(notAfter, now time.Time) string {
+	d := notAfter.Sub(now)
+	switch {
+	case d <= 0:
+		return "<invalid>"
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"bytes"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRenewCert(t *testing.T) {
+	caKey, err := NewPrivateKey(x509.RSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := NewSelfSignedCACert(caKey, "kubernetes", nil, CAValidity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewPrivateKey(x509.RSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := NewSignedCert(Config{
+		CommonName:   "kube-apiserver",
+		Organization: []string{"sealer"},
+		Validity:     duration365d,
+		AltNames: AltNames{
+			DNSNames: map[string]string{"apiserver.cluster.local": "apiserver.cluster.local"},
+			IPs:      map[string]net.IP{"192.168.0.10": net.ParseIP("192.168.0.10")},
+		},
+		Usages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, key, caCert, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renewed, err := RenewCert(old, key, caCert, caKey)
+	if err != nil {
+		t.Fatalf("RenewCert() error = %v", err)
+	}
+	if err := renewed.CheckSignatureFrom(caCert); err != nil {
+		t.Errorf("renewed cert is not signed by ca: %v", err)
+	}
+	if !renewed.NotAfter.After(old.NotAfter) {
+		t.Errorf("renewed cert expires at %v, want after %v", renewed.NotAfter, old.NotAfter)
+	}
+	if renewed.Subject.CommonName != old.Subject.CommonName || renewed.Subject.Organization[0] != "sealer" {
+		t.Errorf("renewed cert subject = %v, want %v", renewed.Subject, old.Subject)
+	}
+	if len(renewed.DNSNames) != 1 || renewed.DNSNames[0] != "apiserver.cluster.local" ||
+		len(renewed.IPAddresses) != 1 || !renewed.IPAddresses[0].Equal(net.ParseIP("192.168.0.10")) {
+		t.Errorf("renewed cert alt names = %v %v, want the ones of old cert", renewed.DNSNames, renewed.IPAddresses)
+	}
+	if !bytes.Equal(renewed.RawSubjectPublicKeyInfo, old.RawSubjectPublicKeyInfo) {
+		t.Errorf("renewed cert does not keep the key of old cert")
+	}
+}
+
+func TestResidualTime(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		want     string
+	}{
+		{"days", now.Add(365*24*time.Hour + time.Minute), "365d"},
+		{"hours", now.Add(23*time.Hour + time.Minute), "23h"},
+		{"minutes", now.Add(30*time.Minute + time.Second), "30m"},
+		{"expired", now.Add(-time.Hour), "<invalid>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResidualTime(tt.notAfter, now); got != tt.want {
+				t.Errorf("ResidualTime() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
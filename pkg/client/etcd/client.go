@@ -0,0 +1,147 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/snapshot"
+	"go.uber.org/zap"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/utils/ssh"
+)
+
+const (
+	// RemoteCertDir is the dir of etcd certs on masters and etcd hosts.
+	RemoteCertDir = "/etc/kubernetes/pki/etcd"
+	CACert        = "ca.crt"
+	ClientCert    = "healthcheck-client.crt"
+	ClientKey     = "healthcheck-client.key"
+
+	dialTimeout    = 5 * time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// MemberStatus is the status of the etcd member serving an endpoint.
+type MemberStatus struct {
+	Endpoint  string
+	Version   string
+	DBSize    int64
+	IsLeader  bool
+	RaftTerm  uint64
+	RaftIndex uint64
+	Errors    []string
+	// Err is the error of getting status, the other fields are empty if it is not nil.
+	Err error
+}
+
+// GetEndpoint return the client endpoint of the etcd member on host.
+func GetEndpoint(host string) string {
+	return "https://" + net.JoinHostPort(host, "2379")
+}
+
+// FetchCerts fetch the etcd ca and the healthcheck client cert and key from host to dir.
+func FetchCerts(sshClient ssh.Interface, host, dir string) error {
+	for _, cert := range []string{ClientCert, ClientKey, CACert} {
+		if err := sshClient.Fetch(host, filepath.Join(dir, cert), filepath.Join(RemoteCertDir, cert)); err != nil {
+			return fmt.Errorf("host %s %s file does not exist, err: %v", host, cert, err)
+		}
+	}
+	return nil
+}
+
+// NewConfig return the config of client connecting to endpoints with the certs fetched to dir.
+func NewConfig(endpoints []string, dir string) (clientv3.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(dir, ClientCert), filepath.Join(dir, ClientKey))
+	if err != nil {
+		return clientv3.Config{}, fmt.Errorf("cacert or key file is not exist, err:%v", err)
+	}
+
+	caData, err := ioutil.ReadFile(filepath.Join(dir, CACert))
+	if err != nil {
+		return clientv3.Config{}, fmt.Errorf("ca certificate reading failed, err:%v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caData)
+	// #nosec
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+	}
+
+	return clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: dialTimeout,
+		TLS:         tlsConfig,
+	}, nil
+}
+
+// Snapshot save the snapshot of the first endpoint to path.
+func Snapshot(cfg clientv3.Config, path string) error {
+	lg, err := zap.NewProduction()
+	if err != nil {
+		return fmt.Errorf("get zap logger error, err:%v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg.Endpoints = cfg.Endpoints[:1]
+	if err := snapshot.Save(ctx, lg, cfg, path); err != nil {
+		return fmt.Errorf("snapshot save err: %v", err)
+	}
+	logger.Info("Snapshot saved at %s", path)
+	return nil
+}
+
+// Status return the status of the members serving endpoints, the error of an unreachable member is set in its status.
+func Status(cfg clientv3.Config) ([]MemberStatus, error) {
+	cli, err := clientv3.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connect to etcd failed, err:%v", err)
+	}
+	defer cli.Close()
+
+	var statuses []MemberStatus
+	for _, endpoint := range cfg.Endpoints {
+		status := MemberStatus{Endpoint: endpoint}
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		resp, err := cli.Status(ctx, endpoint)
+		cancel()
+		if err != nil {
+			status.Err = err
+			statuses = append(statuses, status)
+			continue
+		}
+		status.Version = resp.Version
+		status.DBSize = resp.DbSize
+		status.IsLeader = resp.Leader == resp.Header.MemberId
+		status.RaftTerm = resp.RaftTerm
+		status.RaftIndex = resp.RaftIndex
+		status.Errors = resp.Errors
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
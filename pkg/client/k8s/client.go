@@ -20,6 +20,8 @@ import (
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/kubernetes"
@@ -48,9 +50,13 @@ func Newk8sClient() (*Client, error) {
 	if home := homedir.HomeDir(); home != "" {
 		kubeconfig = filepath.Join(home, ".kube", "config")
 	}
+	return NewK8sClientFromKubeconfig(kubeconfig, "")
+}
 
+// NewK8sClientFromKubeconfig return the client of the kubeconfig file, the server in it is replaced if server is not empty.
+func NewK8sClientFromKubeconfig(kubeconfig, server string) (*Client, error) {
 	// use the current context in kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	config, err := clientcmd.BuildConfigFromFlags(server, kubeconfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build kube config")
 	}
@@ -73,6 +79,14 @@ func (c *Client) ListNodes() (*v1.NodeList, error) {
 	return nodes, nil
 }
 
+func (c *Client) GetNode(name string) (*v1.Node, error) {
+	node, err := c.client.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get cluster node %s", name)
+	}
+	return node, nil
+}
+
 func (c *Client) UpdateNode(node v1.Node) (*v1.Node, error) {
 	n, err := c.client.CoreV1().Nodes().Update(context.TODO(), &node, metav1.UpdateOptions{})
 	if err != nil {
@@ -161,6 +175,14 @@ func (c *Client) ListAllNamespacesSvcs() ([]*NamespaceSvc, error) {
 	return namespaceSvcList, nil
 }
 
+func (c *Client) ListPods(namespace string, opts metav1.ListOptions) (*v1.PodList, error) {
+	pods, err := c.client.CoreV1().Pods(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get pods in namespace %s", namespace)
+	}
+	return pods, nil
+}
+
 func (c *Client) GetEndpointsList(namespace string) (*v1.EndpointsList, error) {
 	endpointsList, err := c.client.CoreV1().Endpoints(namespace).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
@@ -205,3 +227,43 @@ func (c *Client) ListKubeSystemPodsStatus() (bool, error) {
 	}
 	return true, nil
 }
+
+// ApplyRoleBinding create or update the binding of the cluster role to subjects,
+// it is a ClusterRoleBinding if namespace is empty, or a RoleBinding in the namespace.
+func (c *Client) ApplyRoleBinding(name, namespace, clusterRole string, subjects []rbacv1.Subject) error {
+	meta := metav1.ObjectMeta{Name: name, Namespace: namespace}
+	roleRef := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRole}
+	if namespace == "" {
+		bindings := c.client.RbacV1().ClusterRoleBindings()
+		binding, err := bindings.Get(context.TODO(), name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = bindings.Create(context.TODO(), &rbacv1.ClusterRoleBinding{ObjectMeta: meta, Subjects: subjects, RoleRef: roleRef}, metav1.CreateOptions{})
+			return errors.Wrapf(err, "failed to create cluster role binding %s", name)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to get cluster role binding %s", name)
+		}
+		if binding.RoleRef != roleRef {
+			return errors.Errorf("cluster role binding %s already exists with role %s", name, binding.RoleRef.Name)
+		}
+		binding.Subjects = subjects
+		_, err = bindings.Update(context.TODO(), binding, metav1.UpdateOptions{})
+		return errors.Wrapf(err, "failed to update cluster role binding %s", name)
+	}
+
+	bindings := c.client.RbacV1().RoleBindings(namespace)
+	binding, err := bindings.Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = bindings.Create(context.TODO(), &rbacv1.RoleBinding{ObjectMeta: meta, Subjects: subjects, RoleRef: roleRef}, metav1.CreateOptions{})
+		return errors.Wrapf(err, "failed to create role binding %s in namespace %s", name, namespace)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get role binding %s in namespace %s", name, namespace)
+	}
+	if binding.RoleRef != roleRef {
+		return errors.Errorf("role binding %s in namespace %s already exists with role %s", name, namespace, binding.RoleRef.Name)
+	}
+	binding.Subjects = subjects
+	_, err = bindings.Update(context.TODO(), binding, metav1.UpdateOptions{})
+	return errors.Wrapf(err, "failed to update role binding %s in namespace %s", name, namespace)
+}
@@ -0,0 +1,260 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	k8sV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/runtime"
+	v1 "github.com/alibaba/sealer/types/api/v1"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+	"github.com/alibaba/sealer/utils"
+)
+
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// ConvertV1ToV2 rewrite the multi-document Clusterfile data with v1 Cluster to v2 format.
+// Config, Plugin and other documents are kept as they are, the certSANS and network of v1 Cluster are moved to
+// the ClusterConfiguration document and the env. It returns the warnings of fields which can not be represented in v2.
+func ConvertV1ToV2(data []byte) ([]byte, []string, error) {
+	var (
+		docs          [][]byte
+		warnings      []string
+		clusterConfig map[string]interface{}
+		// the index of ClusterConfiguration document in docs, -1 if not exist.
+		clusterConfigIndex = -1
+		clusterV1          *v1.Cluster
+		clusterComments    []byte
+	)
+
+	for _, raw := range documentSeparator.Split(string(data), -1) {
+		doc := []byte(strings.Trim(raw, "\n"))
+		metaType := k8sV1.TypeMeta{}
+		if err := yaml.Unmarshal(doc, &metaType); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode document: %v", err)
+		}
+		switch metaType.Kind {
+		case "":
+			// empty or comment only document.
+			if len(bytes.TrimSpace(doc)) != 0 {
+				docs = append(docs, doc)
+			}
+			continue
+		case common.Cluster:
+			if metaType.APIVersion == typeV2 {
+				warnings = append(warnings, "Cluster is already in v2 format, keep it as it is")
+				docs = append(docs, doc)
+				continue
+			}
+			if clusterV1 != nil {
+				return nil, nil, fmt.Errorf("more than one Cluster document found")
+			}
+			clusterV1 = &v1.Cluster{}
+			if err := yaml.Unmarshal(doc, clusterV1); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode v1 Cluster: %v", err)
+			}
+			clusterComments = leadingComments(doc)
+			// the converted Cluster is rendered after all the documents are read.
+			docs = append(docs, nil)
+			continue
+		case runtime.ClusterConfiguration:
+			clusterConfig = map[string]interface{}{}
+			if err := yaml.Unmarshal(doc, &clusterConfig); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode ClusterConfiguration: %v", err)
+			}
+			clusterConfigIndex = len(docs)
+		}
+		docs = append(docs, doc)
+	}
+
+	if clusterV1 == nil {
+		return data, warnings, nil
+	}
+
+	cluster, convertWarnings := convertCluster(clusterV1)
+	warnings = append(warnings, convertWarnings...)
+	clusterData, err := marshalDocument(cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range docs {
+		if docs[i] == nil {
+			docs[i] = append(clusterComments, clusterData...)
+		}
+	}
+
+	if clusterV1.Spec.Network == (v1.Network{}) && len(clusterV1.Spec.CertSANS) == 0 {
+		return joinDocuments(docs), warnings, nil
+	}
+	if clusterConfig == nil {
+		clusterConfig = map[string]interface{}{
+			"apiVersion": runtime.KubeadmV1beta2,
+			"kind":       runtime.ClusterConfiguration,
+		}
+	}
+	warnings = append(warnings, mergeClusterConfiguration(clusterConfig, clusterV1)...)
+	clusterConfigData, err := marshalDocument(clusterConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if clusterConfigIndex < 0 {
+		docs = append(docs, clusterConfigData)
+	} else {
+		docs[clusterConfigIndex] = clusterConfigData
+	}
+	return joinDocuments(docs), warnings, nil
+}
+
+func convertCluster(clusterV1 *v1.Cluster) (*v2.Cluster, []string) {
+	var warnings []string
+	cluster := clusterFromV1(clusterV1)
+
+	if clusterV1.Spec.Provider != "" {
+		warnings = append(warnings, fmt.Sprintf("spec.provider %s is dropped, v2 Cluster is always applied on existing hosts", clusterV1.Spec.Provider))
+	}
+	for _, h := range []struct {
+		role  string
+		hosts v1.Hosts
+	}{{common.MASTER, clusterV1.Spec.Masters}, {common.NODE, clusterV1.Spec.Nodes}} {
+		path := "spec." + h.role + "s"
+		if h.hosts.CPU != "" || h.hosts.Memory != "" || h.hosts.SystemDisk != "" || len(h.hosts.DataDisks) != 0 {
+			warnings = append(warnings, fmt.Sprintf("%s cpu, memory, systemDisk and dataDisks are dropped, they are only used by infra providers", path))
+		}
+		if len(h.hosts.IPList) == 0 && h.hosts.Count != "" {
+			warnings = append(warnings, fmt.Sprintf("%s.count %s can not be represented, add the ips of %s hosts manually", path, h.hosts.Count, h.role))
+		}
+	}
+
+	// the cluster image templates read the network from env.
+	network := clusterV1.Spec.Network
+	if network.PodCIDR != "" && !hasEnv(cluster.Spec.Env, runtime.PodCIDR) {
+		cluster.Spec.Env = append(cluster.Spec.Env, fmt.Sprintf("%s=%s", runtime.PodCIDR, network.PodCIDR))
+	}
+	if network.SvcCIDR != "" && !hasEnv(cluster.Spec.Env, runtime.SvcCIDR) {
+		cluster.Spec.Env = append(cluster.Spec.Env, fmt.Sprintf("%s=%s", runtime.SvcCIDR, network.SvcCIDR))
+	}
+	return cluster, warnings
+}
+
+// mergeClusterConfiguration set the networking and apiServer.certSANs of ClusterConfiguration from v1 Cluster,
+// the existing networking is kept if it is different from the v1 one.
+func mergeClusterConfiguration(clusterConfig map[string]interface{}, clusterV1 *v1.Cluster) []string {
+	var warnings []string
+	getMap := func(key string) map[string]interface{} {
+		m, ok := clusterConfig[key].(map[string]interface{})
+		if !ok {
+			m = map[string]interface{}{}
+			clusterConfig[key] = m
+		}
+		return m
+	}
+	setNetworking := func(key, value string) {
+		if value == "" {
+			return
+		}
+		networking := getMap("networking")
+		if current, ok := networking[key].(string); ok && current != "" && current != value {
+			warnings = append(warnings, fmt.Sprintf("ClusterConfiguration networking.%s %s is kept, the v1 network %s is dropped", key, current, value))
+			return
+		}
+		networking[key] = value
+	}
+	setNetworking("podSubnet", clusterV1.Spec.Network.PodCIDR)
+	setNetworking("serviceSubnet", clusterV1.Spec.Network.SvcCIDR)
+
+	if len(clusterV1.Spec.CertSANS) != 0 {
+		apiServer := getMap("apiServer")
+		var certSANs []string
+		if current, ok := apiServer["certSANs"].([]interface{}); ok {
+			for _, san := range current {
+				certSANs = append(certSANs, fmt.Sprint(san))
+			}
+		}
+		apiServer["certSANs"] = utils.RemoveDuplicate(append(certSANs, clusterV1.Spec.CertSANS...))
+	}
+	return warnings
+}
+
+// leadingComments return the comment lines before the content of document, like the license header.
+func leadingComments(doc []byte) []byte {
+	var comments []byte
+	for _, line := range bytes.SplitAfter(doc, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) != 0 && trimmed[0] != '#' {
+			break
+		}
+		comments = append(comments, line...)
+	}
+	return comments
+}
+
+func hasEnv(env []string, key string) bool {
+	for _, e := range env {
+		if strings.HasPrefix(e, key+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// marshalDocument marshal the object to yaml without the empty fields, like "creationTimestamp: null" and "ssh: {}".
+func marshalDocument(obj interface{}) ([]byte, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	pruneEmpty(m)
+	out, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(out, "\n"), nil
+}
+
+func pruneEmpty(m map[string]interface{}) {
+	for k, v := range m {
+		switch value := v.(type) {
+		case nil:
+			delete(m, k)
+		case map[string]interface{}:
+			pruneEmpty(value)
+			if len(value) == 0 {
+				delete(m, k)
+			}
+		case []interface{}:
+			for _, item := range value {
+				if im, ok := item.(map[string]interface{}); ok {
+					pruneEmpty(im)
+				}
+			}
+		}
+	}
+}
+
+func joinDocuments(docs [][]byte) []byte {
+	return append(bytes.Join(docs, []byte("\n---\n")), '\n')
+}
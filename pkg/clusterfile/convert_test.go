@@ -0,0 +1,141 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/runtime"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+const v1Clusterfile = `# my cluster
+apiVersion: sealer.aliyun.com/v1alpha1
+kind: Cluster
+metadata:
+  name: my-cluster
+spec:
+  image: kubernetes:v1.19.8
+  provider: BAREMETAL
+  ssh:
+    passwd: xxx
+  network:
+    podCIDR: 100.64.0.0/10
+    svcCIDR: 10.96.0.0/22
+  certSANS:
+    - aliyun-inc.com
+  masters:
+    ipList:
+      - 192.168.0.2
+  nodes:
+    cpu: 4
+    ipList:
+      - 192.168.0.3
+---
+apiVersion: sealer.aliyun.com/v1alpha1
+kind: Config
+metadata:
+  name: redis-config
+spec:
+  path: etc/redis.yaml
+  data: |
+    user: root
+`
+
+func TestConvertV1ToV2(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantWarnings int
+		wantDocs     int
+		wantPodCIDR  string
+		wantCertSANs []string
+	}{
+		{
+			name:         "new ClusterConfiguration",
+			data:         v1Clusterfile,
+			wantWarnings: 2,
+			wantDocs:     3,
+			wantPodCIDR:  "100.64.0.0/10",
+			wantCertSANs: []string{"aliyun-inc.com"},
+		},
+		{
+			name: "merge ClusterConfiguration",
+			data: v1Clusterfile + `---
+apiVersion: kubeadm.k8s.io/v1beta2
+kind: ClusterConfiguration
+networking:
+  podSubnet: 172.16.0.0/16
+apiServer:
+  certSANs:
+    - 10.0.0.2
+`,
+			wantWarnings: 3,
+			wantDocs:     3,
+			wantPodCIDR:  "172.16.0.0/16",
+			wantCertSANs: []string{"10.0.0.2", "aliyun-inc.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ConvertV1ToV2([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("ConvertV1ToV2() error = %v", err)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("ConvertV1ToV2() warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+			if !strings.HasPrefix(string(got), "# my cluster\n") {
+				t.Errorf("ConvertV1ToV2() should keep the leading comments, got %s", got)
+			}
+			docs := documentSeparator.Split(string(got), -1)
+			if len(docs) != tt.wantDocs {
+				t.Fatalf("ConvertV1ToV2() got %d documents, want %d", len(docs), tt.wantDocs)
+			}
+			if !strings.Contains(docs[1], "path: etc/redis.yaml") {
+				t.Errorf("ConvertV1ToV2() should keep Config document, got %s", docs[1])
+			}
+
+			cluster, err := GetClusterFromDataCompatV1([]byte(docs[0]))
+			if err != nil {
+				t.Fatalf("failed to decode converted cluster: %v", err)
+			}
+			wantHosts := []v2.Host{
+				{IPS: []string{"192.168.0.2"}, Roles: []string{common.MASTER}},
+				{IPS: []string{"192.168.0.3"}, Roles: []string{common.NODE}},
+			}
+			if !reflect.DeepEqual(cluster.Spec.Hosts, wantHosts) {
+				t.Errorf("converted hosts = %v, want %v", cluster.Spec.Hosts, wantHosts)
+			}
+			if cluster.Spec.SSH.Passwd != "xxx" || cluster.Spec.Image != "kubernetes:v1.19.8" {
+				t.Errorf("converted cluster spec = %v", cluster.Spec)
+			}
+
+			kubeadmConfig, err := runtime.LoadKubeadmConfigs(string(got), runtime.DecodeCRDFromString)
+			if err != nil {
+				t.Fatalf("failed to decode converted ClusterConfiguration: %v", err)
+			}
+			if kubeadmConfig.Networking.PodSubnet != tt.wantPodCIDR || kubeadmConfig.Networking.ServiceSubnet != "10.96.0.0/22" {
+				t.Errorf("converted networking = %v", kubeadmConfig.Networking)
+			}
+			if !reflect.DeepEqual(kubeadmConfig.APIServer.CertSANs, tt.wantCertSANs) {
+				t.Errorf("converted certSANs = %v, want %v", kubeadmConfig.APIServer.CertSANs, tt.wantCertSANs)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	k8sV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/utils"
+)
+
+// JSONPatchKind is the kind of overlay document which applies RFC 6902 operations to the target document,
+// other overlay documents are merged into the base document of the same kind and name as RFC 7386 merge patches,
+// except that the spec.hosts of Cluster are merged by ip.
+const JSONPatchKind = "JSONPatch"
+
+// deleteDirective is the value of "$patch" of an overlay host to remove the base host, like strategic merge patch.
+const deleteDirective = "delete"
+
+const renderedClusterfileName = "Clusterfile.rendered"
+
+type JSONPatch struct {
+	k8sV1.TypeMeta `json:",inline"`
+	Target         PatchTarget     `json:"target"`
+	Patch          json.RawMessage `json:"patch"`
+}
+
+type PatchTarget struct {
+	Kind string `json:"kind"`
+	Name string `json:"name,omitempty"`
+}
+
+type document struct {
+	kind string
+	name string
+	// raw is the origin text, it is kept as it is if the document is not patched,
+	// so that the env templates in it are not touched.
+	raw     []byte
+	patched []byte
+}
+
+func (d *document) json() ([]byte, error) {
+	if d.patched != nil {
+		return d.patched, nil
+	}
+	return yaml.YAMLToJSON(d.raw)
+}
+
+func parseDocuments(data []byte) []*document {
+	var docs []*document
+	for _, raw := range documentSeparator.Split(string(data), -1) {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		doc := &document{raw: []byte(strings.Trim(raw, "\n"))}
+		meta := struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		}{}
+		// the documents can not be parsed before env render are kept but can not be patched.
+		if err := yaml.Unmarshal(doc.raw, &meta); err == nil {
+			doc.kind, doc.name = meta.Kind, meta.Metadata.Name
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func findDocument(docs []*document, kind, name string) *document {
+	for _, doc := range docs {
+		if doc.kind == kind && doc.name == name {
+			return doc
+		}
+	}
+	return nil
+}
+
+// Render apply the overlays to the base Clusterfile in order, documents are matched by kind and metadata.name,
+// an overlay document matches no base document is added.
+func Render(base string, overlays ...string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(base))
+	if err != nil {
+		return nil, err
+	}
+	docs := parseDocuments(data)
+	for _, overlay := range overlays {
+		data, err := ioutil.ReadFile(filepath.Clean(overlay))
+		if err != nil {
+			return nil, err
+		}
+		for _, patch := range parseDocuments(data) {
+			if docs, err = applyPatch(docs, patch); err != nil {
+				return nil, fmt.Errorf("failed to apply overlay %s: %v", overlay, err)
+			}
+		}
+	}
+
+	var out [][]byte
+	for _, doc := range docs {
+		if doc.patched == nil {
+			out = append(out, doc.raw)
+			continue
+		}
+		y, err := yaml.JSONToYAML(doc.patched)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, bytes.TrimRight(y, "\n"))
+	}
+	return joinDocuments(out), nil
+}
+
+func applyPatch(docs []*document, patch *document) ([]*document, error) {
+	if patch.kind == "" {
+		return nil, fmt.Errorf("overlay document has no kind:\n%s", patch.raw)
+	}
+
+	if patch.kind == JSONPatchKind {
+		p := &JSONPatch{}
+		if err := yaml.Unmarshal(patch.raw, p); err != nil {
+			return nil, err
+		}
+		target := findDocument(docs, p.Target.Kind, p.Target.Name)
+		if target == nil {
+			return nil, fmt.Errorf("target %s %q of %s not found", p.Target.Kind, p.Target.Name, JSONPatchKind)
+		}
+		ops, err := jsonpatch.DecodePatch(p.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid patch of %s %q: %v", p.Target.Kind, p.Target.Name, err)
+		}
+		original, err := target.json()
+		if err != nil {
+			return nil, err
+		}
+		if target.patched, err = ops.Apply(original); err != nil {
+			return nil, fmt.Errorf("failed to patch %s %q: %v", p.Target.Kind, p.Target.Name, err)
+		}
+		return docs, nil
+	}
+
+	target := findDocument(docs, patch.kind, patch.name)
+	if target == nil {
+		return append(docs, patch), nil
+	}
+	original, err := target.json()
+	if err != nil {
+		return nil, err
+	}
+	mergePatch, err := patch.json()
+	if err != nil {
+		return nil, err
+	}
+	if patch.kind == common.Cluster {
+		if mergePatch, err = mergeHosts(original, mergePatch); err != nil {
+			return nil, fmt.Errorf("failed to merge hosts of %s %q: %v", patch.kind, patch.name, err)
+		}
+	}
+	if target.patched, err = jsonpatch.MergePatch(original, mergePatch); err != nil {
+		return nil, fmt.Errorf("failed to merge %s %q: %v", patch.kind, patch.name, err)
+	}
+	return docs, nil
+}
+
+// mergeHosts replace the spec.hosts of Cluster merge patch with the base hosts merged with it by ip:
+// a host sharing an ip with a base host is merged into it as a merge patch and the ips are added to it,
+// the one with "$patch: delete" removes the base host, and the others are appended.
+func mergeHosts(original, patch []byte) ([]byte, error) {
+	var patchDoc map[string]interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, err
+	}
+	spec, _ := patchDoc["spec"].(map[string]interface{})
+	patchHosts, ok := spec["hosts"].([]interface{})
+	if !ok {
+		return patch, nil
+	}
+	base := struct {
+		Spec struct {
+			Hosts []interface{} `json:"hosts"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(original, &base); err != nil {
+		return nil, err
+	}
+	hosts := base.Spec.Hosts
+	for _, h := range patchHosts {
+		host, ok := h.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid host %v", h)
+		}
+		index := findHost(hosts, hostIPs(host))
+		directive := host["$patch"]
+		delete(host, "$patch")
+		switch {
+		case directive == deleteDirective:
+			if index >= 0 {
+				hosts = append(hosts[:index], hosts[index+1:]...)
+			}
+		case index < 0:
+			hosts = append(hosts, host)
+		default:
+			merged, err := mergeHost(hosts[index], host)
+			if err != nil {
+				return nil, err
+			}
+			hosts[index] = merged
+		}
+	}
+	spec["hosts"] = hosts
+	return json.Marshal(patchDoc)
+}
+
+func mergeHost(base, patch interface{}) (interface{}, error) {
+	baseData, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	patchData, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	data, err := jsonpatch.MergePatch(baseData, patchData)
+	if err != nil {
+		return nil, err
+	}
+	var merged map[string]interface{}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	// the ips of base host are kept, the overlay host need not list all of them.
+	merged["ips"] = utils.RemoveDuplicate(append(hostIPs(base), hostIPs(patch)...))
+	return merged, nil
+}
+
+func hostIPs(host interface{}) []string {
+	var ips []string
+	if m, ok := host.(map[string]interface{}); ok {
+		list, _ := m["ips"].([]interface{})
+		for _, ip := range list {
+			if s, ok := ip.(string); ok {
+				ips = append(ips, s)
+			}
+		}
+	}
+	return ips
+}
+
+// findHost return the index of the host sharing any of ips, -1 if not found.
+func findHost(hosts []interface{}, ips []string) int {
+	for i, host := range hosts {
+		for _, ip := range hostIPs(host) {
+			if utils.InList(ip, ips) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// RenderToWorkDir render the overlays to the base Clusterfile and save the result in the cluster work dir,
+// the later commands like join and delete reload the Clusterfile from it.
+func RenderToWorkDir(base string, overlays ...string) (string, error) {
+	data, err := Render(base, overlays...)
+	if err != nil {
+		return "", err
+	}
+	var cluster string
+	for _, doc := range parseDocuments(data) {
+		if doc.kind == common.Cluster {
+			cluster = doc.name
+			break
+		}
+	}
+	if cluster == "" {
+		return "", fmt.Errorf("no named Cluster found in %s", base)
+	}
+	path := filepath.Join(common.GetClusterWorkDir(cluster), renderedClusterfileName)
+	if err := utils.WriteFile(path, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,185 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const baseClusterfile = `apiVersion: sealer.cloud/v2
+kind: Cluster
+metadata:
+  name: my-cluster
+spec:
+  image: kubernetes:v1.19.8
+  env:
+    - docker-dir={{ .DockerDir }}
+  hosts:
+    - ips: [ 192.168.0.2 ]
+      roles: [ master ]
+---
+apiVersion: sealer.aliyun.com/v1alpha1
+kind: Config
+metadata:
+  name: redis-config
+spec:
+  path: etc/redis.yaml
+  data: |
+    user: {{ .User }}
+---
+apiVersion: kubeadm.k8s.io/v1beta2
+kind: ClusterConfiguration
+networking:
+  podSubnet: 100.64.0.0/10
+`
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		overlays []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name: "merge patch",
+			overlays: []string{`apiVersion: kubeadm.k8s.io/v1beta2
+kind: ClusterConfiguration
+networking:
+  serviceSubnet: 10.96.0.0/22
+---
+apiVersion: sealer.aliyun.com/v1alpha1
+kind: Config
+metadata:
+  name: prod-config
+spec:
+  path: etc/prod.yaml
+`},
+			want: []string{
+				"    user: {{ .User }}",
+				"  podSubnet: 100.64.0.0/10\n  serviceSubnet: 10.96.0.0/22",
+				"  name: prod-config",
+			},
+		},
+		{
+			name: "json patch after merge patch",
+			overlays: []string{`apiVersion: sealer.cloud/v2
+kind: Cluster
+metadata:
+  name: my-cluster
+spec:
+  image: kubernetes:v1.20.4
+`, `apiVersion: sealer.cloud/v2
+kind: JSONPatch
+target:
+  kind: Cluster
+  name: my-cluster
+patch:
+  - op: add
+    path: /spec/hosts/-
+    value:
+      ips: [ 192.168.0.3 ]
+      roles: [ node ]
+`},
+			want: []string{
+				"  image: kubernetes:v1.20.4",
+				"  - ips:\n    - 192.168.0.3\n    roles:\n    - node",
+				"  - docker-dir={{ .DockerDir }}",
+			},
+		},
+		{
+			name: "merge hosts by ip",
+			overlays: []string{`apiVersion: sealer.cloud/v2
+kind: Cluster
+metadata:
+  name: my-cluster
+spec:
+  hosts:
+    - ips: [ 192.168.0.2 ]
+      env: [ key=value ]
+    - ips: [ 192.168.0.3 ]
+      roles: [ node ]
+`},
+			want: []string{
+				"  - env:\n    - key=value\n    ips:\n    - 192.168.0.2\n    roles:\n    - master",
+				"  - ips:\n    - 192.168.0.3\n    roles:\n    - node",
+			},
+		},
+		{
+			name: "delete host",
+			overlays: []string{`apiVersion: sealer.cloud/v2
+kind: Cluster
+metadata:
+  name: my-cluster
+spec:
+  hosts:
+    - ips: [ 192.168.0.2 ]
+      $patch: delete
+    - ips: [ 192.168.0.4 ]
+      roles: [ master ]
+`},
+			want: []string{
+				"  hosts:\n  - ips:\n    - 192.168.0.4\n    roles:\n    - master\n  image",
+			},
+		},
+		{
+			name: "json patch target not found",
+			overlays: []string{`apiVersion: sealer.cloud/v2
+kind: JSONPatch
+target:
+  kind: Cluster
+  name: other-cluster
+patch:
+  - op: remove
+    path: /spec/env
+`},
+			wantErr: true,
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "Clusterfile")
+	if err := ioutil.WriteFile(base, []byte(baseClusterfile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var overlays []string
+			for i, data := range tt.overlays {
+				overlay := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+string(rune('0'+i)))
+				if err := ioutil.WriteFile(overlay, []byte(data), 0600); err != nil {
+					t.Fatal(err)
+				}
+				overlays = append(overlays, overlay)
+			}
+			got, err := Render(base, overlays...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("Render() = %s, want contains %s", got, want)
+				}
+			}
+		})
+	}
+}
@@ -81,6 +81,11 @@ func GetDefaultCluster() (cluster *v2.Cluster, err error) {
 	return GetClusterFromFile(filepath)
 }
 
+// IsV1Cluster return true if the Cluster document of apiVersion is decoded as v1 by GetClusterFromDataCompatV1.
+func IsV1Cluster(apiVersion string) bool {
+	return apiVersion == typeV1
+}
+
 func GetClusterFromDataCompatV1(data []byte) (*v2.Cluster, error) {
 	var cluster *v2.Cluster
 	metaType := k8sV1.TypeMeta{}
@@ -92,25 +97,11 @@ func GetClusterFromDataCompatV1(data []byte) (*v2.Cluster, error) {
 		return nil, fmt.Errorf("not found type cluster from: \n%s", data)
 	}
 	if metaType.APIVersion == typeV1 {
-		cluster = &v2.Cluster{}
 		clusterV1 := &v1.Cluster{}
 		if err := yaml.Unmarshal(data, &clusterV1); err != nil {
 			return nil, err
 		}
-		var hosts []v2.Host
-		if len(clusterV1.Spec.Masters.IPList) != 0 {
-			hosts = append(hosts, v2.Host{IPS: clusterV1.Spec.Masters.IPList, Roles: []string{common.MASTER}})
-		}
-		if len(clusterV1.Spec.Nodes.IPList) != 0 {
-			hosts = append(hosts, v2.Host{IPS: clusterV1.Spec.Nodes.IPList, Roles: []string{common.NODE}})
-		}
-		cluster.APIVersion = typeV2
-		cluster.Spec.SSH = clusterV1.Spec.SSH
-		cluster.Spec.Env = clusterV1.Spec.Env
-		cluster.Spec.Hosts = hosts
-		cluster.Spec.Image = clusterV1.Spec.Image
-		cluster.Name = clusterV1.Name
-		cluster.Kind = clusterV1.Kind
+		cluster = clusterFromV1(clusterV1)
 	} else {
 		c, err := runtime.DecodeCRDFromString(string(data), common.Cluster)
 		if err != nil {
@@ -122,3 +113,26 @@ func GetClusterFromDataCompatV1(data []byte) (*v2.Cluster, error) {
 	}
 	return cluster, nil
 }
+
+// clusterFromV1 returns the v2 Cluster with the metadata, image, ssh, env and host ips of v1 Cluster.
+func clusterFromV1(clusterV1 *v1.Cluster) *v2.Cluster {
+	cluster := &v2.Cluster{}
+	var hosts []v2.Host
+	if len(clusterV1.Spec.Masters.IPList) != 0 {
+		hosts = append(hosts, v2.Host{IPS: clusterV1.Spec.Masters.IPList, Roles: []string{common.MASTER}})
+	}
+	if len(clusterV1.Spec.Nodes.IPList) != 0 {
+		hosts = append(hosts, v2.Host{IPS: clusterV1.Spec.Nodes.IPList, Roles: []string{common.NODE}})
+	}
+	cluster.APIVersion = typeV2
+	cluster.Kind = common.Cluster
+	cluster.Name = clusterV1.Name
+	cluster.Namespace = clusterV1.Namespace
+	cluster.Labels = clusterV1.Labels
+	cluster.Annotations = clusterV1.Annotations
+	cluster.Spec.SSH = clusterV1.Spec.SSH
+	cluster.Spec.Env = clusterV1.Spec.Env
+	cluster.Spec.Hosts = hosts
+	cluster.Spec.Image = clusterV1.Spec.Image
+	return cluster
+}
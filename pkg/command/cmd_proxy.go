@@ -24,7 +24,7 @@ import (
   exec some commands on remote host like create ipvs rules or add routers.
   create certs on master1 master2.
 */
-func RemoteCerts(altNames []string, hostIP, hostName, serviceCIRD, DNSDomain string) string {
+func RemoteCerts(altNames []string, hostIP, hostName, serviceCIRD, DNSDomain string, caValidity, certValidity, keyAlgorithm string) string {
 	cmd := "seautil certs "
 	if hostIP != "" {
 		cmd += fmt.Sprintf(" --node-ip %s", hostIP)
@@ -42,6 +42,18 @@ func RemoteCerts(altNames []string, hostIP, hostName, serviceCIRD, DNSDomain str
 		cmd += fmt.Sprintf(" --dns-domain %s", DNSDomain)
 	}
 
+	if caValidity != "" {
+		cmd += fmt.Sprintf(" --ca-validity %s", caValidity)
+	}
+
+	if certValidity != "" {
+		cmd += fmt.Sprintf(" --cert-validity %s", certValidity)
+	}
+
+	if keyAlgorithm != "" {
+		cmd += fmt.Sprintf(" --key-algorithm %s", keyAlgorithm)
+	}
+
 	for _, name := range append(altNames, common.APIServerDomain) {
 		if name != "" {
 			cmd += fmt.Sprintf(" --alt-names %s", name)
@@ -88,14 +88,9 @@ func (c *Dumper) WriteFiles() (err error) {
 		return nil
 	}
 	for _, config := range c.Configs {
-		configData := []byte(config.Spec.Data)
-		configPath := filepath.Join(common.DefaultMountCloudImageDir(c.ClusterName), config.Spec.Path)
-		//only the YAML format is supported
-		if config.Spec.Strategy == Merge {
-			configData, err = getMergeConfigData(configPath, configData)
-			if err != nil {
-				return err
-			}
+		configPath, configData, err := RenderConfig(c.ClusterName, config)
+		if err != nil {
+			return err
 		}
 		err = utils.WriteFile(configPath, configData)
 		if err != nil {
@@ -106,6 +101,21 @@ func (c *Dumper) WriteFiles() (err error) {
 	return nil
 }
 
+// RenderConfig return the path in the mounted cluster image and the data which Dump will write for config.
+func RenderConfig(clusterName string, config v1.Config) (string, []byte, error) {
+	configData := []byte(config.Spec.Data)
+	configPath := filepath.Join(common.DefaultMountCloudImageDir(clusterName), config.Spec.Path)
+	//only the YAML format is supported
+	if config.Spec.Strategy == Merge {
+		data, err := getMergeConfigData(configPath, configData)
+		if err != nil {
+			return "", nil, err
+		}
+		configData = data
+	}
+	return configPath, configData, nil
+}
+
 //merge the contents of data into the path file
 func getMergeConfigData(path string, data []byte) ([]byte, error) {
 	var configs [][]byte
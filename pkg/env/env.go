@@ -21,6 +21,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/secret"
 	v2 "github.com/alibaba/sealer/types/api/v2"
 )
 
@@ -46,9 +48,14 @@ func NewEnvProcessor(cluster *v2.Cluster) Interface {
 	return &processor{cluster}
 }
 
+// WrapperShell use the env resolved as much as possible, Validate should be called before to stop on the env failed to resolve.
 func (p *processor) WrapperShell(host, shell string) string {
+	hostEnv, err := p.getHostEnv(host)
+	if err != nil {
+		logger.Error("failed to get env of %s: %v", host, err)
+	}
 	var env string
-	for k, v := range p.getHostEnv(host) {
+	for k, v := range hostEnv {
 		switch value := v.(type) {
 		case []string:
 			env = fmt.Sprintf("%s%s=(%s) ", env, k, strings.Join(value, " "))
@@ -63,6 +70,10 @@ func (p *processor) WrapperShell(host, shell string) string {
 }
 
 func (p *processor) RenderAll(host, dir string) error {
+	hostEnv, err := p.getHostEnv(host)
+	if err != nil {
+		return err
+	}
 	return filepath.Walk(dir, func(path string, info os.FileInfo, errIn error) error {
 		if errIn != nil {
 			return errIn
@@ -81,7 +92,7 @@ func (p *processor) RenderAll(host, dir string) error {
 		if err != nil {
 			return fmt.Errorf("failed to create template: %s %v", path, err)
 		}
-		if err := t.Execute(writer, p.getHostEnv(host)); err != nil {
+		if err := t.Execute(writer, hostEnv); err != nil {
 			return fmt.Errorf("failed to render env template: %s %v", path, err)
 		}
 		return nil
@@ -102,21 +113,52 @@ func mergeList(hostEnv, globalEnv map[string]interface{}) map[string]interface{}
 }
 
 // Merge the host ENV and global env, the host env will overwrite cluster.Spec.Env
-func (p *processor) getHostEnv(hostIP string) (env map[string]interface{}) {
-	hostEnv, globalEnv := map[string]interface{}{}, ConvertEnv(p.Spec.Env)
+func (p *processor) getHostEnv(hostIP string) (map[string]interface{}, error) {
+	hostEnv := map[string]interface{}{}
+	globalEnv, err := ConvertEnv(p.Spec.Env)
+	if err != nil {
+		return globalEnv, err
+	}
 
 	for _, host := range p.Spec.Hosts {
 		for _, ip := range host.IPS {
 			if ip == hostIP {
-				hostEnv = ConvertEnv(host.Env)
+				if hostEnv, err = ConvertEnv(host.Env); err != nil {
+					return mergeList(hostEnv, globalEnv), fmt.Errorf("host %s: %v", hostIP, err)
+				}
 			}
 		}
 	}
-	return mergeList(hostEnv, globalEnv)
+	return mergeList(hostEnv, globalEnv), nil
+}
+
+// Validate resolve the env of cluster and hosts, the error of the secret sources failed to resolve is returned,
+// so the apply stops before rendering or running anything with an empty value.
+func Validate(cluster *v2.Cluster) error {
+	if _, err := ConvertEnv(cluster.Spec.Env); err != nil {
+		return err
+	}
+	for _, host := range cluster.Spec.Hosts {
+		if _, err := ConvertEnv(host.Env); err != nil {
+			return fmt.Errorf("host %v: %v", host.IPS, err)
+		}
+	}
+	return nil
+}
+
+// StringGetter return the getter of the string values of envList converted by ConvertEnv, the value of an env not set
+// or set to a list is empty. The error of the env failed to resolve is returned with the getter of the resolved ones.
+func StringGetter(envList []string) (func(key string) string, error) {
+	envs, err := ConvertEnv(envList)
+	return func(key string) string {
+		value, _ := envs[key].(string)
+		return value
+	}, err
 }
 
 // ConvertEnv []string to map[string]interface{}, example [IP=127.0.0.1,IP=192.160.0.2,Key=value] will convert to {IP:[127.0.0.1,192.168.0.2],key:value}
-func ConvertEnv(envList []string) (env map[string]interface{}) {
+// The env failed to resolve or decrypt is left out, and the first of the errors is returned with the env resolved.
+func ConvertEnv(envList []string) (env map[string]interface{}, err error) {
 	temp := make(map[string][]string)
 	env = make(map[string]interface{})
 
@@ -125,7 +167,26 @@ func ConvertEnv(envList []string) (env map[string]interface{}) {
 		if kv = strings.SplitN(e, "=", 2); len(kv) != 2 {
 			continue
 		}
-		temp[kv[0]] = append(temp[kv[0]], strings.Split(kv[1], ";")...)
+		// the value of a secret reference like ${env:NAME} is not split.
+		if _, ok := secret.ParseRef(kv[1]); ok {
+			value, resolveErr := secret.ResolveValue(kv[1])
+			if resolveErr != nil {
+				if err == nil {
+					err = fmt.Errorf("failed to resolve env %s: %v", kv[0], resolveErr)
+				}
+				continue
+			}
+			temp[kv[0]] = append(temp[kv[0]], value)
+			continue
+		}
+		value, decryptErr := secret.Decrypt(kv[1])
+		if decryptErr != nil {
+			if err == nil {
+				err = fmt.Errorf("failed to decrypt env %s: %v", kv[0], decryptErr)
+			}
+			continue
+		}
+		temp[kv[0]] = append(temp[kv[0]], strings.Split(value, ";")...)
 	}
 
 	for k, v := range temp {
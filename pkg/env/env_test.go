@@ -30,22 +30,46 @@ func Test_convertEnv(t *testing.T) {
 		name    string
 		args    args
 		wantEnv map[string]interface{}
+		wantErr bool
 	}{
 		{
 			"test convert env",
 			args{envList: []string{"IP=127.0.0.1;127.0.0.2;127.0.0.3", "IP=192.168.0.2", "key=value"}},
 			map[string]interface{}{"IP": []string{"127.0.0.1", "127.0.0.2", "127.0.0.3", "192.168.0.2"}, "key": "value"},
+			false,
+		},
+		{
+			"test env failed to resolve",
+			args{envList: []string{"key=value", "password=${file:/not/exist/password}"}},
+			map[string]interface{}{"key": "value"},
+			true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if gotEnv := ConvertEnv(tt.args.envList); !reflect.DeepEqual(gotEnv, tt.wantEnv) {
+			gotEnv, err := ConvertEnv(tt.args.envList)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("convertEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(gotEnv, tt.wantEnv) {
 				t.Errorf("convertEnv() = %v, want %v", gotEnv, tt.wantEnv)
 			}
 		})
 	}
 }
 
+func TestStringGetter(t *testing.T) {
+	getEnv, err := StringGetter([]string{"IP=127.0.0.1;127.0.0.2", "key=value", "password=${file:/not/exist/password}"})
+	if err == nil {
+		t.Errorf("StringGetter() error = nil, want the error of password")
+	}
+	for key, want := range map[string]string{"IP": "", "key": "value", "password": "", "unset": ""} {
+		if got := getEnv(key); got != want {
+			t.Errorf("getEnv(%s) = %s, want %s", key, got, want)
+		}
+	}
+}
+
 func getTestCluster() *v2.Cluster {
 	return &v2.Cluster{
 		Spec: v2.ClusterSpec{
@@ -29,7 +29,10 @@ func (p *processor) Process(path string) ([]byte, error) {
 		return nil, err
 	}
 	buf := bytes.NewBuffer([]byte{})
-	environ := ConvertEnv(p.Spec.Env)
+	environ, err := ConvertEnv(p.Spec.Env)
+	if err != nil {
+		return nil, err
+	}
 	err = tem.Execute(buf, environ)
 	if err != nil {
 		return nil, err
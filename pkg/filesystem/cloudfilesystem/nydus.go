@@ -15,12 +15,10 @@
 package cloudfilesystem
 
 import (
-	"context"
 	"fmt"
+	"net"
 	"path/filepath"
 
-	"golang.org/x/sync/errgroup"
-
 	"github.com/alibaba/sealer/logger"
 	"github.com/alibaba/sealer/pkg/env"
 
@@ -53,11 +51,7 @@ func (n *nydusFileSystem) MountRootfs(cluster *v2.Cluster, hosts []string, initF
 		return fmt.Errorf("cp nydusdfile failed %v", err)
 	}
 	//scp roofs to all Masters and Nodes,then do init.sh
-	if err := mountNydusRootfs(hosts, clusterRootfsDir, cluster, initFlag); err != nil {
-		return fmt.Errorf("mount rootfs failed %v", err)
-	}
-
-	return nil
+	return mountNydusRootfs(hosts, clusterRootfsDir, cluster, initFlag)
 }
 
 func (n *nydusFileSystem) UnMountRootfs(cluster *v2.Cluster, hosts []string) error {
@@ -82,7 +76,7 @@ func (n *nydusFileSystem) UnMountRootfs(cluster *v2.Cluster, hosts []string) err
 }
 
 func mountNydusRootfs(ipList []string, target string, cluster *v2.Cluster, initFlag bool) error {
-	localIP, err := utils.GetLocalIP(cluster.GetMaster0IP() + ":22")
+	localIP, err := utils.GetLocalIP(net.JoinHostPort(cluster.GetMaster0IP(), "22"))
 	if err != nil {
 		return fmt.Errorf("failed to get local address, %v", err)
 	}
@@ -97,13 +91,14 @@ func mountNydusRootfs(ipList []string, target string, cluster *v2.Cluster, initF
 		cleanCmd          = fmt.Sprintf("echo '%s' >> "+common.DefaultClusterClearBashFile, nydusdCleanCmd, cluster.Name)
 		envProcessor      = env.NewEnvProcessor(cluster)
 		config            = runtime.GetRegistryConfig(src, runtime.GetMaster0Ip(cluster))
+		registries        = runtime.GetRegistryHosts(cluster, config)
 		initCmd           = fmt.Sprintf(RemoteChmod, target, config.Domain, config.Port)
 	)
 
 	// use env list to render image mount dir: etc,charts,manifests.
 	err = renderENV(src, ipList, envProcessor)
 	if err != nil {
-		return err
+		return fmt.Errorf("mount rootfs failed %v", err)
 	}
 
 	//convert image and start nydusd http server
@@ -113,36 +108,32 @@ func mountNydusRootfs(ipList []string, target string, cluster *v2.Cluster, initF
 	}
 	logger.Info("nydus images converted and nydusd http server started")
 
-	eg, _ := errgroup.WithContext(context.Background())
-	for _, IP := range ipList {
-		ip := IP
-		eg.Go(func() error {
-			sshClient, err := ssh.GetHostSSHClient(ip, cluster)
+	// the failed hosts are returned in HostsError, so the failed nodes could be skipped when scaling up.
+	return runtime.RunInParallel(ipList, func(ip string) error {
+		sshClient, err := ssh.GetHostSSHClient(ip, cluster)
+		if err != nil {
+			return fmt.Errorf("get host ssh client failed %v", err)
+		}
+		err = copyFiles(sshClient, utils.InList(ip, registries), ip, nydusdSrcDir, nydusdDir)
+		if err != nil {
+			return fmt.Errorf("scp nydusd failed %v", err)
+		}
+		if initFlag {
+			err = sshClient.CmdAsync(ip, envProcessor.WrapperShell(ip, nydusdInitCmd))
 			if err != nil {
-				return fmt.Errorf("get host ssh client failed %v", err)
+				return fmt.Errorf("init nydusd failed %v", err)
 			}
-			err = copyFiles(sshClient, ip == config.IP, ip, nydusdSrcDir, nydusdDir)
+			err = sshClient.CmdAsync(ip, envProcessor.WrapperShell(ip, initCmd))
 			if err != nil {
-				return fmt.Errorf("scp nydusd failed %v", err)
+				return fmt.Errorf("exec init.sh failed %v", err)
 			}
-			if initFlag {
-				err = sshClient.CmdAsync(ip, envProcessor.WrapperShell(ip, nydusdInitCmd))
-				if err != nil {
-					return fmt.Errorf("init nydusd failed %v", err)
-				}
-				err = sshClient.CmdAsync(ip, envProcessor.WrapperShell(ip, initCmd))
-				if err != nil {
-					return fmt.Errorf("exec init.sh failed %v", err)
-				}
-				err = sshClient.CmdAsync(ip, envProcessor.WrapperShell(ip, cleanCmd))
-				if err != nil {
-					return fmt.Errorf("echo nydusdcleancmd to clean.sh failed %v", err)
-				}
+			err = sshClient.CmdAsync(ip, envProcessor.WrapperShell(ip, cleanCmd))
+			if err != nil {
+				return fmt.Errorf("echo nydusdcleancmd to clean.sh failed %v", err)
 			}
-			return err
-		})
-	}
-	return eg.Wait()
+		}
+		return err
+	})
 }
 
 func NewNydusFileSystem() (Interface, error) {
@@ -22,6 +22,7 @@ import (
 	"github.com/alibaba/sealer/pkg/env"
 	"github.com/alibaba/sealer/pkg/runtime"
 	v2 "github.com/alibaba/sealer/types/api/v2"
+	"github.com/alibaba/sealer/utils"
 	"github.com/alibaba/sealer/utils/mount"
 	"github.com/alibaba/sealer/utils/ssh"
 	"golang.org/x/sync/errgroup"
@@ -37,10 +38,7 @@ type overlayFileSystem struct {
 func (o *overlayFileSystem) MountRootfs(cluster *v2.Cluster, hosts []string, initFlag bool) error {
 	clusterRootfsDir := common.DefaultTheClusterRootfsDir(cluster.Name)
 	//scp roofs to all Masters and Nodes,then do init.sh
-	if err := mountRootfs(hosts, clusterRootfsDir, cluster, initFlag); err != nil {
-		return fmt.Errorf("mount rootfs failed %v", err)
-	}
-	return nil
+	return mountRootfs(hosts, clusterRootfsDir, cluster, initFlag)
 }
 
 func (o *overlayFileSystem) UnMountRootfs(cluster *v2.Cluster, hosts []string) error {
@@ -56,37 +54,34 @@ func mountRootfs(ipList []string, target string, cluster *v2.Cluster, initFlag b
 		src          = common.DefaultMountCloudImageDir(cluster.Name)
 		envProcessor = env.NewEnvProcessor(cluster)
 		config       = runtime.GetRegistryConfig(src, runtime.GetMaster0Ip(cluster))
+		registries   = runtime.GetRegistryHosts(cluster, config)
 		initCmd      = fmt.Sprintf(RemoteChmod, target, config.Domain, config.Port)
 	)
 
 	// use env list to render image mount dir: etc,charts,manifests.
 	err := renderENV(src, ipList, envProcessor)
 	if err != nil {
-		return err
+		return fmt.Errorf("mount rootfs failed %v", err)
 	}
 
-	eg, _ := errgroup.WithContext(context.Background())
-	for _, IP := range ipList {
-		ip := IP
-		eg.Go(func() error {
-			sshClient, err := ssh.GetHostSSHClient(ip, cluster)
-			if err != nil {
-				return fmt.Errorf("get host ssh client failed %v", err)
-			}
-			err = copyFiles(sshClient, ip == config.IP, ip, src, target)
+	// the failed hosts are returned in HostsError, so the failed nodes could be skipped when scaling up.
+	return runtime.RunInParallel(ipList, func(ip string) error {
+		sshClient, err := ssh.GetHostSSHClient(ip, cluster)
+		if err != nil {
+			return fmt.Errorf("get host ssh client failed %v", err)
+		}
+		err = copyFiles(sshClient, utils.InList(ip, registries), ip, src, target)
+		if err != nil {
+			return fmt.Errorf("copy rootfs failed %v", err)
+		}
+		if initFlag {
+			err = sshClient.CmdAsync(ip, envProcessor.WrapperShell(ip, initCmd))
 			if err != nil {
-				return fmt.Errorf("copy rootfs failed %v", err)
-			}
-			if initFlag {
-				err = sshClient.CmdAsync(ip, envProcessor.WrapperShell(ip, initCmd))
-				if err != nil {
-					return fmt.Errorf("exec init.sh failed %v", err)
-				}
+				return fmt.Errorf("exec init.sh failed %v", err)
 			}
-			return err
-		})
-	}
-	return eg.Wait()
+		}
+		return err
+	})
 }
 
 func unmountRootfs(ipList []string, cluster *v2.Cluster) error {
@@ -124,6 +124,8 @@ func GenerateCluster(arg *ParserArg) (*v2.Cluster, error) {
 	cluster.APIVersion = common.APIVersion
 	cluster.Name = arg.Name
 	cluster.Spec.Image = arg.Image
+	// only the clusters installed by kubeadm could be taken over.
+	cluster.SetAnnotations(common.ClusterRuntime, string(runtime.K8s))
 	cluster.Spec.SSH.Passwd = arg.Passwd
 	cluster.Spec.SSH.Port = strconv.Itoa(int(arg.Port))
 	cluster.Spec.SSH.Pk = arg.Pk
@@ -0,0 +1,308 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributionutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/distribution"
+	// register the OCI manifest to put and delete it.
+	_ "github.com/docker/distribution/manifest/ocischema"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/opencontainers/go-digest"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/alibaba/sealer/utils"
+	"github.com/alibaba/sealer/utils/archive"
+)
+
+const (
+	dockerArchiveManifest = "manifest.json"
+	ociLayoutIndex        = "index.json"
+)
+
+// ArchiveImage is an image in a docker archive or an OCI layout.
+type ArchiveImage struct {
+	// Names are the repo tags of docker archive or the ref names of OCI layout, it may be empty.
+	Names  []string
+	config archiveBlob
+	layers []archiveBlob
+	// manifest is the raw manifest of OCI layout, the manifest of docker archive is built when it is pushed.
+	manifest []byte
+}
+
+// archiveBlob is a file in the archive, desc is empty for the docker archive, whose layers are gzipped when pushed.
+type archiveBlob struct {
+	path string
+	desc distribution.Descriptor
+}
+
+// Archive is the images loaded from a docker archive or an OCI layout.
+type Archive struct {
+	Images []ArchiveImage
+	tmpDir string
+}
+
+// OpenArchive load the images from src, which is a docker archive tar, an OCI layout dir or tar, or an image of local
+// docker which is saved to a docker archive first. The archive should be closed to remove the files extracted.
+func OpenArchive(src string) (*Archive, error) {
+	a := &Archive{}
+	dir := src
+	if !utils.IsExist(src) {
+		tmpDir, err := ioutil.TempDir("", "sealer-archive")
+		if err != nil {
+			return nil, err
+		}
+		a.tmpDir = tmpDir
+		src = filepath.Join(tmpDir, "image.tar")
+		if _, err := utils.RunSimpleCmd(fmt.Sprintf("docker save -o %s %s", src, dir)); err != nil {
+			_ = a.Close()
+			return nil, fmt.Errorf("%s is not found, and failed to save it from docker: %v", dir, err)
+		}
+	}
+	if !utils.IsDir(src) {
+		if a.tmpDir == "" {
+			tmpDir, err := ioutil.TempDir("", "sealer-archive")
+			if err != nil {
+				return nil, err
+			}
+			a.tmpDir = tmpDir
+		}
+		dir = filepath.Join(a.tmpDir, "rootfs")
+		if err := extractArchive(src, dir); err != nil {
+			_ = a.Close()
+			return nil, fmt.Errorf("failed to extract %s: %v", src, err)
+		}
+	}
+
+	var err error
+	switch {
+	case utils.IsFileExist(filepath.Join(dir, dockerArchiveManifest)):
+		a.Images, err = loadDockerArchive(dir)
+	case utils.IsFileExist(filepath.Join(dir, ociLayoutIndex)):
+		a.Images, err = loadOCILayout(dir)
+	default:
+		err = fmt.Errorf("%s is not a docker archive or an OCI layout", src)
+	}
+	if err != nil {
+		_ = a.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *Archive) Close() error {
+	if a.tmpDir == "" {
+		return nil
+	}
+	return os.RemoveAll(a.tmpDir)
+}
+
+func extractArchive(src, dir string) error {
+	file, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	gzipped, err := isGzip(reader)
+	if err != nil {
+		return err
+	}
+	_, err = archive.Decompress(reader, dir, archive.Options{Compress: gzipped})
+	return err
+}
+
+func isGzip(reader *bufio.Reader) (bool, error) {
+	magic, err := reader.Peek(2)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b, nil
+}
+
+func loadDockerArchive(dir string) ([]ArchiveImage, error) {
+	var manifests []struct {
+		Config   string
+		RepoTags []string
+		Layers   []string
+	}
+	data, err := ioutil.ReadFile(filepath.Clean(filepath.Join(dir, dockerArchiveManifest)))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &manifests); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", dockerArchiveManifest, err)
+	}
+	var images []ArchiveImage
+	for _, m := range manifests {
+		image := ArchiveImage{Names: m.RepoTags, config: archiveBlob{path: filepath.Join(dir, m.Config)}}
+		for _, layer := range m.Layers {
+			image.layers = append(image.layers, archiveBlob{path: filepath.Join(dir, layer)})
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+func loadOCILayout(dir string) ([]ArchiveImage, error) {
+	var index ociv1.Index
+	data, err := ioutil.ReadFile(filepath.Clean(filepath.Join(dir, ociLayoutIndex)))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", ociLayoutIndex, err)
+	}
+	var images []ArchiveImage
+	for _, desc := range index.Manifests {
+		if desc.MediaType != ociv1.MediaTypeImageManifest {
+			return nil, fmt.Errorf("the manifest %s of %s is not supported, only the image manifest is supported", desc.Digest, desc.MediaType)
+		}
+		image := ArchiveImage{}
+		if name := desc.Annotations[ociv1.AnnotationRefName]; name != "" {
+			image.Names = []string{name}
+		}
+		if image.manifest, err = ioutil.ReadFile(ociBlobPath(dir, desc.Digest)); err != nil {
+			return nil, err
+		}
+		var manifest ociv1.Manifest
+		if err := json.Unmarshal(image.manifest, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to decode manifest %s: %v", desc.Digest, err)
+		}
+		image.config = ociArchiveBlob(dir, manifest.Config)
+		for _, layer := range manifest.Layers {
+			image.layers = append(image.layers, ociArchiveBlob(dir, layer))
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+func ociBlobPath(dir string, dgst digest.Digest) string {
+	return filepath.Join(dir, "blobs", dgst.Algorithm().String(), dgst.Hex())
+}
+
+func ociArchiveBlob(dir string, desc ociv1.Descriptor) archiveBlob {
+	return archiveBlob{
+		path: ociBlobPath(dir, desc.Digest),
+		desc: distribution.Descriptor{MediaType: desc.MediaType, Digest: desc.Digest, Size: desc.Size},
+	}
+}
+
+// PushArchiveImage push the image to repo as tag, the blobs of OCI layout already in repo are skipped.
+func PushArchiveImage(ctx context.Context, repo distribution.Repository, image ArchiveImage, tag string) error {
+	bs := repo.Blobs(ctx)
+	var layers []distribution.Descriptor
+	for _, layer := range image.layers {
+		desc, err := pushArchiveBlob(ctx, bs, layer)
+		if err != nil {
+			return fmt.Errorf("failed to push layer %s: %v", filepath.Base(layer.path), err)
+		}
+		layers = append(layers, desc)
+	}
+
+	var (
+		manifest distribution.Manifest
+		err      error
+	)
+	if image.manifest != nil {
+		if _, err = pushArchiveBlob(ctx, bs, image.config); err != nil {
+			return fmt.Errorf("failed to push config: %v", err)
+		}
+		manifest, _, err = distribution.UnmarshalManifest(ociv1.MediaTypeImageManifest, image.manifest)
+	} else {
+		configJSON, readErr := ioutil.ReadFile(filepath.Clean(image.config.path))
+		if readErr != nil {
+			return readErr
+		}
+		// the config is put by the builder if it is not in repo.
+		builder := schema2.NewManifestBuilder(bs, schema2.MediaTypeImageConfig, configJSON)
+		for _, layer := range layers {
+			if err = builder.AppendReference(layer); err != nil {
+				return err
+			}
+		}
+		manifest, err = builder.Build(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	ms, err := repo.Manifests(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = ms.Put(ctx, manifest, distribution.WithTag(tag))
+	return err
+}
+
+func pushArchiveBlob(ctx context.Context, bs distribution.BlobService, blob archiveBlob) (distribution.Descriptor, error) {
+	if blob.desc.Digest != "" {
+		if _, err := bs.Stat(ctx, blob.desc.Digest); err == nil {
+			return blob.desc, nil
+		}
+	}
+	file, err := os.Open(filepath.Clean(blob.path))
+	if err != nil {
+		return distribution.Descriptor{}, err
+	}
+	defer file.Close()
+
+	var (
+		buffered            = bufio.NewReader(file)
+		reader    io.Reader = buffered
+		mediaType           = blob.desc.MediaType
+	)
+	if blob.desc.Digest == "" {
+		// the layers of docker archive are tar, or gzipped tar saved by the newer docker.
+		mediaType = schema2.MediaTypeLayer
+		gzipped, err := isGzip(buffered)
+		if err != nil {
+			return distribution.Descriptor{}, err
+		}
+		if !gzipped {
+			compressed, _ := archive.GzipCompress(reader)
+			defer compressed.Close()
+			reader = compressed
+		}
+	}
+
+	writer, err := bs.Create(ctx)
+	if err != nil {
+		return distribution.Descriptor{}, err
+	}
+	defer writer.Close()
+	digester := digest.Canonical.Digester()
+	size, err := writer.ReadFrom(io.TeeReader(reader, digester.Hash()))
+	if err != nil {
+		return distribution.Descriptor{}, err
+	}
+	desc := distribution.Descriptor{MediaType: mediaType, Digest: digester.Digest(), Size: size}
+	if blob.desc.Digest != "" && blob.desc.Digest != desc.Digest {
+		return distribution.Descriptor{}, fmt.Errorf("digest of %s is %s, but %s in manifest", blob.path, desc.Digest, blob.desc.Digest)
+	}
+	if _, err := writer.Commit(ctx, desc); err != nil {
+		return distribution.Descriptor{}, err
+	}
+	return desc, nil
+}
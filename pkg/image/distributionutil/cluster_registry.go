@@ -0,0 +1,159 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributionutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/registry/api/errcode"
+	v2 "github.com/docker/distribution/registry/api/v2"
+	dockerRegistryClient "github.com/docker/distribution/registry/client"
+	dockerAuth "github.com/docker/distribution/registry/client/auth"
+	"github.com/docker/docker/api/types"
+)
+
+// ClusterRegistry is a registry of cluster reached by ip at Endpoint like 192.168.0.2:5000, its cert is
+// issued for Domain, so it is verified by the CA in CertFile with Domain as the server name.
+type ClusterRegistry struct {
+	Endpoint string
+	Domain   string
+	CertFile string
+	Auth     types.AuthConfig
+}
+
+func (r ClusterRegistry) config() (registryConfig, error) {
+	data, err := ioutil.ReadFile(r.CertFile)
+	if err != nil {
+		return registryConfig{}, fmt.Errorf("failed to read the cert of registry %s: %v", r.Domain, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return registryConfig{}, fmt.Errorf("no cert is found in %s", r.CertFile)
+	}
+	return registryConfig{Domain: r.Endpoint, RootCAs: pool, ServerName: r.Domain}, nil
+}
+
+// isPlainHTTP return whether err is returned because the registry serves plain http instead of https.
+func isPlainHTTP(err error) bool {
+	if err == nil {
+		return false
+	}
+	var recordErr tls.RecordHeaderError
+	return errors.As(err, &recordErr) || strings.Contains(err.Error(), "server gave HTTP response to HTTPS client")
+}
+
+// NewClusterRepository return the repository on the registry of cluster, it falls back to http only if the
+// registry serves plain http.
+func NewClusterRepository(r ClusterRegistry, repoName string, actions ...string) (distribution.Repository, error) {
+	config, err := r.config()
+	if err != nil {
+		return nil, err
+	}
+	repo, err := NewRepository(context.Background(), r.Auth, repoName, config, actions...)
+	if !isPlainHTTP(err) {
+		return repo, err
+	}
+	config.NonSSL = true
+	return NewRepository(context.Background(), r.Auth, repoName, config, actions...)
+}
+
+// ListRepositories return the catalog of the registry.
+func ListRepositories(ctx context.Context, r ClusterRegistry) ([]string, error) {
+	config, err := r.config()
+	if err != nil {
+		return nil, err
+	}
+	scope := dockerAuth.RegistryScope{Name: "catalog", Actions: []string{"*"}}
+	tr, rurl, err := newTransport(ctx, r.Auth, config, scope)
+	if isPlainHTTP(err) {
+		config.NonSSL = true
+		tr, rurl, err = newTransport(ctx, r.Auth, config, scope)
+	}
+	if err != nil {
+		return nil, err
+	}
+	registry, err := dockerRegistryClient.NewRegistry(rurl.String(), tr)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		repos   []string
+		last    string
+		entries = make([]string, 100)
+	)
+	for {
+		n, err := registry.Repositories(ctx, entries, last)
+		repos = append(repos, entries[:n]...)
+		if err == io.EOF || n == 0 {
+			return repos, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		last = entries[n-1]
+	}
+}
+
+// ListTags return the tags of repo, it is empty if all the tags are deleted.
+func ListTags(ctx context.Context, repo distribution.Repository) ([]string, error) {
+	tags, err := repo.Tags(ctx).All(ctx)
+	if err != nil && hasErrorCode(err, v2.ErrorCodeNameUnknown) {
+		return nil, nil
+	}
+	return tags, err
+}
+
+// DeleteTag delete the manifest of tag from repo, the other tags of the same manifest are deleted as well,
+// and the blobs are left until the registry is garbage collected.
+func DeleteTag(ctx context.Context, repo distribution.Repository, tag string) error {
+	desc, err := repo.Tags(ctx).Get(ctx, tag)
+	if err != nil {
+		return err
+	}
+	ms, err := repo.Manifests(ctx)
+	if err != nil {
+		return err
+	}
+	err = ms.Delete(ctx, desc.Digest)
+	if err != nil && hasErrorCode(err, errcode.ErrorCodeUnsupported) {
+		return fmt.Errorf("%v, the deletion should be enabled by storage.delete.enabled in the registry config", err)
+	}
+	return err
+}
+
+func hasErrorCode(err error, code errcode.ErrorCode) bool {
+	switch e := err.(type) {
+	case errcode.Errors:
+		for _, one := range e {
+			if hasErrorCode(one, code) {
+				return true
+			}
+		}
+	case errcode.Error:
+		return e.Code == code
+	case errcode.ErrorCode:
+		return e == code
+	}
+	return false
+}
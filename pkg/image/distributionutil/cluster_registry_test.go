@@ -0,0 +1,103 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributionutil
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newRegistryHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	})
+	mux.HandleFunc("/v2/_catalog", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"repositories":["library/nginx"]}`))
+	})
+	return mux
+}
+
+func writeServerCert(t *testing.T, dir string, server *httptest.Server) string {
+	certFile := filepath.Join(dir, "sea.hub.crt")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(certFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return certFile
+}
+
+func TestListRepositories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sealer-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tlsServer := httptest.NewTLSServer(newRegistryHandler())
+	defer tlsServer.Close()
+	httpServer := httptest.NewServer(newRegistryHandler())
+	defer httpServer.Close()
+
+	// the cert of httptest server is issued for example.com.
+	certFile := writeServerCert(t, dir, tlsServer)
+	tests := []struct {
+		name     string
+		registry ClusterRegistry
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "the cert is verified",
+			registry: ClusterRegistry{Endpoint: strings.TrimPrefix(tlsServer.URL, "https://"), Domain: "example.com", CertFile: certFile},
+			want:     []string{"library/nginx"},
+		},
+		{
+			name:     "the registry serves http",
+			registry: ClusterRegistry{Endpoint: strings.TrimPrefix(httpServer.URL, "http://"), Domain: "example.com", CertFile: certFile},
+			want:     []string{"library/nginx"},
+		},
+		{
+			name:     "the cert is not issued for the domain",
+			registry: ClusterRegistry{Endpoint: strings.TrimPrefix(tlsServer.URL, "https://"), Domain: "sea.hub", CertFile: certFile},
+			wantErr:  true,
+		},
+		{
+			name:     "the cert file does not exist",
+			registry: ClusterRegistry{Endpoint: strings.TrimPrefix(tlsServer.URL, "https://"), Domain: "example.com", CertFile: filepath.Join(dir, "none.crt")},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ListRepositories(context.Background(), tt.registry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListRepositories() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListRepositories() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@
 package distributionutil
 
 import (
+	"crypto/x509"
 	"time"
 
 	"github.com/docker/docker/pkg/progress"
@@ -36,4 +37,8 @@ type registryConfig struct {
 	NonSSL   bool
 	Timeout  time.Duration
 	Headers  map[string]string
+	// RootCAs and ServerName verify the registry cert if they are set, it is
+	// used while the registry is reached by ip instead of its domain.
+	RootCAs    *x509.CertPool
+	ServerName string
 }
@@ -35,8 +35,31 @@ import (
 )
 
 func NewRepository(ctx context.Context, authConfig types.AuthConfig, repoName string, config registryConfig, actions ...string) (distribution.Repository, error) {
+	scope := dockerAuth.RepositoryScope{
+		Repository: repoName,
+		Actions:    actions,
+		Class:      "image",
+	}
+	tr, rurl, err := newTransport(ctx, authConfig, config, scope)
+	if err != nil {
+		return nil, err
+	}
+	repoNameRef, err := reference.WithName(repoName)
+	if err != nil {
+		return nil, err
+	}
+
+	return dockerRegistryClient.NewRepository(repoNameRef, rurl.String(), tr)
+}
+
+// newTransport return the transport authorized by the auth config with the scope, and the url of registry.
+func newTransport(ctx context.Context, authConfig types.AuthConfig, config registryConfig, scope dockerAuth.Scope) (http.RoundTripper, *url.URL, error) {
 	tlsConfig := tlsconfig.ServerDefault()
 	tlsConfig.InsecureSkipVerify = config.Insecure
+	if config.RootCAs != nil {
+		tlsConfig.RootCAs = config.RootCAs
+	}
+	tlsConfig.ServerName = config.ServerName
 
 	rurlStr := strings.TrimSuffix(config.Domain, "/")
 	if !strings.HasPrefix(rurlStr, "https://") && !strings.HasPrefix(rurlStr, "http://") {
@@ -49,7 +72,7 @@ func NewRepository(ctx context.Context, authConfig types.AuthConfig, repoName st
 
 	rurl, err := url.Parse(rurlStr)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	direct := &net.Dialer{
@@ -73,19 +96,13 @@ func NewRepository(ctx context.Context, authConfig types.AuthConfig, repoName st
 
 	challengeManager, err := dockerRegistry.PingV2Registry(rurl, authTransport)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	// typically, this filed would be empty
 	if authConfig.RegistryToken != "" {
 		passThruTokenHandler := &existingTokenHandler{token: authConfig.RegistryToken}
 		modifiers = append(modifiers, dockerAuth.NewAuthorizer(challengeManager, passThruTokenHandler))
 	} else {
-		scope := dockerAuth.RepositoryScope{
-			Repository: repoName,
-			Actions:    actions,
-			Class:      "image",
-		}
-
 		creds := dockerRegistry.NewStaticCredentialStore(&authConfig)
 		tokenHandlerOptions := dockerAuth.TokenHandlerOptions{
 			Transport:   authTransport,
@@ -98,13 +115,7 @@ func NewRepository(ctx context.Context, authConfig types.AuthConfig, repoName st
 		modifiers = append(modifiers, dockerAuth.NewAuthorizer(challengeManager, tokenHandler, basicHandler))
 	}
 
-	tr := dockerTransport.NewTransport(base, modifiers...)
-	repoNameRef, err := reference.WithName(repoName)
-	if err != nil {
-		return nil, err
-	}
-
-	return dockerRegistryClient.NewRepository(repoNameRef, rurl.String(), tr)
+	return dockerTransport.NewTransport(base, modifiers...), rurl, nil
 }
 
 type existingTokenHandler struct {
@@ -0,0 +1,292 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/utils"
+)
+
+// handle manages the ipvs rules of a virtual server and its real servers, the servers are like 10.103.97.2:6443.
+type handle interface {
+	// AddVirtualServer create the virtual server if not exist.
+	AddVirtualServer(vs string) error
+	// DeleteVirtualServer delete the virtual server and its real servers if exist.
+	DeleteVirtualServer(vs string) error
+	// AddRealServer add the real server to the virtual server if not exist.
+	AddRealServer(vs, rs string) error
+	DeleteRealServer(vs, rs string) error
+	// ListRealServers return the real servers in use of the virtual server.
+	ListRealServers(vs string) ([]string, error)
+}
+
+// RealServerStatus is the health of a real server checked by LvsCare.
+type RealServerStatus struct {
+	Address string `json:"address"`
+	Healthy bool   `json:"healthy"`
+	// InUse is whether the real server is in the ipvs rules.
+	InUse     bool      `json:"inUse"`
+	LastCheck time.Time `json:"lastCheck,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	// Failures is the count of the failed health checks.
+	Failures int `json:"failures"`
+	// Removals is the count of removing the real server from the ipvs rules.
+	Removals int `json:"removals"`
+}
+
+// Status is the status of the virtual server and its real servers.
+type Status struct {
+	VirtualServer string             `json:"virtualServer"`
+	RealServers   []RealServerStatus `json:"realServers"`
+}
+
+// LvsCare keeps the ipvs rules of the virtual server, the real servers are removed when their health checks fail,
+// and added back after they recover.
+type LvsCare struct {
+	VirtualServer string
+	RealServers   []string
+	HealthPath    string
+	HealthScheme  string
+	Interval      time.Duration
+	Timeout       time.Duration
+	// RunOnce only creates the ipvs rules without health checking.
+	RunOnce bool
+	// Clean deletes the existing virtual server before creating it.
+	Clean bool
+	// StatusAddr is the address serving /status and /metrics, not served if empty.
+	StatusAddr string
+
+	handle handle
+	probe  func(rs string) error
+	mu     sync.RWMutex
+	status map[string]*RealServerStatus
+}
+
+// Run create the ipvs rules and check the real servers every interval until ctx is done.
+func (c *LvsCare) Run(ctx context.Context) error {
+	if err := c.init(); err != nil {
+		return err
+	}
+	if c.Clean {
+		if err := c.handle.DeleteVirtualServer(c.VirtualServer); err != nil {
+			logger.Warn("failed to clean virtual server %s: %v", c.VirtualServer, err)
+		}
+	}
+	if err := c.handle.AddVirtualServer(c.VirtualServer); err != nil {
+		return fmt.Errorf("failed to create virtual server %s: %v", c.VirtualServer, err)
+	}
+	for _, rs := range c.RealServers {
+		if err := c.handle.AddRealServer(c.VirtualServer, rs); err != nil {
+			return fmt.Errorf("failed to add real server %s to %s: %v", rs, c.VirtualServer, err)
+		}
+		c.status[rs].InUse = true
+	}
+	if c.RunOnce {
+		return nil
+	}
+
+	if c.StatusAddr != "" {
+		server := &http.Server{Addr: c.StatusAddr, Handler: c.Handler()}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("failed to serve status on %s: %v", c.StatusAddr, err)
+			}
+		}()
+		defer server.Close()
+	}
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			c.checkRealServers()
+		}
+	}
+}
+
+func (c *LvsCare) init() error {
+	if c.VirtualServer == "" || len(c.RealServers) == 0 {
+		return fmt.Errorf("virtual server and real servers are required")
+	}
+	if c.Interval <= 0 {
+		c.Interval = 5 * time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 3 * time.Second
+	}
+	if c.handle == nil {
+		h, err := newHandle()
+		if err != nil {
+			return err
+		}
+		c.handle = h
+	}
+	if c.probe == nil {
+		c.probe = c.httpProbe
+	}
+	c.status = map[string]*RealServerStatus{}
+	for _, rs := range c.RealServers {
+		c.status[rs] = &RealServerStatus{Address: rs, Healthy: true}
+	}
+	return nil
+}
+
+// httpProbe check the real server by the health path, it is unhealthy if no response or the status code is 5xx.
+func (c *LvsCare) httpProbe(rs string) error {
+	client := &http.Client{
+		Timeout: c.Timeout,
+		// #nosec
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	resp, err := client.Get(fmt.Sprintf("%s://%s%s", c.HealthScheme, rs, c.HealthPath))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("health check returns %s", resp.Status)
+	}
+	return nil
+}
+
+// checkRealServers probe the real servers in parallel, then remove the failed ones from the ipvs rules
+// and add the recovered ones back.
+func (c *LvsCare) checkRealServers() {
+	results := make([]error, len(c.RealServers))
+	var wg sync.WaitGroup
+	for i, rs := range c.RealServers {
+		wg.Add(1)
+		go func(i int, rs string) {
+			defer wg.Done()
+			results[i] = c.probe(rs)
+		}(i, rs)
+	}
+	wg.Wait()
+
+	inUse, err := c.handle.ListRealServers(c.VirtualServer)
+	if err != nil {
+		logger.Error("failed to list real servers of %s: %v", c.VirtualServer, err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, rs := range c.RealServers {
+		st := c.status[rs]
+		st.LastCheck = time.Now()
+		st.InUse = utils.InList(rs, inUse)
+		if err := results[i]; err != nil {
+			if st.Healthy {
+				logger.Warn("real server %s is unhealthy: %v", rs, err)
+			}
+			st.Healthy = false
+			st.LastError = err.Error()
+			st.Failures++
+			if st.InUse {
+				if err := c.handle.DeleteRealServer(c.VirtualServer, rs); err != nil {
+					logger.Error("failed to remove real server %s: %v", rs, err)
+					continue
+				}
+				logger.Info("real server %s is removed from %s", rs, c.VirtualServer)
+				st.InUse = false
+				st.Removals++
+			}
+			continue
+		}
+		if !st.Healthy {
+			logger.Info("real server %s is recovered", rs)
+		}
+		st.Healthy = true
+		st.LastError = ""
+		if !st.InUse {
+			if err := c.handle.AddRealServer(c.VirtualServer, rs); err != nil {
+				logger.Error("failed to add real server %s: %v", rs, err)
+				continue
+			}
+			logger.Info("real server %s is added to %s", rs, c.VirtualServer)
+			st.InUse = true
+		}
+	}
+}
+
+// Status return the status of the real servers sorted by address.
+func (c *LvsCare) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status := Status{VirtualServer: c.VirtualServer}
+	for _, st := range c.status {
+		status.RealServers = append(status.RealServers, *st)
+	}
+	sort.Slice(status.RealServers, func(i, j int) bool {
+		return status.RealServers[i].Address < status.RealServers[j].Address
+	})
+	return status
+}
+
+// Handler serve the status in json on /status and the metrics in prometheus text format on /metrics.
+func (c *LvsCare) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(c.Status()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = io.WriteString(w, renderMetrics(c.Status()))
+	})
+	return mux
+}
+
+func renderMetrics(status Status) string {
+	metrics := []struct {
+		name, help, typ string
+		value           func(st RealServerStatus) int
+	}{
+		{"seautil_ipvs_real_server_healthy", "Whether the real server is healthy.", "gauge", func(st RealServerStatus) int { return boolToInt(st.Healthy) }},
+		{"seautil_ipvs_real_server_in_use", "Whether the real server is in the ipvs rules.", "gauge", func(st RealServerStatus) int { return boolToInt(st.InUse) }},
+		{"seautil_ipvs_real_server_check_failures_total", "The count of the failed health checks.", "counter", func(st RealServerStatus) int { return st.Failures }},
+		{"seautil_ipvs_real_server_removals_total", "The count of removing the real server from the ipvs rules.", "counter", func(st RealServerStatus) int { return st.Removals }},
+	}
+	var out string
+	for _, m := range metrics {
+		out += fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+		for _, st := range status.RealServers {
+			out += fmt.Sprintf("%s{virtual_server=%q,real_server=%q} %d\n", m.name, status.VirtualServer, st.Address, m.value(st))
+		}
+	}
+	return out
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
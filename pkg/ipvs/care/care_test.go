@@ -0,0 +1,104 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package care
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alibaba/sealer/utils"
+)
+
+type fakeHandle struct {
+	realServers []string
+}
+
+func (f *fakeHandle) AddVirtualServer(vs string) error    { return nil }
+func (f *fakeHandle) DeleteVirtualServer(vs string) error { f.realServers = nil; return nil }
+func (f *fakeHandle) AddRealServer(vs, rs string) error {
+	if utils.NotIn(rs, f.realServers) {
+		f.realServers = append(f.realServers, rs)
+	}
+	return nil
+}
+func (f *fakeHandle) DeleteRealServer(vs, rs string) error {
+	var servers []string
+	for _, s := range f.realServers {
+		if s != rs {
+			servers = append(servers, s)
+		}
+	}
+	f.realServers = servers
+	return nil
+}
+func (f *fakeHandle) ListRealServers(vs string) ([]string, error) { return f.realServers, nil }
+
+func TestCheckRealServers(t *testing.T) {
+	down := map[string]bool{}
+	h := &fakeHandle{}
+	c := &LvsCare{
+		VirtualServer: "10.103.97.2:6443",
+		RealServers:   []string{"192.168.0.2:6443", "192.168.0.3:6443"},
+		RunOnce:       true,
+		handle:        h,
+		probe: func(rs string) error {
+			if down[rs] {
+				return fmt.Errorf("connection refused")
+			}
+			return nil
+		},
+	}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	steps := []struct {
+		down        []string
+		wantServers []string
+	}{
+		{nil, []string{"192.168.0.2:6443", "192.168.0.3:6443"}},
+		{[]string{"192.168.0.2:6443"}, []string{"192.168.0.3:6443"}},
+		{[]string{"192.168.0.2:6443"}, []string{"192.168.0.3:6443"}},
+		{nil, []string{"192.168.0.3:6443", "192.168.0.2:6443"}},
+	}
+	for i, step := range steps {
+		down = map[string]bool{}
+		for _, rs := range step.down {
+			down[rs] = true
+		}
+		c.checkRealServers()
+		if !reflect.DeepEqual(h.realServers, step.wantServers) {
+			t.Errorf("step %d: real servers = %v, want %v", i, h.realServers, step.wantServers)
+		}
+	}
+
+	status := c.Status()
+	if st := status.RealServers[0]; st.Address != "192.168.0.2:6443" || !st.Healthy || !st.InUse || st.Failures != 2 || st.Removals != 1 {
+		t.Errorf("status of 192.168.0.2:6443 = %+v, want healthy, in use, 2 failures and 1 removal", st)
+	}
+	metrics := renderMetrics(status)
+	for _, want := range []string{
+		`seautil_ipvs_real_server_healthy{virtual_server="10.103.97.2:6443",real_server="192.168.0.2:6443"} 1`,
+		`seautil_ipvs_real_server_check_failures_total{virtual_server="10.103.97.2:6443",real_server="192.168.0.2:6443"} 2`,
+		`seautil_ipvs_real_server_removals_total{virtual_server="10.103.97.2:6443",real_server="192.168.0.3:6443"} 0`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("renderMetrics() = %s, want it contains %s", metrics, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package care
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+
+	libipvs "github.com/moby/ipvs"
+)
+
+// netlinkHandle manages the ipvs rules by netlink.
+type netlinkHandle struct {
+	handle *libipvs.Handle
+}
+
+func newHandle() (handle, error) {
+	h, err := libipvs.New("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ipvs netlink handle: %v", err)
+	}
+	return &netlinkHandle{handle: h}, nil
+}
+
+func splitServer(server string) (net.IP, uint16, error) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		return nil, 0, err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("%s is not an ip address", host)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid port of %s: %v", server, err)
+	}
+	return ip, uint16(p), nil
+}
+
+func addressFamily(ip net.IP) uint16 {
+	if ip.To4() != nil {
+		return syscall.AF_INET
+	}
+	return syscall.AF_INET6
+}
+
+func toService(vs string) (*libipvs.Service, error) {
+	ip, port, err := splitServer(vs)
+	if err != nil {
+		return nil, err
+	}
+	svc := &libipvs.Service{
+		Address:       ip,
+		Protocol:      syscall.IPPROTO_TCP,
+		Port:          port,
+		SchedName:     libipvs.RoundRobin,
+		AddressFamily: addressFamily(ip),
+		Netmask:       0xffffffff,
+	}
+	if svc.AddressFamily == syscall.AF_INET6 {
+		svc.Netmask = 128
+	}
+	return svc, nil
+}
+
+func toDestination(rs string) (*libipvs.Destination, error) {
+	ip, port, err := splitServer(rs)
+	if err != nil {
+		return nil, err
+	}
+	return &libipvs.Destination{Address: ip, Port: port, Weight: 1, AddressFamily: addressFamily(ip)}, nil
+}
+
+func (n *netlinkHandle) AddVirtualServer(vs string) error {
+	svc, err := toService(vs)
+	if err != nil {
+		return err
+	}
+	if n.handle.IsServicePresent(svc) {
+		return nil
+	}
+	return n.handle.NewService(svc)
+}
+
+func (n *netlinkHandle) DeleteVirtualServer(vs string) error {
+	svc, err := toService(vs)
+	if err != nil {
+		return err
+	}
+	if !n.handle.IsServicePresent(svc) {
+		return nil
+	}
+	return n.handle.DelService(svc)
+}
+
+func (n *netlinkHandle) AddRealServer(vs, rs string) error {
+	svc, err := toService(vs)
+	if err != nil {
+		return err
+	}
+	dst, err := toDestination(rs)
+	if err != nil {
+		return err
+	}
+	err = n.handle.NewDestination(svc, dst)
+	// the existing real server may be set weight 0 by others, reset its weight.
+	if errors.Is(err, syscall.EEXIST) {
+		return n.handle.UpdateDestination(svc, dst)
+	}
+	return err
+}
+
+func (n *netlinkHandle) DeleteRealServer(vs, rs string) error {
+	svc, err := toService(vs)
+	if err != nil {
+		return err
+	}
+	dst, err := toDestination(rs)
+	if err != nil {
+		return err
+	}
+	return n.handle.DelDestination(svc, dst)
+}
+
+func (n *netlinkHandle) ListRealServers(vs string) ([]string, error) {
+	svc, err := toService(vs)
+	if err != nil {
+		return nil, err
+	}
+	dsts, err := n.handle.GetDestinations(svc)
+	if err != nil {
+		return nil, err
+	}
+	var servers []string
+	for _, dst := range dsts {
+		// the real servers with weight 0 are not used, they are treated as removed.
+		if dst.Weight == 0 {
+			continue
+		}
+		servers = append(servers, net.JoinHostPort(dst.Address.String(), strconv.Itoa(int(dst.Port))))
+	}
+	return servers, nil
+}
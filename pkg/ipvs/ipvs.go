@@ -15,7 +15,7 @@
 package ipvs
 
 import (
-	"strings"
+	"net"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
@@ -24,39 +24,68 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 
 	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/utils"
 )
 
 const (
 	LvsCareStaticPodName = "kube-lvscare"
-	LvsCareCommand       = "/usr/bin/lvscare"
+	LvsCareCommand       = "/usr/bin/seautil"
+	// LvsCareStatusAddr serves the status and metrics of the real servers.
+	LvsCareStatusAddr = "127.0.0.1:9081"
+
+	// the lvscare image run by the seautil of older ClusterImages, which has no ipvs care daemon.
+	LegacyLvsCareCommand = "/usr/bin/lvscare"
 	DefaultLvsCareImage  = "sea.hub:5000/fanux/lvscare:latest"
 )
 
-// return lvs care static pod yaml
+// return lvs care static pod yaml, it runs the seautil of host, which is in the rootfs of ClusterImage,
+// so the image could be any image on the host like kube-proxy.
 func LvsStaticPodYaml(vip string, masters []string, image string) string {
-	if vip == "" || len(masters) == 0 {
+	if vip == "" || len(masters) == 0 || image == "" {
 		return ""
 	}
-	if image == "" {
-		image = DefaultLvsCareImage
+	args := []string{"ipvs", "--vs", net.JoinHostPort(vip, "6443"), "--health-path", "/healthz", "--health-schem", "https", "--status-addr", LvsCareStatusAddr}
+	pod := lvsCarePod(image, LvsCareCommand, append(args, realServerArgs(masters)...))
+	hostPathType := v1.HostPathFile
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{Name: "seautil", VolumeSource: v1.VolumeSource{
+		HostPath: &v1.HostPathVolumeSource{Path: LvsCareCommand, Type: &hostPathType},
+	}})
+	pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts,
+		v1.VolumeMount{Name: "seautil", ReadOnly: true, MountPath: LvsCareCommand})
+	return lvsCarePodYaml(pod)
+}
+
+// LegacyLvsStaticPodYaml return the lvs care static pod yaml running the lvscare image, it is used on the hosts
+// whose seautil has no ipvs care daemon.
+func LegacyLvsStaticPodYaml(vip string, masters []string) string {
+	if vip == "" || len(masters) == 0 {
+		return ""
 	}
-	args := []string{"care", "--vs", vip + ":6443", "--health-path", "/healthz", "--health-schem", "https"}
+	args := []string{"care", "--vs", net.JoinHostPort(vip, "6443"), "--health-path", "/healthz", "--health-schem", "https"}
+	return lvsCarePodYaml(lvsCarePod(DefaultLvsCareImage, LegacyLvsCareCommand, append(args, realServerArgs(masters)...)))
+}
+
+func realServerArgs(masters []string) []string {
+	var args []string
 	for _, m := range masters {
-		if strings.Contains(m, ":") {
-			m = strings.Split(m, ":")[0]
-		}
-		args = append(args, "--rs")
-		args = append(args, m+":6443")
+		args = append(args, "--rs", net.JoinHostPort(utils.GetHostIP(m), "6443"))
 	}
+	return args
+}
+
+func lvsCarePod(image, command string, args []string) v1.Pod {
 	flag := true
-	pod := componentPod(v1.Container{
+	return componentPod(v1.Container{
 		Name:            LvsCareStaticPodName,
 		Image:           image,
-		Command:         []string{LvsCareCommand},
+		Command:         []string{command},
 		Args:            args,
 		ImagePullPolicy: v1.PullIfNotPresent,
 		SecurityContext: &v1.SecurityContext{Privileged: &flag},
 	})
+}
+
+func lvsCarePodYaml(pod v1.Pod) string {
 	yaml, err := podToYaml(pod)
 	if err != nil {
 		logger.Error("decode lvs care static pod yaml failed %s", err)
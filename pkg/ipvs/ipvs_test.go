@@ -31,13 +31,15 @@ metadata:
 spec:
   containers:
   - args:
-    - care
+    - ipvs
     - --vs
     - 10.10.10.10:6443
     - --health-path
     - /healthz
     - --health-schem
     - https
+    - --status-addr
+    - 127.0.0.1:9081
     - --rs
     - 116.31.96.134:6443
     - --rs
@@ -45,7 +47,7 @@ spec:
     - --rs
     - 116.31.96.136:6443
     command:
-    - /usr/bin/lvscare
+    - /usr/bin/seautil
     image: fanux/lvscare:latest
     imagePullPolicy: IfNotPresent
     name: kube-sealyun-lvscare
@@ -56,6 +58,9 @@ spec:
     - mountPath: /lib/modules
       name: lib-modules
       readOnly: true
+    - mountPath: /usr/bin/seautil
+      name: seautil
+      readOnly: true
   hostNetwork: true
   priorityClassName: system-cluster-critical
   volumes:
@@ -63,6 +68,10 @@ spec:
       path: /lib/modules
       type: ""
     name: lib-modules
+  - hostPath:
+      path: /usr/bin/seautil
+      type: File
+    name: seautil
 status: {}
 `,
 }
@@ -0,0 +1,81 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipvs
+
+import (
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/utils"
+)
+
+const (
+	KubeVIPStaticPodName = "kube-vip"
+	DefaultKubeVIPImage  = "sea.hub:5000/plndr/kube-vip:v0.4.0"
+	kubeVIPKubeConfig    = "/etc/kubernetes/admin.conf"
+)
+
+// KubeVIPStaticPodYaml return the kube-vip static pod yaml run on masters, the vip floats among the masters by
+// ARP and the leader election of kube-vip, and the vip is bound on the interface, or the default route interface if empty.
+func KubeVIPStaticPodYaml(vip, iface, image string) string {
+	if vip == "" {
+		return ""
+	}
+	if image == "" {
+		image = DefaultKubeVIPImage
+	}
+	vipCIDR := "32"
+	if utils.IsIPv6(vip) {
+		vipCIDR = "128"
+	}
+	envs := []v1.EnvVar{
+		{Name: "vip_arp", Value: "true"},
+		{Name: "port", Value: "6443"},
+		{Name: "vip_cidr", Value: vipCIDR},
+		{Name: "cp_enable", Value: "true"},
+		{Name: "cp_namespace", Value: "kube-system"},
+		{Name: "vip_ddns", Value: "false"},
+		{Name: "vip_leaderelection", Value: "true"},
+		{Name: "vip_leaseduration", Value: "5"},
+		{Name: "vip_renewdeadline", Value: "3"},
+		{Name: "vip_retryperiod", Value: "1"},
+		{Name: "address", Value: vip},
+	}
+	if iface != "" {
+		envs = append(envs, v1.EnvVar{Name: "vip_interface", Value: iface})
+	}
+	pod := componentPod(v1.Container{
+		Name:            KubeVIPStaticPodName,
+		Image:           image,
+		Args:            []string{"manager"},
+		Env:             envs,
+		ImagePullPolicy: v1.PullIfNotPresent,
+		SecurityContext: &v1.SecurityContext{Capabilities: &v1.Capabilities{
+			Add: []v1.Capability{"NET_ADMIN", "NET_RAW"},
+		}},
+	})
+	// kube-vip use the admin kubeconfig of master for the leader election.
+	hostPathType := v1.HostPathFile
+	pod.Spec.Volumes = []v1.Volume{{Name: "kubeconfig", VolumeSource: v1.VolumeSource{
+		HostPath: &v1.HostPathVolumeSource{Path: kubeVIPKubeConfig, Type: &hostPathType},
+	}}}
+	pod.Spec.Containers[0].VolumeMounts = []v1.VolumeMount{{Name: "kubeconfig", MountPath: kubeVIPKubeConfig}}
+	yaml, err := podToYaml(pod)
+	if err != nil {
+		logger.Error("decode kube-vip static pod yaml failed %s", err)
+		return ""
+	}
+	return string(yaml)
+}
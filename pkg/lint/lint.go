@@ -0,0 +1,250 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/kube-proxy/config/v1alpha1"
+	"k8s.io/kubelet/config/v1beta1"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/clusterfile"
+	"github.com/alibaba/sealer/pkg/env"
+	"github.com/alibaba/sealer/pkg/runtime"
+	"github.com/alibaba/sealer/pkg/runtime/kubeadm_types/v1beta2"
+	v1 "github.com/alibaba/sealer/types/api/v1"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in the Clusterfile, located by line and column.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// kubeadmKinds is the kubeadm documents decoded by runtime.LoadKubeadmConfigs.
+var kubeadmKinds = map[string]reflect.Type{
+	runtime.InitConfiguration:      reflect.TypeOf(v1beta2.InitConfiguration{}),
+	runtime.ClusterConfiguration:   reflect.TypeOf(v1beta2.ClusterConfiguration{}),
+	runtime.JoinConfiguration:      reflect.TypeOf(v1beta2.JoinConfiguration{}),
+	runtime.KubeletConfiguration:   reflect.TypeOf(v1beta1.KubeletConfiguration{}),
+	runtime.KubeProxyConfiguration: reflect.TypeOf(v1alpha1.KubeProxyConfiguration{}),
+}
+
+var syntaxErrorLine = regexp.MustCompile(`line (\d+)`)
+
+type linter struct {
+	file        string
+	diagnostics []Diagnostic
+	// the line of the ip which first appears, to report duplicate ips.
+	hostIPs map[string]int
+}
+
+func (l *linter) report(node *yaml.Node, severity Severity, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		File:     l.file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) errorf(node *yaml.Node, format string, args ...interface{}) {
+	l.report(node, SeverityError, format, args...)
+}
+
+func (l *linter) warnf(node *yaml.Node, format string, args ...interface{}) {
+	l.report(node, SeverityWarning, format, args...)
+}
+
+// Lint check every document of the Clusterfile against the schema of its kind and the semantic rules.
+func Lint(path string) ([]Diagnostic, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	// render the env templates like apply does, the lines are kept unless the env value is multi-line.
+	if bytes.Contains(data, []byte("{{")) {
+		cf := clusterfile.NewClusterFile(path)
+		if err := cf.Process(); err == nil {
+			cluster := cf.GetCluster()
+			if rendered, err := env.NewEnvProcessor(&cluster).Process(path); err == nil {
+				data = rendered
+			}
+		}
+	}
+	return LintData(path, data)
+}
+
+// LintData check the Clusterfile data, file is only used to locate the diagnostics.
+func LintData(file string, data []byte) ([]Diagnostic, error) {
+	l := &linter{file: file, hostIPs: map[string]int{}}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var clusters []*yaml.Node
+	for {
+		doc := &yaml.Node{}
+		if err := decoder.Decode(doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			// syntax error stops the decoding, report it with the line in the error message.
+			line := 1
+			if m := syntaxErrorLine.FindStringSubmatch(err.Error()); m != nil {
+				line, _ = strconv.Atoi(m[1])
+			}
+			l.diagnostics = append(l.diagnostics, Diagnostic{File: file, Line: line, Column: 1, Severity: SeverityError, Message: err.Error()})
+			return l.diagnostics, nil
+		}
+		if len(doc.Content) == 0 || isNull(doc.Content[0]) {
+			continue
+		}
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			l.errorf(root, "document must be an object, got %s", kindName(root))
+			continue
+		}
+		if l.lintDocument(root) {
+			clusters = append(clusters, root)
+		}
+	}
+
+	if len(clusters) == 0 {
+		l.diagnostics = append(l.diagnostics, Diagnostic{File: file, Line: 1, Column: 1, Severity: SeverityError,
+			Message: "no Cluster document found"})
+	}
+	if len(clusters) > 1 {
+		for _, c := range clusters[1:] {
+			l.warnf(c, "only the first Cluster document is used, this one is ignored")
+		}
+	}
+	return l.diagnostics, nil
+}
+
+// lintDocument check the document by its kind, return true if it is a Cluster.
+func (l *linter) lintDocument(root *yaml.Node) bool {
+	apiVersion, kind := mappingValue(root, "apiVersion"), mappingValue(root, "kind")
+	if apiVersion == nil || apiVersion.Value == "" {
+		l.errorf(root, "apiVersion is required")
+	}
+	if kind == nil || kind.Value == "" {
+		l.errorf(root, "kind is required")
+		return false
+	}
+
+	switch kind.Value {
+	case common.Cluster:
+		if apiVersion != nil && clusterfile.IsV1Cluster(apiVersion.Value) {
+			l.checkSchema(root, reflect.TypeOf(v1.Cluster{}), "")
+			l.requireName(root)
+			l.requireValue(root, "spec.image", "spec", "image")
+			return true
+		}
+		l.checkSchema(root, reflect.TypeOf(v2.Cluster{}), "")
+		l.lintCluster(root)
+		return true
+	case common.Config:
+		l.checkSchema(root, reflect.TypeOf(v1.Config{}), "")
+		l.lintConfig(root)
+	case common.Plugin:
+		l.checkSchema(root, reflect.TypeOf(v1.Plugin{}), "")
+		l.lintPlugin(root)
+	default:
+		t, ok := kubeadmKinds[kind.Value]
+		if !ok {
+			l.warnf(kind, "unknown kind %q, the document is ignored", kind.Value)
+			return false
+		}
+		l.checkSchema(root, t, "")
+	}
+	return false
+}
+
+// Check lint the Clusterfile and log the diagnostics, return error if any error found.
+func Check(path string) error {
+	diagnostics, err := Lint(path)
+	if err != nil {
+		return fmt.Errorf("failed to lint %s: %v", path, err)
+	}
+	errCount := 0
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			errCount++
+			logger.Error(d.String())
+			continue
+		}
+		logger.Warn(d.String())
+	}
+	if errCount != 0 {
+		return fmt.Errorf("found %d errors in %s, run sealer lint -f %s for details", errCount, path, path)
+	}
+	return nil
+}
+
+// HasError return true if any diagnostic is an error.
+func HasError(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// mappingValue return the value node of key in the mapping node, follow the keys for nested mapping.
+func mappingValue(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		node = resolveAlias(node)
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				value = resolveAlias(node.Content[i+1])
+				break
+			}
+		}
+		if value == nil {
+			return nil
+		}
+		node = value
+	}
+	return node
+}
@@ -0,0 +1,188 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"strings"
+	"testing"
+)
+
+const validCluster = `apiVersion: sealer.cloud/v2
+kind: Cluster
+metadata:
+  name: my-cluster
+spec:
+  image: kubernetes:v1.19.8
+  ssh:
+    passwd: xxx
+    port: "22"
+  hosts:
+    - ips: [ 192.168.0.2 ]
+      roles: [ master ]
+    - ips: [ 192.168.0.3 ]
+      roles: [ node ]
+`
+
+func TestLintData(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// want is the "line:column: severity: message" prefix of diagnostics.
+		want []string
+	}{
+		{
+			name: "valid",
+			data: validCluster + `---
+apiVersion: sealer.aliyun.com/v1alpha1
+kind: Plugin
+metadata:
+  name: label
+spec:
+  type: LABEL
+  action: PreGuest
+  on: 192.168.0.2-192.168.0.3
+---
+apiVersion: sealer.aliyun.com/v1alpha1
+kind: Config
+metadata:
+  name: redis-config
+spec:
+  path: etc/redis.yaml
+  strategy: merge
+  data: |
+    user: root
+---
+apiVersion: kubeadm.k8s.io/v1beta2
+kind: ClusterConfiguration
+networking:
+  podSubnet: 100.64.0.0/10
+`,
+		},
+		{
+			name: "cluster errors",
+			data: `apiVersion: sealer.cloud/v2
+kind: Cluster
+metadata:
+  name: my-cluster
+spec:
+  imag: kubernetes:v1.19.8
+  ssh:
+    port: abc
+  hosts:
+    - ips: [ 192.168.0.2, 192.168.0.300 ]
+      roles: [ master ]
+    - ips: [ 192.168.0.2 ]
+    - ips: [ 192.168.0.4 ]
+      roles: [ etcd, node ]
+    - ips: [ 192.168.0.5 ]
+      roles: [ etcd ]
+`,
+			want: []string{
+				"6:3: error: spec: unknown field \"imag\"",
+				"6:3: error: spec.image is required",
+				"8:11: error: invalid ssh port",
+				"10:27: error: invalid ip",
+				"12:7: error: host must have at least one role",
+				"12:14: error: duplicate ip 192.168.0.2, it is already defined at line 10",
+				"14:14: error: etcd role can not be combined with master or node role",
+			},
+		},
+		{
+			name: "ssh secret sources",
+			data: `apiVersion: sealer.cloud/v2
+kind: Cluster
+metadata:
+  name: my-cluster
+spec:
+  image: kubernetes:v1.19.8
+  ssh:
+    passwd: xxx
+    passwdFrom:
+      env: SSH_PASSWD
+  hosts:
+    - ips: [ 192.168.0.2 ]
+      roles: [ master ]
+      ssh:
+        pkPasswdFrom:
+          file: /root/pk-passwd
+          command: cat /root/pk-passwd
+`,
+			want: []string{
+				"8:13: warning: passwd is ignored since passwdFrom is set",
+				"16:11: error: pkPasswdFrom must set exactly one of env, file and command",
+			},
+		},
+		{
+			name: "plugin and config errors",
+			data: validCluster + `---
+apiVersion: sealer.aliyun.com/v1alpha1
+kind: Plugin
+metadata:
+  name: shell
+spec:
+  type: NOTEXIST
+  action: PreInit
+  on: role=master
+---
+apiVersion: sealer.aliyun.com/v1alpha1
+kind: Config
+metadata:
+  name: escape
+spec:
+  path: ../../etc/passwd
+  strategy: replace
+`,
+			want: []string{
+				"21:9: warning: unknown plugin type \"NOTEXIST\"",
+				"23:7: error: the action must be PostInstall",
+				"30:9: error: config path \"../../etc/passwd\"",
+				"31:13: error: unknown config strategy \"replace\"",
+			},
+		},
+		{
+			name: "no cluster",
+			data: `apiVersion: kubeadm.k8s.io/v1beta2
+kind: InitConfiguration
+localAPIEndpoint:
+  bindPort: "abc"
+`,
+			want: []string{
+				"4:13: error: localAPIEndpoint.bindPort: invalid value \"abc\", expected int",
+				"1:1: error: no Cluster document found",
+			},
+		},
+		{
+			name: "syntax error",
+			data: "apiVersion: v1\nkind: [Cluster\n",
+			want: []string{"1:1: error: yaml: line 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LintData("Clusterfile", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("LintData() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("LintData() got %d diagnostics %v, want %d", len(got), got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(got[i].String(), "Clusterfile:"+want) {
+					t.Errorf("LintData() diagnostic %d = %s, want prefix Clusterfile:%s", i, got[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/config"
+	"github.com/alibaba/sealer/pkg/plugin"
+)
+
+var pluginPhases = []plugin.Phase{
+	plugin.PhaseOriginally,
+	plugin.PhasePreInit,
+	plugin.PhasePreInstall,
+	plugin.PhasePreGuest,
+	plugin.PhasePostInstall,
+	plugin.PhasePostClean,
+}
+
+func (l *linter) requireName(root *yaml.Node) {
+	l.requireValue(root, "metadata.name", "metadata", "name")
+}
+
+// requireValue report error if the scalar value of keys is empty, it is reported on the nearest existing parent.
+func (l *linter) requireValue(root *yaml.Node, path string, keys ...string) *yaml.Node {
+	value := mappingValue(root, keys...)
+	if value != nil && value.Kind == yaml.ScalarNode && value.Value != "" {
+		return value
+	}
+	node := root
+	for i := len(keys) - 1; i > 0; i-- {
+		if parent := mappingValue(root, keys[:i]...); parent != nil {
+			node = parent
+			break
+		}
+	}
+	l.errorf(node, "%s is required", path)
+	return nil
+}
+
+func (l *linter) lintCluster(root *yaml.Node) {
+	l.requireName(root)
+	l.requireValue(root, "spec.image", "spec", "image")
+	l.lintEnv(mappingValue(root, "spec", "env"))
+	l.lintSSH(mappingValue(root, "spec", "ssh"))
+
+	hosts := mappingValue(root, "spec", "hosts")
+	if hosts == nil || hosts.Kind != yaml.SequenceNode || len(hosts.Content) == 0 {
+		l.errorf(root, "spec.hosts is required")
+		return
+	}
+	hasMaster := false
+	for _, host := range hosts.Content {
+		host = resolveAlias(host)
+		if host.Kind != yaml.MappingNode {
+			continue
+		}
+		roles := mappingValue(host, "roles")
+		if roles == nil || roles.Kind != yaml.SequenceNode || len(roles.Content) == 0 {
+			l.errorf(host, "host must have at least one role")
+		} else {
+			isClusterHost, isEtcdHost := false, false
+			for _, role := range roles.Content {
+				if role.Value == common.MASTER || role.Value == common.NODE {
+					isClusterHost = true
+				}
+				if role.Value == common.MASTER {
+					hasMaster = true
+				}
+				if role.Value == common.ETCD {
+					isEtcdHost = true
+				}
+			}
+			switch {
+			case isEtcdHost && isClusterHost:
+				// the kubelet of etcd host runs in standalone mode.
+				l.errorf(roles, "%s role can not be combined with %s or %s role", common.ETCD, common.MASTER, common.NODE)
+			case !isClusterHost && !isEtcdHost:
+				l.warnf(roles, "host has neither %s nor %s role, it will not join the cluster", common.MASTER, common.NODE)
+			}
+		}
+
+		ips := mappingValue(host, "ips")
+		if ips == nil || ips.Kind != yaml.SequenceNode || len(ips.Content) == 0 {
+			l.errorf(host, "host must have at least one ip")
+		} else {
+			for _, ip := range ips.Content {
+				l.lintHostIP(ip)
+			}
+		}
+		l.lintEnv(mappingValue(host, "env"))
+		l.lintSSH(mappingValue(host, "ssh"))
+	}
+	if !hasMaster {
+		l.errorf(hosts, "at least one host must have the %s role", common.MASTER)
+	}
+}
+
+func (l *linter) lintHostIP(ip *yaml.Node) {
+	if ip.Kind != yaml.ScalarNode {
+		return
+	}
+	if net.ParseIP(ip.Value) == nil {
+		l.errorf(ip, "invalid ip %q", ip.Value)
+		return
+	}
+	if line, ok := l.hostIPs[ip.Value]; ok {
+		l.errorf(ip, "duplicate ip %s, it is already defined at line %d", ip.Value, line)
+		return
+	}
+	l.hostIPs[ip.Value] = ip.Line
+}
+
+func (l *linter) lintEnv(env *yaml.Node) {
+	if env == nil || env.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, e := range env.Content {
+		if e.Kind == yaml.ScalarNode && !strings.Contains(e.Value, "=") {
+			l.warnf(e, "env %q is not in the format of key=value, it is ignored", e.Value)
+		}
+	}
+}
+
+func (l *linter) lintSSH(ssh *yaml.Node) {
+	l.lintValueFrom(ssh, "passwd")
+	l.lintValueFrom(ssh, "pkPasswd")
+	port := mappingValue(ssh, "port")
+	if port == nil || port.Kind != yaml.ScalarNode || port.Value == "" {
+		return
+	}
+	if p, err := strconv.Atoi(port.Value); err != nil || p <= 0 || p > 65535 {
+		l.errorf(port, "invalid ssh port %q", port.Value)
+	}
+}
+
+// lintValueFrom check the secret source of key like passwdFrom.
+func (l *linter) lintValueFrom(ssh *yaml.Node, key string) {
+	from := mappingValue(ssh, key+"From")
+	if from == nil || from.Kind != yaml.MappingNode {
+		return
+	}
+	n := 0
+	for _, k := range []string{"env", "file", "command"} {
+		if v := mappingValue(from, k); v != nil && v.Value != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		l.errorf(from, "%sFrom must set exactly one of env, file and command", key)
+	}
+	if v := mappingValue(ssh, key); v != nil && v.Value != "" {
+		l.warnf(v, "%s is ignored since %sFrom is set", key, key)
+	}
+}
+
+func (l *linter) lintConfig(root *yaml.Node) {
+	l.requireName(root)
+	if path := l.requireValue(root, "spec.path", "spec", "path"); path != nil {
+		// the config is written to the path under cluster rootfs.
+		cleaned := filepath.Clean(path.Value)
+		if filepath.IsAbs(path.Value) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			l.errorf(path, "config path %q must be a relative path inside the cluster rootfs", path.Value)
+		}
+	}
+
+	strategy := mappingValue(root, "spec", "strategy")
+	if strategy == nil || strategy.Value == "" {
+		return
+	}
+	if strategy.Value != config.Merge && strategy.Value != config.Overwrite {
+		l.errorf(strategy, "unknown config strategy %q, must be %s or %s", strategy.Value, config.Merge, config.Overwrite)
+		return
+	}
+	data := mappingValue(root, "spec", "data")
+	if strategy.Value == config.Merge && data != nil {
+		content := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(data.Value), &content); err != nil {
+			l.errorf(data, "config data must be yaml when strategy is %s: %v", config.Merge, err)
+		}
+	}
+}
+
+func (l *linter) lintPlugin(root *yaml.Node) {
+	l.requireName(root)
+	if pluginType := l.requireValue(root, "spec.type", "spec", "type"); pluginType != nil && !plugin.IsRegistered(pluginType.Value) {
+		// out-of-tree plugins are loaded from the cluster image, they are unknown until the image is mounted.
+		l.warnf(pluginType, "unknown plugin type %q, it must be provided by an out-of-tree plugin of the cluster image", pluginType.Value)
+	}
+
+	action := l.requireValue(root, "spec.action", "spec", "action")
+	if action == nil {
+		return
+	}
+	validAction := false
+	for _, phase := range pluginPhases {
+		if action.Value == string(phase) {
+			validAction = true
+		}
+	}
+	if !validAction {
+		l.errorf(action, "unknown plugin action %q, must be one of %v", action.Value, pluginPhases)
+		return
+	}
+
+	on := mappingValue(root, "spec", "on")
+	if on == nil || on.Kind != yaml.ScalarNode || strings.TrimSpace(on.Value) == "" {
+		return
+	}
+	l.lintPluginOn(on, plugin.Phase(action.Value))
+}
+
+// lintPluginOn check the on field like plugin.GetIpsByOnField does.
+func (l *linter) lintPluginOn(on *yaml.Node, phase plugin.Phase) {
+	value := strings.TrimSpace(on.Value)
+	if strings.Contains(value, plugin.EqualSymbol) {
+		if phase != plugin.PhasePostInstall {
+			l.errorf(on, "the action must be %s when nodes are specified by label %q", plugin.PhasePostInstall, value)
+		}
+		return
+	}
+	if value == common.MASTER || value == common.NODE || value == common.MASTER0 {
+		return
+	}
+	for _, item := range strings.Split(value, ",") {
+		for _, ip := range strings.Split(item, plugin.DelSymbol) {
+			if net.ParseIP(strings.TrimSpace(ip)) == nil {
+				l.errorf(on, "invalid on field %q, must be %s, %s, %s, a label selector or ip list", value,
+					common.MASTER, common.NODE, common.MASTER0)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// checkSchema walk the yaml node with the go type which the document is decoded to,
+// report unknown fields and values of wrong type.
+func (l *linter) checkSchema(node *yaml.Node, t reflect.Type, path string) {
+	node = resolveAlias(node)
+	if node == nil || isNull(node) {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// types like metav1.Time and resource.Quantity decode themselves.
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			l.errorf(node, "%s: expected an object, got %s", path, kindName(node))
+			return
+		}
+		fields := jsonFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			ft, ok := lookupField(fields, key.Value)
+			if !ok {
+				l.errorf(key, "%s: unknown field %q", path, key.Value)
+				continue
+			}
+			l.checkSchema(value, ft, joinPath(path, key.Value))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			l.errorf(node, "%s: expected a map, got %s", path, kindName(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			l.checkSchema(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+		}
+	case reflect.Slice, reflect.Array:
+		// []byte is encoded as base64 string.
+		if t.Elem().Kind() == reflect.Uint8 {
+			l.checkScalar(node, path, "")
+			return
+		}
+		if node.Kind != yaml.SequenceNode {
+			l.errorf(node, "%s: expected a list, got %s", path, kindName(node))
+			return
+		}
+		for _, item := range node.Content {
+			l.checkSchema(item, t.Elem(), path+"[]")
+		}
+	case reflect.Bool:
+		l.checkScalar(node, path, "!!bool")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		l.checkScalar(node, path, "!!int")
+	case reflect.Float32, reflect.Float64:
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!int" {
+			return
+		}
+		l.checkScalar(node, path, "!!float")
+	case reflect.String:
+		// numbers and booleans are converted to string by sigs.k8s.io/yaml.
+		l.checkScalar(node, path, "")
+	}
+}
+
+func (l *linter) checkScalar(node *yaml.Node, path, tag string) {
+	if node.Kind != yaml.ScalarNode {
+		l.errorf(node, "%s: expected a scalar value, got %s", path, kindName(node))
+		return
+	}
+	if tag != "" && node.Tag != tag {
+		l.errorf(node, "%s: invalid value %q, expected %s", path, node.Value, strings.TrimPrefix(tag, "!!"))
+	}
+}
+
+// jsonFields return the fields of struct by json name, the fields of inline and embedded structs are flattened.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if name == "" && (f.Anonymous || strings.Contains(tag, "inline")) && ft.Kind() == reflect.Struct {
+			for n, t := range jsonFields(ft) {
+				fields[n] = t
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupField match the key like encoding/json, which prefer the exact match and fall back to case-insensitive one.
+func lookupField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func kindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "an object"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return "a scalar value"
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
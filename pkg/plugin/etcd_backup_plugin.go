@@ -15,22 +15,15 @@
 package plugin
 
 import (
-	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"errors"
-	"fmt"
-	"io/ioutil"
-	"time"
 
-	clientv3 "go.etcd.io/etcd/client/v3"
-	"go.etcd.io/etcd/client/v3/snapshot"
-	"go.uber.org/zap"
-
-	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/client/etcd"
 	"github.com/alibaba/sealer/utils/ssh"
 )
 
+// the certs are fetched to certDir to connect etcd.
+const certDir = "/tmp"
+
 type EtcdBackupPlugin struct {
 }
 
@@ -48,16 +41,20 @@ func (e EtcdBackupPlugin) Run(context Context, phase Phase) error {
 		return err
 	}
 
-	if err := fetchRemoteCert(context, masterIP); err != nil {
+	sshClient, err := ssh.GetHostSSHClient(masterIP, context.Cluster)
+	if err != nil {
+		return err
+	}
+	if err := etcd.FetchCerts(sshClient, masterIP, certDir); err != nil {
 		return err
 	}
 
-	cfg, err := connEtcd(masterIP)
+	cfg, err := etcd.NewConfig([]string{etcd.GetEndpoint(masterIP)}, certDir)
 	if err != nil {
 		return err
 	}
 
-	return snapshotEtcd(context.Plugin.Spec.On, cfg)
+	return etcd.Snapshot(cfg, context.Plugin.Spec.On)
 }
 
 func getMasterIP(context Context) (string, error) {
@@ -67,79 +64,3 @@ func getMasterIP(context Context) (string, error) {
 	}
 	return masterIPList[0], nil
 }
-
-func fetchRemoteCert(context Context, masterIP string) error {
-	certs := []string{"healthcheck-client.crt", "healthcheck-client.key", "ca.crt"}
-	for _, cert := range certs {
-		sshClient, err := ssh.GetHostSSHClient(masterIP, context.Cluster)
-		if err != nil {
-			return err
-		}
-		if err := sshClient.Fetch(masterIP, "/tmp/"+cert, "/etc/kubernetes/pki/etcd/"+cert); err != nil {
-			return fmt.Errorf("host %s %s file does not exist, err: %v", masterIP, cert, err)
-		}
-	}
-	return nil
-}
-
-func connEtcd(masterIP string) (clientv3.Config, error) {
-	const (
-		dialTimeout = 5 * time.Second
-		etcdCert    = "/tmp/healthcheck-client.crt"
-		etcdCertKey = "/tmp/healthcheck-client.key"
-		etcdCa      = "/tmp/ca.crt"
-	)
-
-	cert, err := tls.LoadX509KeyPair(etcdCert, etcdCertKey)
-	if err != nil {
-		return clientv3.Config{}, fmt.Errorf("cacert or key file is not exist, err:%v", err)
-	}
-
-	caData, err := ioutil.ReadFile(etcdCa)
-	if err != nil {
-		return clientv3.Config{}, fmt.Errorf("ca certificate reading failed, err:%v", err)
-	}
-
-	pool := x509.NewCertPool()
-	pool.AppendCertsFromPEM(caData)
-	// #nosec
-	_tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		RootCAs:      pool,
-	}
-
-	endpoints := []string{fmt.Sprintf("https://%s:2379", masterIP)}
-	cfg := clientv3.Config{
-		Endpoints:   endpoints,
-		DialTimeout: dialTimeout,
-		TLS:         _tlsConfig,
-	}
-
-	cli, err := clientv3.New(cfg)
-	if err != nil {
-		return clientv3.Config{}, fmt.Errorf("connect to etcd failed, err:%v", err)
-	}
-
-	logger.Info("connect to etcd success")
-
-	defer cli.Close()
-
-	return cfg, nil
-}
-
-func snapshotEtcd(snapshotPath string, cfg clientv3.Config) error {
-	lg, err := zap.NewProduction()
-	if err != nil {
-		return fmt.Errorf("get zap logger error, err:%v", err)
-	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	if err := snapshot.Save(ctx, lg, cfg, snapshotPath); err != nil {
-		return fmt.Errorf("snapshot save err: %v", err)
-	}
-	logger.Info("Snapshot saved at %s\n", snapshotPath)
-
-	return nil
-}
@@ -29,3 +29,9 @@ func Register(name string, factory Interface) {
 
 	pluginFactories[name] = factory
 }
+
+// IsRegistered return true if the plugin type is registered by in-tree plugins or loaded out-of-tree plugins.
+func IsRegistered(name string) bool {
+	_, ok := pluginFactories[name]
+	return ok
+}
@@ -0,0 +1,221 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	Docker     = "docker"
+	Containerd = "containerd"
+
+	ContainerdCertDir = "/etc/containerd/certs.d"
+	// ContainerdNamespace is the namespace of cri plugin, images loaded in it could be used by kubelet without pulling.
+	ContainerdNamespace = "k8s.io"
+	RegistryImage       = "registry:2.7.1"
+
+	// RegistryGarbageCollectCommand stop the registry, run the garbage collection in a one-off container on the
+	// registry data dir and config of rootfs, and start the registry again even if the garbage collection failed.
+	// The args are the cli, the registry name, the run args and the registry image.
+	RegistryGarbageCollectCommand = `%[1]s stop %[2]s && %[1]s run --rm %[3]s %[4]s garbage-collect --delete-untagged /etc/docker/registry/config.yml; rc=$?; %[1]s start %[2]s && [ $rc -eq 0 ]`
+
+	DetectContainerRuntimeCmd = `if command -v docker > /dev/null 2>&1 && docker info > /dev/null 2>&1; then echo docker; else echo containerd; fi`
+	DockerLoginCommand        = "docker login %s -u %s -p %s"
+	RemoteWriteHostsToml      = `mkdir -p %s && echo '%s' > %s/hosts.toml`
+	containerdHostsToml       = `server = "https://%s"
+
+[host."https://%s"]
+  capabilities = ["pull", "resolve", "push"]
+  ca = "%s"`
+	// the basic auth is sent in the header of every request to registry, the base64 encoded value needs no escaping.
+	containerdHostsTomlAuth = `
+
+  [host."https://%s".header]
+    Authorization = ["Basic %s"]`
+)
+
+// ContainerRuntime is the container runtime of the cluster hosts, it runs the registry of ClusterImage and
+// let the hosts trust and login to the registry. All the methods return the commands executed on hosts.
+type ContainerRuntime interface {
+	Name() string
+	// CRISocket is the cri socket used by kubelet.
+	CRISocket() string
+	// CgroupDriverCmd print the cgroup driver of the container runtime.
+	CgroupDriverCmd() string
+	// RegistryCertDir is the dir of registry certs, the cert of registry is sent to <dir>/<domain>:<port>/<domain>.crt.
+	RegistryCertDir() string
+	// TrustRegistryCmd let the container runtime trust the registry cert sent to RegistryCertDir, empty if nothing to do.
+	TrustRegistryCmd(cf *RegistryConfig) string
+	// LoginRegistryCmd login to the registry with the auth of cf, empty if nothing to do.
+	LoginRegistryCmd(cf *RegistryConfig) string
+	// StartRegistryCmd preload the images of rootfs and start the registry on the registry host.
+	StartRegistryCmd(rootfs string, cf *RegistryConfig) string
+	StopRegistryCmd() string
+	// GarbageCollectRegistryCmd remove the blobs not referenced by any manifest and the untagged manifests from the
+	// registry, the registry is stopped during the garbage collection.
+	GarbageCollectRegistryCmd(rootfs string) string
+}
+
+// NewContainerRuntime return the container runtime of name, it is docker or containerd.
+func NewContainerRuntime(name string) (ContainerRuntime, error) {
+	switch strings.TrimSpace(name) {
+	case Docker:
+		return &DockerRuntime{}, nil
+	case Containerd:
+		return &ContainerdRuntime{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported container runtime %s", name)
+	}
+}
+
+func registryEndpoint(cf *RegistryConfig) string {
+	return fmt.Sprintf("%s:%s", cf.Domain, cf.Port)
+}
+
+// registryCertPath return the path of registry cert trusted by the container runtime.
+func registryCertPath(cr ContainerRuntime, cf *RegistryConfig) string {
+	return filepath.Join(cr.RegistryCertDir(), registryEndpoint(cf), cf.Domain+".crt")
+}
+
+type DockerRuntime struct{}
+
+func (d *DockerRuntime) Name() string {
+	return Docker
+}
+
+func (d *DockerRuntime) CRISocket() string {
+	return DefaultDockerCRISocket
+}
+
+func (d *DockerRuntime) CgroupDriverCmd() string {
+	return DockerShell
+}
+
+func (d *DockerRuntime) RegistryCertDir() string {
+	return DockerCertDir
+}
+
+// TrustRegistryCmd docker trusts the certs in DockerCertDir without any config.
+func (d *DockerRuntime) TrustRegistryCmd(cf *RegistryConfig) string {
+	return ""
+}
+
+func (d *DockerRuntime) LoginRegistryCmd(cf *RegistryConfig) string {
+	return fmt.Sprintf(DockerLoginCommand, registryEndpoint(cf), cf.Username, cf.Password)
+}
+
+// StartRegistryCmd use the init-registry.sh of ClusterImage, which loads images and runs registry by docker. The
+// deletion of registry is enabled by REGISTRY_STORAGE_DELETE_ENABLED for the script to pass to the registry.
+func (d *DockerRuntime) StartRegistryCmd(rootfs string, cf *RegistryConfig) string {
+	return fmt.Sprintf("cd %s/scripts && REGISTRY_STORAGE_DELETE_ENABLED=true sh init-registry.sh %s %s %s", rootfs, cf.Port, filepath.Join(rootfs, "registry"), cf.Domain)
+}
+
+func (d *DockerRuntime) StopRegistryCmd() string {
+	return fmt.Sprintf("if docker inspect %s;then docker rm -f %s;fi", RegistryName, RegistryName)
+}
+
+func (d *DockerRuntime) GarbageCollectRegistryCmd(rootfs string) string {
+	return fmt.Sprintf(RegistryGarbageCollectCommand, "docker", RegistryName, registryVolumeArgs(rootfs), RegistryImage)
+}
+
+// registryVolumeArgs return the volume args of the registry data dir and config of rootfs, the config is mounted
+// only if it exists like init-registry.sh does.
+func registryVolumeArgs(rootfs string) string {
+	config := filepath.Join(rootfs, "etc", RegistryBindConfig)
+	return fmt.Sprintf(`-v %s:%s $(if [ -f %s ]; then echo "-v %s:/etc/docker/registry/config.yml"; fi)`,
+		filepath.Join(rootfs, "registry"), RegistryBindDest, config, config)
+}
+
+// ContainerdRuntime uses ctr to load images, nerdctl to run registry and hosts.toml to trust and login to registry,
+// the config_path of cri registry in containerd config should be ContainerdCertDir.
+type ContainerdRuntime struct{}
+
+func (c *ContainerdRuntime) Name() string {
+	return Containerd
+}
+
+func (c *ContainerdRuntime) CRISocket() string {
+	return DefaultContainerdCRISocket
+}
+
+func (c *ContainerdRuntime) CgroupDriverCmd() string {
+	return ContainerdShell
+}
+
+func (c *ContainerdRuntime) RegistryCertDir() string {
+	return ContainerdCertDir
+}
+
+// TrustRegistryCmd write the hosts.toml of registry in ContainerdCertDir, which is read by containerd on every pull
+// without restarting it. The auth of cf is kept in it, so trusting the registry again does not drop the login.
+func (c *ContainerdRuntime) TrustRegistryCmd(cf *RegistryConfig) string {
+	endpoint := registryEndpoint(cf)
+	dir := filepath.Join(ContainerdCertDir, endpoint)
+	hostsToml := fmt.Sprintf(containerdHostsToml, endpoint, endpoint, registryCertPath(c, cf))
+	if cf.Username != "" && cf.Password != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(cf.Username + ":" + cf.Password))
+		hostsToml += fmt.Sprintf(containerdHostsTomlAuth, endpoint, auth)
+	}
+	return fmt.Sprintf(RemoteWriteHostsToml, dir, hostsToml, dir)
+}
+
+// LoginRegistryCmd set the auth used by kubelet to pull images in the hosts.toml of registry instead of containerd
+// config, the login of nerdctl is only used by nerdctl itself.
+func (c *ContainerdRuntime) LoginRegistryCmd(cf *RegistryConfig) string {
+	return c.TrustRegistryCmd(cf)
+}
+
+// StartRegistryCmd import the images of rootfs to ContainerdNamespace, and run registry from the imported image
+// with the certs, config and htpasswd of rootfs like init-registry.sh does.
+func (c *ContainerdRuntime) StartRegistryCmd(rootfs string, cf *RegistryConfig) string {
+	var (
+		imageDir = filepath.Join(rootfs, "images")
+		certsDir = filepath.Join(rootfs, "certs")
+		dataDir  = filepath.Join(rootfs, "registry")
+		config   = filepath.Join(rootfs, "etc", RegistryBindConfig)
+		htpasswd = filepath.Join(rootfs, "etc", DefaultRegistryHtPasswdFile)
+		nerdctl  = "nerdctl -n " + ContainerdNamespace
+	)
+	args := strings.Join([]string{
+		"-d --restart=always --net=host --name " + RegistryName,
+		fmt.Sprintf("-v %s:/certs -v %s:%s", certsDir, dataDir, RegistryBindDest),
+		fmt.Sprintf("-e REGISTRY_HTTP_ADDR=0.0.0.0:%s", cf.Port),
+		fmt.Sprintf("-e REGISTRY_HTTP_TLS_CERTIFICATE=/certs/%s.crt -e REGISTRY_HTTP_TLS_KEY=/certs/%s.key", cf.Domain, cf.Domain),
+		"-e REGISTRY_STORAGE_DELETE_ENABLED=true",
+	}, " ")
+	cmds := []string{
+		fmt.Sprintf("mkdir -p %s", dataDir),
+		fmt.Sprintf(`for image in %s/*; do if [ -f "$image" ]; then ctr -n %s images import "$image"; fi; done`, imageDir, ContainerdNamespace),
+		fmt.Sprintf("(%s rm -f %s > /dev/null 2>&1 || true)", nerdctl, RegistryName),
+		fmt.Sprintf(`args="%s"`, args),
+		fmt.Sprintf(`if [ -f %s ]; then args="$args -v %s:/etc/docker/registry/config.yml"; fi`, config, config),
+		fmt.Sprintf(`if [ -f %s ]; then args="$args -v %s:/htpasswd -e REGISTRY_AUTH=htpasswd -e REGISTRY_AUTH_HTPASSWD_PATH=/htpasswd -e REGISTRY_AUTH_HTPASSWD_REALM=sealer"; fi`, htpasswd, htpasswd),
+		fmt.Sprintf("%s run --pull=never $args %s", nerdctl, RegistryImage),
+	}
+	return strings.Join(cmds, " && ")
+}
+
+func (c *ContainerdRuntime) StopRegistryCmd() string {
+	return fmt.Sprintf("if nerdctl -n %s inspect %s > /dev/null 2>&1;then nerdctl -n %s rm -f %s;fi", ContainerdNamespace, RegistryName, ContainerdNamespace, RegistryName)
+}
+
+func (c *ContainerdRuntime) GarbageCollectRegistryCmd(rootfs string) string {
+	return fmt.Sprintf(RegistryGarbageCollectCommand, "nerdctl -n "+ContainerdNamespace, RegistryName,
+		"--pull=never "+registryVolumeArgs(rootfs), RegistryImage)
+}
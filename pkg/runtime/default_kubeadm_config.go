@@ -93,9 +93,6 @@ etcd:
 apiVersion: kubeproxy.config.k8s.io/v1alpha1
 kind: KubeProxyConfiguration
 mode: "ipvs"
-ipvs:
-  excludeCIDRs:
-    - "10.103.97.2/32"
 
 ---
 apiVersion: kubelet.config.k8s.io/v1beta1
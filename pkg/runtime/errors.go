@@ -0,0 +1,46 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// HostsError is returned when an action failed on part of hosts,
+// the Succeeded hosts are done and need not to be retried.
+type HostsError struct {
+	Succeeded []string
+	Failed    map[string]error
+}
+
+func (e *HostsError) Error() string {
+	var msgs []string
+	for _, host := range e.FailedHosts() {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", host, e.Failed[host]))
+	}
+	return fmt.Sprintf("failed on hosts [%s]", strings.Join(msgs, "; "))
+}
+
+// FailedHosts return the ips of failed hosts in order.
+func (e *HostsError) FailedHosts() []string {
+	var hosts []string
+	for host := range e.Failed {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,622 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/cert"
+	"github.com/alibaba/sealer/pkg/client/etcd"
+	"github.com/alibaba/sealer/pkg/client/k8s"
+	"github.com/alibaba/sealer/pkg/runtime/kubeadm_types/v1beta2"
+	"github.com/alibaba/sealer/utils"
+)
+
+// The external etcd members run as static pods of a standalone kubelet, like the kubeadm HA etcd setup.
+const (
+	EtcdPKIDir                  = "/etc/kubernetes/pki/etcd"
+	DefaultEtcdDataDir          = "/var/lib/etcd"
+	EtcdInitialClusterStateNew  = "new"
+	EtcdInitialClusterStateJoin = "existing"
+	RemoteWriteEtcdConfig       = `echo '%s' > %s/etc/kubeadm-etcd.yml`
+	RemoteRemoveEtcdKubeletConf = `rm -f /etc/systemd/system/kubelet.service.d/20-etcd-service-manager.conf && systemctl daemon-reload`
+	RemoteWriteEtcdKubeletConf  = `mkdir -p /etc/systemd/system/kubelet.service.d && cat > /etc/systemd/system/kubelet.service.d/20-etcd-service-manager.conf <<EOF
+[Service]
+ExecStart=
+ExecStart=/usr/bin/kubelet --address=127.0.0.1 --pod-manifest-path=/etc/kubernetes/manifests --cgroup-driver=%s --pod-infra-container-image=$(kubeadm config images list --config=%s/etc/kubeadm-etcd.yml 2>/dev/null | grep pause)%s
+Restart=always
+EOF
+systemctl daemon-reload && systemctl restart kubelet`
+	RemoteInitEtcd          = "kubeadm init phase etcd local --config=%s/etc/kubeadm-etcd.yml"
+	RemoteEtcdctl           = `crictl --runtime-endpoint unix://%s exec $(crictl --runtime-endpoint unix://%s ps -q --name '^etcd$' | head -n 1) etcdctl --endpoints=https://localhost:2379 --cacert=/etc/kubernetes/pki/etcd/ca.crt --cert=/etc/kubernetes/pki/etcd/healthcheck-client.crt --key=/etc/kubernetes/pki/etcd/healthcheck-client.key %s`
+	EtcdctlMemberAdd        = "member add %s --peer-urls=%s"
+	EtcdctlMemberList       = "member list"
+	EtcdctlMemberDel        = "member remove %s"
+	EtcdctlHealth           = "endpoint health --cluster"
+	RemoteListEtcdSnapshots = "ls %s/*.db 2>/dev/null | xargs -r stat -c '%%n|%%s|%%Y'"
+	// the etcdctl in etcd container is saved before restoring, it is used to restore etcd when etcd is stopped.
+	// The one in rootfs bin is used if etcd is not running, it is copied to a temp file first so no empty etcdctl is left.
+	RemoteBackupEtcdctl = `if [ ! -s %[1]s/etcdctl ]; then mkdir -p %[1]s && (crictl --runtime-endpoint unix://%[2]s exec $(crictl --runtime-endpoint unix://%[2]s ps -q --name '^etcd$' | head -n 1) cat /usr/local/bin/etcdctl > %[1]s/etcdctl.tmp && [ -s %[1]s/etcdctl.tmp ] || cp -f %[3]s/bin/etcdctl %[1]s/etcdctl.tmp) && chmod +x %[1]s/etcdctl.tmp && mv -f %[1]s/etcdctl.tmp %[1]s/etcdctl; fi`
+	RemoteRestoreEtcd   = `rm -rf %[6]s-restore && ETCDCTL_API=3 %[1]s/etcdctl snapshot restore %[2]s --name %[3]s --initial-cluster %[4]s --initial-advertise-peer-urls %[5]s --data-dir %[6]s-restore && mv %[6]s %[6]s-before-restore-$(date +%%Y%%m%%d%%H%%M%%S) && mv %[6]s-restore %[6]s`
+	// the static pod is stopped by moving its manifest out until the container is gone, and started by moving it back.
+	// The manifest is moved back and the command fails if the container is not gone in time.
+	RemoteStopStaticPod  = `if [ -f /etc/kubernetes/manifests/%[1]s.yaml ]; then mkdir -p %[2]s && mv -f /etc/kubernetes/manifests/%[1]s.yaml %[2]s/%[1]s.yaml.stopped && n=0 && while crictl --runtime-endpoint unix://%[3]s ps -q --name '^%[1]s$' | grep -q .; do if [ $n -ge %[4]d ]; then mv -f %[2]s/%[1]s.yaml.stopped /etc/kubernetes/manifests/%[1]s.yaml; echo "timed out waiting for %[1]s to stop" >&2; exit 1; fi; sleep 2; n=$((n+2)); done; fi`
+	RemoteStartStaticPod = `if [ -f %[2]s/%[1]s.yaml.stopped ]; then mv -f %[2]s/%[1]s.yaml.stopped /etc/kubernetes/manifests/%[1]s.yaml; fi`
+	// staticPodStopTimeout is how long to wait for the container of a static pod to be gone after its manifest is moved out.
+	staticPodStopTimeout = 2 * time.Minute
+	// the endpoints of etcd.external in kubeadm-config are not updated, the etcd-servers arg overrides them.
+	RemoteUpdateAPIServerEtcdServers     = `if [ -f /etc/kubernetes/manifests/kube-apiserver.yaml ];then sed -i 's#--etcd-servers=.*#--etcd-servers=%s#' /etc/kubernetes/manifests/kube-apiserver.yaml;fi`
+	RemoteUpdateKubeadmConfigEtcdServers = `kubectl -n kube-system get cm kubeadm-config -o yaml | sed 's#etcd-servers: .*#etcd-servers: %s#' | kubectl replace -f -`
+)
+
+var etcdInitialClusterRegex = regexp.MustCompile(`ETCD_INITIAL_CLUSTER="([^"]*)"`)
+
+func getEtcdPeerURL(ip string) string {
+	return "https://" + net.JoinHostPort(utils.GetHostIP(ip), "2380")
+}
+
+// getEtcdInitialCluster return the initial-cluster arg of etcd, names are the hostnames of the ips.
+func getEtcdInitialCluster(ips, names []string) string {
+	var members []string
+	for i, ip := range ips {
+		members = append(members, fmt.Sprintf("%s=%s", names[i], getEtcdPeerURL(ip)))
+	}
+	return strings.Join(members, ",")
+}
+
+// getEtcdMemberID find the member id by peer url from the output of etcdctl member list, like:
+// 8e9e05c52164694d, started, etcd-0, https://192.168.0.10:2380, https://192.168.0.10:2379, false
+func getEtcdMemberID(memberList, peerURL string) string {
+	for _, line := range strings.Split(memberList, "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) < 4 {
+			continue
+		}
+		if strings.TrimSpace(fields[3]) == peerURL {
+			return strings.TrimSpace(fields[0])
+		}
+	}
+	return ""
+}
+
+// /var/lib/sealer/data/my-cluster/etcd/192.168.0.10, it is not in the pki dir which is sent to masters.
+func (k *KubeadmRuntime) getEtcdMemberCertPath(ip string) string {
+	return filepath.Join(k.getBasePath(), "etcd", ip)
+}
+
+// getEtcdHosts return the hosts running etcd members, they are masters if etcd is stacked.
+func (k *KubeadmRuntime) getEtcdHosts() []string {
+	if len(k.GetEtcdIPList()) != 0 {
+		return k.GetEtcdIPList()
+	}
+	return k.GetMasterIPList()
+}
+
+// /var/lib/sealer/data/my-cluster/etcd-snapshots on the etcd host.
+func (k *KubeadmRuntime) getEtcdSnapshotDir() string {
+	return filepath.Join(k.getBasePath(), "etcd-snapshots")
+}
+
+// getCRISocket return the configured cri socket, the default sockets follow the container runtime.
+func (k *KubeadmRuntime) getCRISocket() (string, error) {
+	switch socket := k.InitConfiguration.NodeRegistration.CRISocket; socket {
+	case "", DefaultDockerCRISocket, DefaultContainerdCRISocket:
+		cr, err := k.getContainerRuntime()
+		if err != nil {
+			return "", err
+		}
+		return cr.CRISocket(), nil
+	default:
+		return socket, nil
+	}
+}
+
+// runEtcdctl run the etcdctl cmd in the etcd container on host.
+func (k *KubeadmRuntime) runEtcdctl(host, cmd string) (string, error) {
+	socket, err := k.getCRISocket()
+	if err != nil {
+		return "", err
+	}
+	return k.CmdToString(host, fmt.Sprintf(RemoteEtcdctl, socket, socket, cmd), "\n")
+}
+
+// getExternalEtcd return the etcd.external of ClusterConfiguration, masters use the certs sent with the pki dir.
+func (k *KubeadmRuntime) getExternalEtcd() *v1beta2.ExternalEtcd {
+	var endpoints []string
+	for _, ip := range k.GetEtcdIPList() {
+		endpoints = append(endpoints, etcd.GetEndpoint(utils.GetHostIP(ip)))
+	}
+	return &v1beta2.ExternalEtcd{
+		Endpoints: endpoints,
+		CAFile:    filepath.Join(EtcdPKIDir, "ca.crt"),
+		CertFile:  filepath.Join(cert.KubeDefaultCertPath, "apiserver-etcd-client.crt"),
+		KeyFile:   filepath.Join(cert.KubeDefaultCertPath, "apiserver-etcd-client.key"),
+	}
+}
+
+// getEtcdDataDir return the data dir of etcd.local in kubeadm config, which is used by both stacked and external etcd.
+func (k *KubeadmRuntime) getEtcdDataDir() string {
+	if k.Etcd.Local != nil && k.Etcd.Local.DataDir != "" {
+		return k.Etcd.Local.DataDir
+	}
+	return DefaultEtcdDataDir
+}
+
+// etcdConfig return the kubeadm config used by "kubeadm init phase etcd local" on the etcd host,
+// the etcd.local of Clusterfile is inherited, like the image and extraArgs.
+func (k *KubeadmRuntime) etcdConfig(ip, name, initialCluster, state string) ([]byte, error) {
+	local := v1beta2.LocalEtcd{DataDir: k.getEtcdDataDir(), ExtraArgs: map[string]string{}}
+	if k.Etcd.Local != nil {
+		local.ImageMeta = k.Etcd.Local.ImageMeta
+		for key, value := range k.Etcd.Local.ExtraArgs {
+			local.ExtraArgs[key] = value
+		}
+	}
+	local.ExtraArgs["initial-cluster"] = initialCluster
+	local.ExtraArgs["initial-cluster-state"] = state
+
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return nil, err
+	}
+	initConfig := v1beta2.InitConfiguration{}
+	initConfig.APIVersion = k.InitConfiguration.APIVersion
+	initConfig.Kind = InitConfiguration
+	initConfig.NodeRegistration.Name = name
+	initConfig.NodeRegistration.CRISocket = criSocket
+	initConfig.LocalAPIEndpoint.AdvertiseAddress = utils.GetHostIP(ip)
+
+	clusterConfig := v1beta2.ClusterConfiguration{}
+	clusterConfig.APIVersion = k.ClusterConfiguration.APIVersion
+	clusterConfig.Kind = ClusterConfiguration
+	clusterConfig.KubernetesVersion = k.getKubeVersion()
+	clusterConfig.ImageRepository = k.ImageRepository
+	clusterConfig.Etcd.Local = &local
+	return utils.MarshalYamlConfigs(&initConfig, &clusterConfig)
+}
+
+// InitEtcdCluster start all the members of external etcd cluster, it does nothing if etcd is stacked on masters.
+func (k *KubeadmRuntime) InitEtcdCluster() error {
+	etcds := k.GetEtcdIPList()
+	if len(etcds) == 0 {
+		return nil
+	}
+	logger.Info("start to init external etcd cluster %s", etcds)
+	k.setKubeadmAPIVersion()
+	if err := k.sendRegistryCert(etcds); err != nil {
+		return err
+	}
+	names := make([]string, len(etcds))
+	for i, etcd := range etcds {
+		name, err := k.getRemoteHostName(etcd)
+		if err != nil {
+			return err
+		}
+		names[i] = name
+	}
+	initialCluster := getEtcdInitialCluster(etcds, names)
+
+	eg, _ := errgroup.WithContext(context.Background())
+	for i, etcd := range etcds {
+		etcd, name := etcd, names[i]
+		eg.Go(func() error {
+			return k.initEtcdMember(etcd, name, initialCluster, EtcdInitialClusterStateNew)
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return k.waitEtcdHealthy(etcds[0])
+}
+
+func (k *KubeadmRuntime) initEtcdMember(etcd, name, initialCluster, state string) error {
+	certPath := k.getEtcdMemberCertPath(etcd)
+	if err := cert.GenerateEtcdCert(k.getEtcdCertPath(), certPath, etcd, name); err != nil {
+		return fmt.Errorf("failed to generate etcd certs of %s: %v", etcd, err)
+	}
+	if err := k.sendFileToHosts([]string{etcd}, certPath, EtcdPKIDir); err != nil {
+		return err
+	}
+	config, err := k.etcdConfig(etcd, name, initialCluster, state)
+	if err != nil {
+		return err
+	}
+	cGroupDriver, err := k.getCgroupDriverFromShell(etcd)
+	if err != nil {
+		return err
+	}
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return err
+	}
+	var runtimeArgs string
+	if criSocket == DefaultContainerdCRISocket {
+		runtimeArgs = fmt.Sprintf(" --container-runtime=remote --container-runtime-endpoint=unix://%s", DefaultContainerdCRISocket)
+	}
+
+	addRegistryHostsAndLogin, err := k.getRegistryHostsAndLoginCmd()
+	if err != nil {
+		return err
+	}
+	ssh, err := k.getHostSSHClient(etcd)
+	if err != nil {
+		return fmt.Errorf("failed to init etcd %s: %v", etcd, err)
+	}
+	if err := ssh.CmdAsync(etcd, addRegistryHostsAndLogin,
+		fmt.Sprintf(RemoteWriteEtcdConfig, string(config), k.getRootfs()),
+		fmt.Sprintf(RemoteWriteEtcdKubeletConf, cGroupDriver, k.getRootfs(), runtimeArgs),
+		fmt.Sprintf(RemoteInitEtcd, k.getRootfs())); err != nil {
+		return fmt.Errorf("failed to init etcd %s: %v", etcd, err)
+	}
+	logger.Info("Succeeded in starting etcd on %s", etcd)
+	return nil
+}
+
+// EtcdSnapshot is a snapshot file in the etcd snapshot dir.
+type EtcdSnapshot struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// controlPlanePods are stopped while etcd is restored, so that nothing is written to the restored etcd.
+var controlPlanePods = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler"}
+
+// getEtcdClientConfig return the client config connecting to all the etcd members, the certs are fetched from host.
+func (k *KubeadmRuntime) getEtcdClientConfig(host string) (clientv3.Config, error) {
+	ssh, err := k.getHostSSHClient(host)
+	if err != nil {
+		return clientv3.Config{}, err
+	}
+	dir := filepath.Join(k.getBasePath(), "etcd-client")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return clientv3.Config{}, err
+	}
+	if err := etcd.FetchCerts(ssh, host, dir); err != nil {
+		return clientv3.Config{}, err
+	}
+	var endpoints []string
+	for _, h := range k.getEtcdHosts() {
+		endpoints = append(endpoints, etcd.GetEndpoint(utils.GetHostIP(h)))
+	}
+	// the snapshot is saved from the first endpoint, which is the member on host.
+	return etcd.NewConfig(endpoints, dir)
+}
+
+// snapshotEtcd save a snapshot named with prefix and time from the first etcd host, which is master0 if etcd is stacked.
+// The snapshot is saved locally and sent to the snapshot dir of the host.
+func (k *KubeadmRuntime) snapshotEtcd(prefix string) (host, path string, err error) {
+	host = k.getEtcdHosts()[0]
+	cfg, err := k.getEtcdClientConfig(host)
+	if err != nil {
+		return "", "", err
+	}
+	path = filepath.Join(k.getEtcdSnapshotDir(), fmt.Sprintf("%s-%s.db", prefix, time.Now().Format("20060102150405")))
+	if err := utils.MkFileFullPathDir(path); err != nil {
+		return "", "", err
+	}
+	if err := etcd.Snapshot(cfg, path); err != nil {
+		return "", "", err
+	}
+	if err := k.sendFileToHosts([]string{host}, path, path); err != nil {
+		return "", "", err
+	}
+	return host, path, nil
+}
+
+func (k *KubeadmRuntime) listEtcdSnapshots() ([]EtcdSnapshot, error) {
+	host := k.getEtcdHosts()[0]
+	out, err := k.CmdToString(host, fmt.Sprintf(RemoteListEtcdSnapshots, k.getEtcdSnapshotDir()), "\n")
+	if err != nil {
+		return nil, err
+	}
+	return parseEtcdSnapshots(out)
+}
+
+// parseEtcdSnapshots parse the "name|size|mtime" lines of stat output.
+func parseEtcdSnapshots(out string) ([]EtcdSnapshot, error) {
+	var snapshots []EtcdSnapshot
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid etcd snapshot stat: %s", line)
+		}
+		snapshot, err := parseEtcdSnapshot(fields[0], fields[1], fields[2])
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func parseEtcdSnapshot(path, size, mtime string) (EtcdSnapshot, error) {
+	s, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return EtcdSnapshot{}, fmt.Errorf("invalid size of etcd snapshot %s: %v", path, err)
+	}
+	t, err := strconv.ParseInt(mtime, 10, 64)
+	if err != nil {
+		return EtcdSnapshot{}, fmt.Errorf("invalid modify time of etcd snapshot %s: %v", path, err)
+	}
+	return EtcdSnapshot{Name: filepath.Base(path), Size: s, ModTime: time.Unix(t, 0)}, nil
+}
+
+func (k *KubeadmRuntime) etcdStatus() ([]etcd.MemberStatus, error) {
+	cfg, err := k.getEtcdClientConfig(k.getEtcdHosts()[0])
+	if err != nil {
+		return nil, err
+	}
+	return etcd.Status(cfg)
+}
+
+// restoreEtcdFromSnapshot restore etcd from snapshot, which is a name in the snapshot dir or the scheduled backup dir
+// of the first etcd host, or a local file sent to the dir first.
+func (k *KubeadmRuntime) restoreEtcdFromSnapshot(snapshot string) error {
+	hosts := k.getEtcdHosts()
+	dir := k.getEtcdSnapshotDir()
+	if strings.HasPrefix(filepath.Base(snapshot), etcdBackupPrefix+"-") {
+		dir = k.getEtcdBackupDir()
+	}
+	path := filepath.Join(dir, filepath.Base(snapshot))
+	if filepath.IsAbs(snapshot) {
+		if !utils.IsFileExist(snapshot) {
+			return fmt.Errorf("etcd snapshot %s is not found", snapshot)
+		}
+		if err := k.sendFileToHosts(hosts[:1], snapshot, path); err != nil {
+			return err
+		}
+	}
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return err
+	}
+	if err := k.CmdAsyncHosts(hosts, fmt.Sprintf(RemoteBackupEtcdctl, k.getEtcdSnapshotDir(), criSocket, k.getRootfs())); err != nil {
+		return fmt.Errorf("failed to back up etcdctl: %v", err)
+	}
+	return k.restoreEtcd(path, k.getEtcdSnapshotDir())
+}
+
+// restoreEtcd restore all the etcd members from the snapshot on the first etcd host, using the etcdctl saved in etcdctlDir.
+// The control plane and all the members are stopped before restoring, and started after all the members are restored.
+func (k *KubeadmRuntime) restoreEtcd(snapshot, etcdctlDir string) error {
+	hosts := k.getEtcdHosts()
+	ssh, err := k.getHostSSHClient(hosts[0])
+	if err != nil {
+		return err
+	}
+	exist, err := ssh.IsFileExist(hosts[0], snapshot)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return fmt.Errorf("etcd snapshot %s is not found on %s", snapshot, hosts[0])
+	}
+	logger.Info("Start to restore etcd from snapshot %s on %s", snapshot, hosts[0])
+	// the local path differs from the remote one, sealer may run on hosts[0].
+	local := filepath.Join(k.getBasePath(), "etcd-restore", filepath.Base(snapshot))
+	if err := ssh.Fetch(hosts[0], local, snapshot); err != nil {
+		return fmt.Errorf("failed to fetch etcd snapshot: %v", err)
+	}
+	if len(hosts) > 1 {
+		if err := k.sendFileToHosts(hosts[1:], local, snapshot); err != nil {
+			return err
+		}
+	}
+
+	var names []string
+	for _, host := range hosts {
+		name, err := k.getRemoteHostName(host)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	initialCluster := getEtcdInitialCluster(hosts, names)
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return err
+	}
+	stoppedDir := filepath.Join(k.getBasePath(), "etcd-restore")
+	masters := k.GetMasterIPList()
+	for _, pod := range controlPlanePods {
+		if err := k.CmdAsyncHosts(masters, fmt.Sprintf(RemoteStopStaticPod, pod, stoppedDir, criSocket, int(staticPodStopTimeout.Seconds()))); err != nil {
+			return fmt.Errorf("failed to stop %s: %v", pod, err)
+		}
+	}
+	if err := k.CmdAsyncHosts(hosts, fmt.Sprintf(RemoteStopStaticPod, "etcd", stoppedDir, criSocket, int(staticPodStopTimeout.Seconds()))); err != nil {
+		return fmt.Errorf("failed to stop etcd: %v", err)
+	}
+	for i, host := range hosts {
+		ssh, err := k.getHostSSHClient(host)
+		if err != nil {
+			return err
+		}
+		cmd := fmt.Sprintf(RemoteRestoreEtcd, etcdctlDir, snapshot, names[i], initialCluster, getEtcdPeerURL(host), k.getEtcdDataDir())
+		if err := ssh.CmdAsync(host, cmd); err != nil {
+			return fmt.Errorf("failed to restore etcd on %s: %v", host, err)
+		}
+	}
+	if err := k.CmdAsyncHosts(hosts, fmt.Sprintf(RemoteStartStaticPod, "etcd", stoppedDir)); err != nil {
+		return fmt.Errorf("failed to start etcd: %v", err)
+	}
+	if err := k.waitEtcdHealthy(hosts[0]); err != nil {
+		return err
+	}
+	for _, pod := range controlPlanePods {
+		if err := k.CmdAsyncHosts(masters, fmt.Sprintf(RemoteStartStaticPod, pod, stoppedDir)); err != nil {
+			return fmt.Errorf("failed to start %s: %v", pod, err)
+		}
+	}
+	return k.waitControlPlaneReady(masters)
+}
+
+// waitControlPlaneReady wait for the control plane pods of the masters are all Ready.
+func (k *KubeadmRuntime) waitControlPlaneReady(masters []string) error {
+	client, err := k8s.Newk8sClient()
+	if err != nil {
+		return err
+	}
+	for _, master := range masters {
+		err := wait.PollImmediate(5*time.Second, renewWaitTimeout, func() (bool, error) {
+			// apiserver may be not ready yet.
+			name, err := getNodeName(client, master)
+			if err != nil {
+				logger.Debug("failed to get node name of %s: %v", master, err)
+				return false, nil
+			}
+			return isControlPlaneReady(client, name), nil
+		})
+		if err != nil {
+			return fmt.Errorf("control plane of %s is not Ready in %s: %v", master, renewWaitTimeout, err)
+		}
+	}
+	return nil
+}
+
+// waitEtcdHealthy wait for all the members are healthy, the etcd image may take a while to be pulled.
+func (k *KubeadmRuntime) waitEtcdHealthy(etcd string) error {
+	return utils.Retry(30, 10*time.Second, func() error {
+		_, err := k.runEtcdctl(etcd, EtcdctlHealth)
+		return err
+	})
+}
+
+// joinEtcds add members to the external etcd cluster one by one, then point the apiservers to all the members.
+func (k *KubeadmRuntime) joinEtcds(etcds []string) error {
+	if len(etcds) == 0 {
+		return nil
+	}
+	members := k.GetEtcdIPList()
+	for _, etcd := range etcds {
+		members = SliceRemoveStr(members, etcd)
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("no existing etcd member to join %s, etcd stacked on masters can not be changed to external", etcds)
+	}
+	if err := k.MergeKubeadmConfig(); err != nil {
+		return err
+	}
+	k.setKubeadmAPIVersion()
+	if err := k.WaitSSHReady(6, etcds...); err != nil {
+		return errors.Wrap(err, "join etcd wait for ssh ready time out")
+	}
+	if err := k.sendRegistryCert(etcds); err != nil {
+		return err
+	}
+	for i, etcd := range etcds {
+		if err := k.joinEtcd(members[0], etcd); err != nil {
+			// the quorum changes with every member, so the rest are not tried.
+			return &HostsError{Succeeded: etcds[:i], Failed: map[string]error{etcd: err}}
+		}
+	}
+	if err := k.updateEtcdServers(k.GetEtcdIPList()); err != nil {
+		return err
+	}
+	return k.scheduleEtcdBackupOn(etcds)
+}
+
+func (k *KubeadmRuntime) joinEtcd(member, etcd string) error {
+	logger.Info("Start to join %s as etcd member", etcd)
+	name, err := k.getRemoteHostName(etcd)
+	if err != nil {
+		return err
+	}
+	output, err := k.runEtcdctl(member, fmt.Sprintf(EtcdctlMemberAdd, name, getEtcdPeerURL(etcd)))
+	if err != nil {
+		return fmt.Errorf("failed to add etcd member %s: %v", etcd, err)
+	}
+	m := etcdInitialClusterRegex.FindStringSubmatch(output)
+	if m == nil {
+		return fmt.Errorf("failed to get initial cluster from the output of member add: %s", output)
+	}
+	if err := k.initEtcdMember(etcd, name, m[1], EtcdInitialClusterStateJoin); err != nil {
+		return err
+	}
+	return k.waitEtcdHealthy(etcd)
+}
+
+// deleteEtcds remove the members from the external etcd cluster and clean the hosts,
+// the apiservers stop using the members before they are removed.
+func (k *KubeadmRuntime) deleteEtcds(etcds []string) error {
+	if len(etcds) == 0 {
+		return nil
+	}
+	members := k.GetEtcdIPList()
+	for _, etcd := range etcds {
+		members = SliceRemoveStr(members, etcd)
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("can not delete all the members of external etcd cluster")
+	}
+	if err := k.MergeKubeadmConfig(); err != nil {
+		return err
+	}
+	if err := k.updateEtcdServers(members); err != nil {
+		return err
+	}
+	for _, etcd := range etcds {
+		logger.Info("Start to delete etcd member %s", etcd)
+		if err := k.deleteEtcd(members[0], etcd); err != nil {
+			return fmt.Errorf("failed to delete etcd member %s: %v", etcd, err)
+		}
+		logger.Info("Succeeded in deleting etcd member %s", etcd)
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) deleteEtcd(member, etcd string) error {
+	memberList, err := k.runEtcdctl(member, EtcdctlMemberList)
+	if err != nil {
+		return err
+	}
+	if id := getEtcdMemberID(memberList, getEtcdPeerURL(etcd)); id == "" {
+		logger.Warn("etcd member %s is not found, skip removing it", etcd)
+	} else if _, err := k.runEtcdctl(member, fmt.Sprintf(EtcdctlMemberDel, id)); err != nil {
+		return err
+	}
+	return k.resetNode(etcd)
+}
+
+// updateEtcdServers point the apiservers and the kubeadm-config used by joining masters to the etcd members.
+func (k *KubeadmRuntime) updateEtcdServers(etcds []string) error {
+	endpoints := getEtcdEndpointsWithHTTPSPrefix(etcds)
+	if err := k.CmdAsyncHosts(k.GetMasterIPList(), fmt.Sprintf(RemoteUpdateAPIServerEtcdServers, endpoints)); err != nil {
+		return fmt.Errorf("failed to update etcd servers of apiserver: %v", err)
+	}
+	ssh, err := k.getHostSSHClient(k.GetMaster0IP())
+	if err != nil {
+		return err
+	}
+	return ssh.CmdAsync(k.GetMaster0IP(), fmt.Sprintf(RemoteUpdateKubeadmConfigEtcdServers, endpoints))
+}
@@ -0,0 +1,217 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/env"
+)
+
+// the cluster env keys of the scheduled etcd backup, it is disabled if EtcdBackupInterval is empty.
+const (
+	EtcdBackupInterval = "EtcdBackupInterval"
+	EtcdBackupRetain   = "EtcdBackupRetain"
+	EtcdBackupDir      = "EtcdBackupDir"
+
+	defaultEtcdBackupRetain = 7
+	etcdBackupPrefix        = "scheduled"
+)
+
+// Every etcd host saves the snapshot of its own member by a systemd timer with the etcdctl saved from etcd container,
+// so that the backups survive the loss of any host. The snapshots more than retain are removed, the oldest first.
+const (
+	RemoteInstallEtcdBackup = `mkdir -p %[1]s && cat > %[1]s/etcd-backup.sh <<'EOF'
+%[2]s
+EOF
+chmod +x %[1]s/etcd-backup.sh && cat > /etc/systemd/system/sealer-etcd-backup.service <<'EOF'
+[Unit]
+Description=etcd backup by sealer
+
+[Service]
+Type=oneshot
+ExecStart=%[1]s/etcd-backup.sh
+EOF
+cat > /etc/systemd/system/sealer-etcd-backup.timer <<'EOF'
+[Unit]
+Description=Scheduled etcd backup by sealer
+
+[Timer]
+OnActiveSec=%[3]s
+OnUnitActiveSec=%[3]s
+
+[Install]
+WantedBy=timers.target
+EOF
+systemctl daemon-reload && systemctl enable sealer-etcd-backup.timer && systemctl restart sealer-etcd-backup.timer`
+	RemoteRemoveEtcdBackup = `if [ -f /etc/systemd/system/sealer-etcd-backup.timer ]; then systemctl disable --now sealer-etcd-backup.timer; fi && rm -f /etc/systemd/system/sealer-etcd-backup.timer /etc/systemd/system/sealer-etcd-backup.service && systemctl daemon-reload`
+	RemoteListEtcdBackups  = `for f in $(ls -t %[1]s/*.db 2>/dev/null); do echo "$f|$(stat -c '%%s|%%Y' $f)|$(ETCDCTL_API=3 %[2]s/etcdctl snapshot status $f -w json 2>/dev/null)"; done`
+)
+
+// EtcdBackupPolicy is the schedule and retention of the etcd backups.
+type EtcdBackupPolicy struct {
+	Interval time.Duration
+	Retain   int
+	Dir      string
+}
+
+// EtcdBackup is a scheduled backup on an etcd host.
+type EtcdBackup struct {
+	Host string
+	EtcdSnapshot
+	// Revision is 0 if the snapshot status is unknown.
+	Revision int64
+}
+
+// getEtcdBackupPolicy return the policy in cluster env, it is nil if the scheduled backup is disabled.
+func (k *KubeadmRuntime) getEtcdBackupPolicy() (*EtcdBackupPolicy, error) {
+	getEnv, err := env.StringGetter(k.Spec.Env)
+	if err != nil {
+		return nil, err
+	}
+	interval := getEnv(EtcdBackupInterval)
+	if interval == "" {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in cluster env: %v", EtcdBackupInterval, err)
+	}
+	if d < time.Minute {
+		return nil, fmt.Errorf("%s %s in cluster env is less than 1m", EtcdBackupInterval, interval)
+	}
+	policy := &EtcdBackupPolicy{Interval: d, Retain: defaultEtcdBackupRetain, Dir: k.getEtcdBackupDir()}
+	if retain := getEnv(EtcdBackupRetain); retain != "" {
+		if policy.Retain, err = strconv.Atoi(retain); err != nil || policy.Retain < 1 {
+			return nil, fmt.Errorf("invalid %s %s in cluster env, it should be a positive integer", EtcdBackupRetain, retain)
+		}
+	}
+	return policy, nil
+}
+
+// getEtcdBackupDir return the EtcdBackupDir in cluster env, /var/lib/sealer/data/my-cluster/etcd-backups by default.
+func (k *KubeadmRuntime) getEtcdBackupDir() string {
+	// the error of the env failed to resolve is returned by getEtcdBackupPolicy.
+	getEnv, _ := env.StringGetter(k.Spec.Env)
+	if dir := getEnv(EtcdBackupDir); dir != "" {
+		return dir
+	}
+	return filepath.Join(k.getBasePath(), "etcd-backups")
+}
+
+// renderEtcdBackupScript return the script saving a snapshot of the local member to the dir of policy.
+func renderEtcdBackupScript(policy *EtcdBackupPolicy, etcdctlDir string) string {
+	return fmt.Sprintf(`#!/bin/sh
+set -e
+ETCDCTL_API=3 %[1]s/etcdctl --endpoints=https://localhost:2379 --cacert=/etc/kubernetes/pki/etcd/ca.crt --cert=/etc/kubernetes/pki/etcd/healthcheck-client.crt --key=/etc/kubernetes/pki/etcd/healthcheck-client.key snapshot save %[2]s/%[3]s-$(date +%%Y%%m%%d%%H%%M%%S).db
+ls -t %[2]s/%[3]s-*.db | tail -n +%[4]d | xargs -r rm -f`, etcdctlDir, policy.Dir, etcdBackupPrefix, policy.Retain+1)
+}
+
+// scheduleEtcdBackup install, update or remove the scheduled backup on all the etcd hosts by the policy in cluster env.
+func (k *KubeadmRuntime) scheduleEtcdBackup() error {
+	policy, err := k.getEtcdBackupPolicy()
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		logger.Info("%s is not set in cluster env, remove the scheduled etcd backup", EtcdBackupInterval)
+		return k.CmdAsyncHosts(k.getEtcdHosts(), RemoteRemoveEtcdBackup)
+	}
+	return k.installEtcdBackup(k.getEtcdHosts(), policy)
+}
+
+// scheduleEtcdBackupOn install the scheduled backup on the new etcd members if it is enabled in cluster env.
+func (k *KubeadmRuntime) scheduleEtcdBackupOn(hosts []string) error {
+	policy, err := k.getEtcdBackupPolicy()
+	if err != nil || policy == nil || len(hosts) == 0 {
+		return err
+	}
+	return k.installEtcdBackup(hosts, policy)
+}
+
+func (k *KubeadmRuntime) installEtcdBackup(hosts []string, policy *EtcdBackupPolicy) error {
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return err
+	}
+	if err := k.CmdAsyncHosts(hosts, fmt.Sprintf(RemoteBackupEtcdctl, k.getEtcdSnapshotDir(), criSocket, k.getRootfs())); err != nil {
+		return fmt.Errorf("failed to back up etcdctl: %v", err)
+	}
+	script := renderEtcdBackupScript(policy, k.getEtcdSnapshotDir())
+	interval := fmt.Sprintf("%ds", int64(policy.Interval/time.Second))
+	if err := k.CmdAsyncHosts(hosts, fmt.Sprintf(RemoteInstallEtcdBackup, policy.Dir, script, interval)); err != nil {
+		return fmt.Errorf("failed to install the scheduled etcd backup: %v", err)
+	}
+	logger.Info("etcd is backed up to %s every %s on %s, %d backups are retained", policy.Dir, policy.Interval, hosts, policy.Retain)
+	return nil
+}
+
+// initEtcdBackup schedule the backup on the etcd members running after init, which is master0 if etcd is stacked.
+func (k *KubeadmRuntime) initEtcdBackup() error {
+	if len(k.GetEtcdIPList()) != 0 {
+		return k.scheduleEtcdBackupOn(k.GetEtcdIPList())
+	}
+	return k.scheduleEtcdBackupOn([]string{k.GetMaster0IP()})
+}
+
+func (k *KubeadmRuntime) listEtcdBackups() ([]EtcdBackup, error) {
+	var backups []EtcdBackup
+	for _, host := range k.getEtcdHosts() {
+		out, err := k.CmdToString(host, fmt.Sprintf(RemoteListEtcdBackups, k.getEtcdBackupDir(), k.getEtcdSnapshotDir()), "\n")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list etcd backups on %s: %v", host, err)
+		}
+		b, err := parseEtcdBackups(host, out)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, b...)
+	}
+	return backups, nil
+}
+
+// parseEtcdBackups parse the "name|size|mtime|status" lines, status is the json output of etcdctl snapshot status.
+func parseEtcdBackups(host, out string) ([]EtcdBackup, error) {
+	var backups []EtcdBackup
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "|", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid etcd backup stat: %s", line)
+		}
+		snapshot, err := parseEtcdSnapshot(fields[0], fields[1], fields[2])
+		if err != nil {
+			return nil, err
+		}
+		backup := EtcdBackup{Host: host, EtcdSnapshot: snapshot}
+		var status struct {
+			Revision int64 `json:"revision"`
+		}
+		if err := json.Unmarshal([]byte(fields[3]), &status); err == nil {
+			backup.Revision = status.Revision
+		}
+		backups = append(backups, backup)
+	}
+	return backups, nil
+}
@@ -0,0 +1,85 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+func TestParseEtcdBackups(t *testing.T) {
+	out := `/data/etcd-backups/scheduled-20211202120000.db|2048|1638446400|{"hash":1234,"revision":5678,"totalKey":100,"totalSize":2048}
+/data/etcd-backups/scheduled-20211201120000.db|1024|1638360000|
+`
+	want := []EtcdBackup{
+		{Host: "192.168.0.10", EtcdSnapshot: EtcdSnapshot{Name: "scheduled-20211202120000.db", Size: 2048, ModTime: time.Unix(1638446400, 0)}, Revision: 5678},
+		{Host: "192.168.0.10", EtcdSnapshot: EtcdSnapshot{Name: "scheduled-20211201120000.db", Size: 1024, ModTime: time.Unix(1638360000, 0)}},
+	}
+	got, err := parseEtcdBackups("192.168.0.10", out)
+	if err != nil {
+		t.Fatalf("parseEtcdBackups() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEtcdBackups() = %v, want %v", got, want)
+	}
+	if _, err := parseEtcdBackups("192.168.0.10", "/data/etcd-backups/scheduled-20211201120000.db|1024"); err == nil {
+		t.Errorf("parseEtcdBackups() of invalid line should return error")
+	}
+}
+
+func TestRenderEtcdBackupScript(t *testing.T) {
+	script := renderEtcdBackupScript(&EtcdBackupPolicy{Interval: 6 * time.Hour, Retain: 7, Dir: "/data/etcd-backups"}, "/var/lib/sealer/data/my-cluster/etcd-snapshots")
+	for _, want := range []string{
+		"/var/lib/sealer/data/my-cluster/etcd-snapshots/etcdctl --endpoints=https://localhost:2379",
+		"snapshot save /data/etcd-backups/scheduled-$(date +%Y%m%d%H%M%S).db",
+		"ls -t /data/etcd-backups/scheduled-*.db | tail -n +8 | xargs -r rm -f",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("renderEtcdBackupScript() = %s, want it contains %s", script, want)
+		}
+	}
+}
+
+func TestGetEtcdBackupPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     []string
+		want    *EtcdBackupPolicy
+		wantErr bool
+	}{
+		{"disabled", nil, nil, false},
+		{"interval", []string{"EtcdBackupInterval=6h", "EtcdBackupRetain=3", "EtcdBackupDir=/data/etcd-backups"}, &EtcdBackupPolicy{Interval: 6 * time.Hour, Retain: 3, Dir: "/data/etcd-backups"}, false},
+		{"days are not a duration", []string{"EtcdBackupInterval=1d"}, nil, true},
+		{"less than 1m", []string{"EtcdBackupInterval=30s"}, nil, true},
+		{"invalid retain", []string{"EtcdBackupInterval=6h", "EtcdBackupRetain=0"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &KubeadmRuntime{Cluster: &v2.Cluster{}}
+			k.Spec.Env = tt.env
+			got, err := k.getEtcdBackupPolicy()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getEtcdBackupPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getEtcdBackupPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetEtcdInitialCluster(t *testing.T) {
+	got := getEtcdInitialCluster([]string{"192.168.0.10", "192.168.0.11"}, []string{"etcd-0", "etcd-1"})
+	want := "etcd-0=https://192.168.0.10:2380,etcd-1=https://192.168.0.11:2380"
+	if got != want {
+		t.Errorf("getEtcdInitialCluster() = %s, want %s", got, want)
+	}
+}
+
+func TestGetEtcdMemberID(t *testing.T) {
+	memberList := `8e9e05c52164694d, started, etcd-0, https://192.168.0.10:2380, https://192.168.0.10:2379, false
+91bc3c398fb3c146, started, etcd-1, https://192.168.0.11:2380, https://192.168.0.11:2379, false
+`
+	tests := []struct {
+		name    string
+		peerURL string
+		want    string
+	}{
+		{"found", "https://192.168.0.11:2380", "91bc3c398fb3c146"},
+		{"not found", "https://192.168.0.12:2380", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getEtcdMemberID(memberList, tt.peerURL); got != tt.want {
+				t.Errorf("getEtcdMemberID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEtcdSnapshots(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    []EtcdSnapshot
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{
+			"snapshots",
+			"/var/lib/sealer/data/my-cluster/etcd-snapshots/pre-upgrade-20211201120000.db|1024|1638360000\n" +
+				"/var/lib/sealer/data/my-cluster/etcd-snapshots/snapshot-20211202120000.db|2048|1638446400\n",
+			[]EtcdSnapshot{
+				{Name: "pre-upgrade-20211201120000.db", Size: 1024, ModTime: time.Unix(1638360000, 0)},
+				{Name: "snapshot-20211202120000.db", Size: 2048, ModTime: time.Unix(1638446400, 0)},
+			},
+			false,
+		},
+		{"invalid size", "/tmp/a.db|x|1638360000", nil, true},
+		{"invalid line", "/tmp/a.db", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEtcdSnapshots(tt.out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEtcdSnapshots() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEtcdSnapshots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
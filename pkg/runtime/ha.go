@@ -0,0 +1,151 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/env"
+	"github.com/alibaba/sealer/pkg/ipvs"
+	"github.com/alibaba/sealer/utils"
+)
+
+// the cluster env keys of the control plane HA mode.
+const (
+	HAMode      = "HAMode"
+	HAVIP       = "HAVIP"
+	HAInterface = "HAInterface"
+	HAImage     = "HAImage"
+)
+
+const (
+	// HAModeLvscare is the default mode, each worker balances the masters by the ipvs rules kept by lvscare.
+	HAModeLvscare = "lvscare"
+	// HAModeKubeVIP floats a vip among the masters by the kube-vip static pods with ARP.
+	HAModeKubeVIP = "kube-vip"
+	// HAModeExternal uses the load balancer in front of the masters provided by users.
+	HAModeExternal = "external"
+
+	KubeVIPStaticPodFileName     = "/etc/kubernetes/manifests/kube-vip.yaml"
+	RemoteCreateKubeVIPStaticPod = "mkdir -p /etc/kubernetes/manifests && echo '%s' > " + KubeVIPStaticPodFileName
+
+	// KubeadmDefaultImageRepository is used by kubeadm if imageRepository is not set.
+	KubeadmDefaultImageRepository = "k8s.gcr.io"
+
+	// RemoteCheckIPVSCare print true if the seautil of host has the ipvs care daemon serving the status.
+	RemoteCheckIPVSCare = "if %s ipvs --help 2>/dev/null | grep -q -- --status-addr; then echo true; fi"
+)
+
+// HAConfig is how the workers reach the apiservers of masters.
+type HAConfig struct {
+	Mode string
+	// VIP is the address of apiservers for workers, the virtual server of lvscare, the vip of kube-vip
+	// or the address of the external load balancer.
+	VIP string
+	// Interface is the interface kube-vip binds the vip on.
+	Interface string
+	// Image is the kube-vip image.
+	Image string
+}
+
+// getHAConfig return the HA config from the cluster env, HAVIP is required unless the mode is lvscare,
+// and the vip of lvscare should be of the same ip family as masters.
+func getHAConfig(getEnv func(key string) string, master0IP string) (*HAConfig, error) {
+	ha := &HAConfig{Mode: getEnv(HAMode), VIP: getEnv(HAVIP), Interface: getEnv(HAInterface), Image: getEnv(HAImage)}
+	switch ha.Mode {
+	case "", HAModeLvscare:
+		ha.Mode = HAModeLvscare
+		if ha.VIP == "" {
+			ha.VIP = DefaultVIP
+			if utils.IsIPv6(master0IP) {
+				ha.VIP = DefaultVIPv6
+			}
+		}
+		if utils.IsIPv6(ha.VIP) != utils.IsIPv6(master0IP) {
+			return nil, fmt.Errorf("%s %s and master %s are not of the same ip family", HAVIP, ha.VIP, master0IP)
+		}
+	case HAModeKubeVIP, HAModeExternal:
+		if ha.VIP == "" {
+			return nil, fmt.Errorf("%s is required in %s mode", HAVIP, ha.Mode)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported %s %s, it should be %s, %s or %s", HAMode, ha.Mode, HAModeLvscare, HAModeKubeVIP, HAModeExternal)
+	}
+	if net.ParseIP(ha.VIP) == nil {
+		return nil, fmt.Errorf("%s %s is not an ip address", HAVIP, ha.VIP)
+	}
+	return ha, nil
+}
+
+func (k *KubeadmRuntime) setHAConfig() error {
+	getEnv, err := env.StringGetter(k.Spec.Env)
+	if err != nil {
+		return err
+	}
+	ha, err := getHAConfig(getEnv, k.GetMaster0IP())
+	if err != nil {
+		return fmt.Errorf("invalid HA config in cluster env: %v", err)
+	}
+	k.HA = ha
+	k.VIP = ha.VIP
+	return nil
+}
+
+// isLvscareMode return whether the ipvs rules, lvscare and the vip route are set on workers.
+func (k *KubeadmRuntime) isLvscareMode() bool {
+	return k.HA == nil || k.HA.Mode == HAModeLvscare
+}
+
+// getLvscareImage return the kube-proxy image of the cluster to run the seautil of host in lvscare static pod,
+// it is on every worker, so no extra image is needed. The kubeadm config should be merged first.
+func (k *KubeadmRuntime) getLvscareImage() (string, error) {
+	if k.getKubeVersion() == "" {
+		return "", fmt.Errorf("failed to get the lvscare image: the kubernetes version is unknown")
+	}
+	repository := k.ImageRepository
+	if repository == "" {
+		repository = KubeadmDefaultImageRepository
+	}
+	return fmt.Sprintf("%s/kube-proxy:%s", repository, k.getKubeVersion()), nil
+}
+
+// getLvscareStaticPodYaml return the lvscare static pod yaml of host balancing masters, it runs the seautil of host if
+// the seautil has the ipvs care daemon, or the lvscare image like the older ClusterImages.
+func (k *KubeadmRuntime) getLvscareStaticPodYaml(host string, masters []string) (string, error) {
+	out, err := k.CmdToString(host, fmt.Sprintf(RemoteCheckIPVSCare, ipvs.LvsCareCommand), "")
+	if err != nil {
+		return "", fmt.Errorf("failed to check the seautil of %s: %v", host, err)
+	}
+	if strings.TrimSpace(out) != "true" {
+		logger.Warn("the seautil of %s has no ipvs care daemon, run lvscare by image %s", host, ipvs.DefaultLvsCareImage)
+		return ipvs.LegacyLvsStaticPodYaml(k.getVIP(), masters), nil
+	}
+	image, err := k.getLvscareImage()
+	if err != nil {
+		return "", err
+	}
+	return ipvs.LvsStaticPodYaml(k.getVIP(), masters, image), nil
+}
+
+// getHAMasterCmds return the commands run on a master after it is initialized or joined.
+func (k *KubeadmRuntime) getHAMasterCmds() []string {
+	if k.HA == nil || k.HA.Mode != HAModeKubeVIP {
+		return nil
+	}
+	return []string{fmt.Sprintf(RemoteCreateKubeVIPStaticPod, ipvs.KubeVIPStaticPodYaml(k.HA.VIP, k.HA.Interface, k.HA.Image))}
+}
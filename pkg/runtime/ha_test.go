@@ -0,0 +1,87 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetHAConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		envs    map[string]string
+		want    *HAConfig
+		wantErr bool
+	}{
+		{"default lvscare", map[string]string{}, &HAConfig{Mode: HAModeLvscare, VIP: DefaultVIP}, false},
+		{"lvscare with vip", map[string]string{HAMode: HAModeLvscare, HAVIP: "10.103.97.100"}, &HAConfig{Mode: HAModeLvscare, VIP: "10.103.97.100"}, false},
+		{"lvscare with ipv6 vip", map[string]string{HAVIP: "fd00::100"}, nil, true},
+		{"kube-vip", map[string]string{HAMode: HAModeKubeVIP, HAVIP: "192.168.0.100", HAInterface: "eth0"},
+			&HAConfig{Mode: HAModeKubeVIP, VIP: "192.168.0.100", Interface: "eth0"}, false},
+		{"external", map[string]string{HAMode: HAModeExternal, HAVIP: "192.168.0.200"}, &HAConfig{Mode: HAModeExternal, VIP: "192.168.0.200"}, false},
+		{"kube-vip without vip", map[string]string{HAMode: HAModeKubeVIP}, nil, true},
+		{"external with domain", map[string]string{HAMode: HAModeExternal, HAVIP: "lb.example.com"}, nil, true},
+		{"unsupported mode", map[string]string{HAMode: "haproxy", HAVIP: "192.168.0.200"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getHAConfig(func(key string) string { return tt.envs[key] }, "192.168.0.2")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getHAConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getHAConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetHAConfigIPv6(t *testing.T) {
+	got, err := getHAConfig(func(key string) string { return "" }, "fd00::2")
+	if err != nil {
+		t.Fatalf("getHAConfig() error = %v", err)
+	}
+	if got.VIP != DefaultVIPv6 {
+		t.Errorf("getHAConfig() vip = %s, want %s", got.VIP, DefaultVIPv6)
+	}
+}
+
+func TestGetLvscareImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		repository string
+		version    string
+		want       string
+		wantErr    bool
+	}{
+		{"image repository", "sea.hub:5000", "v1.19.8", "sea.hub:5000/kube-proxy:v1.19.8", false},
+		{"kubeadm default repository", "", "v1.19.8", "k8s.gcr.io/kube-proxy:v1.19.8", false},
+		{"unknown version", "sea.hub:5000", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &KubeadmRuntime{KubeadmConfig: &KubeadmConfig{}}
+			k.ImageRepository, k.KubernetesVersion = tt.repository, tt.version
+			got, err := k.getLvscareImage()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getLvscareImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getLvscareImage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
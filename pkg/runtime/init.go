@@ -23,6 +23,7 @@ import (
 	"github.com/alibaba/sealer/common"
 	"github.com/alibaba/sealer/logger"
 	"github.com/alibaba/sealer/pkg/cert"
+	"github.com/alibaba/sealer/pkg/runtime/kubeadm_types/v1beta2"
 	v2 "github.com/alibaba/sealer/types/api/v2"
 	"github.com/alibaba/sealer/utils"
 	"golang.org/x/sync/errgroup"
@@ -35,6 +36,9 @@ const (
 	RemoteCmdExistNetworkInterface = "ip addr show %s | egrep \"%s\" || true"
 	WriteKubeadmConfigCmd          = `cd %s && echo '%s' > etc/kubeadm.yml`
 	DefaultVIP                     = "10.103.97.2"
+	DefaultVIPv6                   = "fd00:10:103:97::2"
+	DefaultPodSubnetV6             = "fd00:100:64::/48"
+	DefaultServiceSubnetV6         = "fd00:10:96::/108"
 	DefaultAPIserverDomain         = "apiserver.cluster.local"
 	DefaultRegistryPort            = 5000
 	DockerCertDir                  = "/etc/docker/certs.d"
@@ -49,6 +53,9 @@ func (k *KubeadmRuntime) ConfigKubeadmOnMaster0() error {
 	if err := k.KubeadmConfig.Merge(k.getDefaultKubeadmConfig()); err != nil {
 		return err
 	}
+	if err := k.setIPv6Subnets(); err != nil {
+		return err
+	}
 	bs, err := k.generateConfigs()
 	if err != nil {
 		return err
@@ -68,9 +75,19 @@ func (k *KubeadmRuntime) generateConfigs() ([]byte, error) {
 		return nil, err
 	}
 	k.setCgroupDriver(cGroupDriver)
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return nil, err
+	}
+	k.setCRISocket(criSocket)
 	k.setKubeadmAPIVersion()
+	// etcd.local is kept in KubeadmConfig, the etcd hosts are configured with it.
+	clusterConfiguration := k.ClusterConfiguration
+	if len(k.GetEtcdIPList()) != 0 {
+		clusterConfiguration.Etcd = v1beta2.Etcd{External: k.getExternalEtcd()}
+	}
 	return utils.MarshalYamlConfigs(&k.InitConfiguration,
-		&k.ClusterConfiguration,
+		&clusterConfiguration,
 		&k.KubeletConfiguration,
 		&k.KubeProxyConfiguration)
 }
@@ -82,8 +99,46 @@ func (k *KubeadmRuntime) handleKubeadmConfig() {
 	if k.APIServer.ExtraArgs == nil {
 		k.APIServer.ExtraArgs = make(map[string]string)
 	}
-	k.APIServer.ExtraArgs[EtcdServers] = getEtcdEndpointsWithHTTPSPrefix(k.GetMasterIPList())
-	k.IPVS.ExcludeCIDRs = append(k.KubeProxyConfiguration.IPVS.ExcludeCIDRs, fmt.Sprintf("%s/32", k.getVIP()))
+	etcds := k.GetMasterIPList()
+	if len(k.GetEtcdIPList()) != 0 {
+		etcds = k.GetEtcdIPList()
+	}
+	k.APIServer.ExtraArgs[EtcdServers] = getEtcdEndpointsWithHTTPSPrefix(etcds)
+	// dual-stack is enabled by default since v1.21.
+	if utils.IsDualStackCIDRs(k.Networking.PodSubnet) && !VersionCompare(k.getKubeVersion(), V1210) {
+		if k.ClusterConfiguration.FeatureGates == nil {
+			k.ClusterConfiguration.FeatureGates = map[string]bool{}
+		}
+		k.ClusterConfiguration.FeatureGates["IPv6DualStack"] = true
+	}
+	if k.isLvscareMode() {
+		k.IPVS.ExcludeCIDRs = utils.RemoveDuplicate(append(k.KubeProxyConfiguration.IPVS.ExcludeCIDRs, utils.GetHostMaskCIDR(k.getVIP())))
+	}
+}
+
+// setIPv6Subnets use the ipv6 default subnets if master0 is ipv6 and the subnets of ClusterImage have no ipv6 one,
+// the ipv4 defaults could not be used by ipv6 hosts. It returns an error instead if Clusterfile sets an ipv4 only one.
+func (k *KubeadmRuntime) setIPv6Subnets() error {
+	if !utils.IsIPv6(k.GetMaster0IP()) {
+		return nil
+	}
+	var podSubnet, serviceSubnet string
+	if k.Config.ClusterFileKubeConfig != nil {
+		podSubnet, serviceSubnet = k.Config.ClusterFileKubeConfig.Networking.PodSubnet, k.Config.ClusterFileKubeConfig.Networking.ServiceSubnet
+	}
+	for _, subnet := range []struct{ name, value string }{{"podSubnet", podSubnet}, {"serviceSubnet", serviceSubnet}} {
+		if subnet.value != "" && !utils.HasIPv6CIDR(subnet.value) {
+			return fmt.Errorf("networking.%s %s of Clusterfile could not be used by ipv6 master %s, set an ipv6 or dual-stack one",
+				subnet.name, subnet.value, k.GetMaster0IP())
+		}
+	}
+	if !utils.HasIPv6CIDR(k.Networking.PodSubnet) {
+		k.Networking.PodSubnet = DefaultPodSubnetV6
+	}
+	if !utils.HasIPv6CIDR(k.Networking.ServiceSubnet) {
+		k.Networking.ServiceSubnet = DefaultServiceSubnetV6
+	}
+	return nil
 }
 
 //CmdToString is in host exec cmd and replace to spilt str
@@ -144,7 +199,7 @@ func (k *KubeadmRuntime) GenerateRegistryCert() error {
 	if err != nil {
 		return err
 	}
-	err = k.sendRegistryCertAndKey()
+	err = k.sendRegistryCertAndKey(k.GetMasterIPList()[:1])
 	if err != nil {
 		return err
 	}
@@ -260,7 +315,7 @@ func (k *KubeadmRuntime) InitMaster0() error {
 		return fmt.Errorf("init master0 failed, error: %s. Please clean and reinstall", err.Error())
 	}
 	k.decodeMaster0Output(output)
-	err = ssh.CmdAsync(k.GetMaster0IP(), RemoteCopyKubeConfig)
+	err = ssh.CmdAsync(k.GetMaster0IP(), append([]string{RemoteCopyKubeConfig}, k.getHAMasterCmds()...)...)
 	if err != nil {
 		return err
 	}
@@ -291,8 +346,10 @@ func (k *KubeadmRuntime) init(cluster *v2.Cluster) error {
 		k.CreateKubeConfig,
 		k.CopyStaticFilesTomasters,
 		k.ApplyRegistry,
+		k.InitEtcdCluster,
 		k.InitMaster0,
 		k.GetKubectlAndKubeconfig,
+		k.initEtcdBackup,
 	}
 
 	for _, f := range pipeline {
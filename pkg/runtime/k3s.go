@@ -0,0 +1,651 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+	"sigs.k8s.io/yaml"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/logger"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+	"github.com/alibaba/sealer/utils"
+)
+
+const (
+	K3sServerService  = "k3s"
+	K3sAgentService   = "k3s-agent"
+	K3sBinPath        = "/usr/local/bin/k3s"
+	K3sConfigDir      = "/etc/rancher/k3s"
+	K3sConfigFile     = "/etc/rancher/k3s/config.yaml"
+	K3sRegistriesFile = "/etc/rancher/k3s/registries.yaml"
+	K3sTokenFile      = "/var/lib/rancher/k3s/server/token"
+	K3sServiceFile    = "/etc/systemd/system/%s.service"
+	K3sCRISocket      = "/run/k3s/containerd/containerd.sock"
+	// K3sAgentImagesDir is imported by k3s on start, so the registry image is present without pulling.
+	K3sAgentImagesDir  = "/var/lib/rancher/k3s/agent/images"
+	K3sPodManifestsDir = "/var/lib/rancher/k3s/agent/pod-manifests"
+
+	RemoteK3sInstall        = "cp -f %s/bin/k3s %s && chmod +x %s && ln -sf %s %s"
+	RemoteK3sWriteFile      = "mkdir -p %s && printf '%%s\\n' %s > %s"
+	RemoteK3sStart          = "systemctl daemon-reload && systemctl enable %s && systemctl restart %s"
+	RemoteK3sCopyKubeConfig = `mkdir -p /root/.kube && sed "s#https://127.0.0.1:6443#https://%s:6443#" /etc/rancher/k3s/k3s.yaml > /root/.kube/config`
+	RemoteK3sClean          = `systemctl disable --now k3s k3s-agent 2>/dev/null; \
+pkill -9 -f containerd-shim 2>/dev/null; \
+for m in $(mount | grep -E '/run/k3s|/var/lib/rancher/k3s|/var/lib/kubelet' | awk '{print $3}' | sort -r); do umount $m; done; \
+ip link delete cni0 2>/dev/null; ip link delete flannel.1 2>/dev/null; \
+rm -rf /etc/rancher/k3s /var/lib/rancher/k3s /var/lib/kubelet /run/k3s /root/.kube \
+/etc/systemd/system/k3s.service /etc/systemd/system/k3s-agent.service /usr/local/bin/k3s /usr/bin/kubectl && \
+systemctl daemon-reload`
+)
+
+// k3sService is the systemd unit of k3s server and agent, the arg is the subcommand.
+const k3sService = `[Unit]
+Description=Lightweight Kubernetes
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=notify
+ExecStartPre=-/sbin/modprobe br_netfilter
+ExecStartPre=-/sbin/modprobe overlay
+ExecStart=/usr/local/bin/k3s %s
+KillMode=process
+Delegate=yes
+LimitNOFILE=1048576
+LimitNPROC=infinity
+LimitCORE=infinity
+TasksMax=infinity
+TimeoutStartSec=0
+Restart=always
+RestartSec=5s
+
+[Install]
+WantedBy=multi-user.target`
+
+// k3sRegistryPod is the static pod of registry run by the kubelet of k3s, the args are the port, the domain of cert
+// and the certs, data and etc dir of rootfs. The config and htpasswd of rootfs are used like init-registry.sh does.
+const k3sRegistryPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: sealer-registry
+  namespace: kube-system
+spec:
+  hostNetwork: true
+  priorityClassName: system-node-critical
+  containers:
+  - name: sealer-registry
+    image: docker.io/library/registry:2.7.1
+    imagePullPolicy: IfNotPresent
+    command:
+    - /bin/sh
+    - -c
+    - config=/etc/docker/registry/config.yml;
+      if [ -f /sealer-etc/registry_config.yml ]; then config=/sealer-etc/registry_config.yml; fi;
+      if [ -f /sealer-etc/registry_htpasswd ]; then export REGISTRY_AUTH=htpasswd REGISTRY_AUTH_HTPASSWD_PATH=/sealer-etc/registry_htpasswd REGISTRY_AUTH_HTPASSWD_REALM=sealer; fi;
+      exec registry serve $config
+    env:
+    - name: REGISTRY_HTTP_ADDR
+      value: 0.0.0.0:%s
+    - name: REGISTRY_HTTP_TLS_CERTIFICATE
+      value: /certs/%s.crt
+    - name: REGISTRY_HTTP_TLS_KEY
+      value: /certs/%s.key
+    - name: REGISTRY_STORAGE_DELETE_ENABLED
+      value: "true"
+    volumeMounts:
+    - name: certs
+      mountPath: /certs
+      readOnly: true
+    - name: data
+      mountPath: /var/lib/registry
+    - name: etc
+      mountPath: /sealer-etc
+      readOnly: true
+  volumes:
+  - name: certs
+    hostPath:
+      path: %s
+  - name: data
+    hostPath:
+      path: %s
+  - name: etc
+    hostPath:
+      path: %s`
+
+// K3sRuntime runs the cluster with k3s from bin/k3s of the ClusterImage rootfs. The servers run embedded etcd,
+// and the agents balance the servers by themselves, so there is no VIP and lvscare.
+// The ssh, registry and cert helpers are shared with KubeadmRuntime, whose methods are not promoted, so K3sRuntime
+// implements only the optional interfaces it supports.
+type K3sRuntime struct {
+	*v2.Cluster
+	kubeadm *KubeadmRuntime
+}
+
+// k3sConfig is written to /etc/rancher/k3s/config.yaml, the keys are the flags of k3s.
+type k3sConfig struct {
+	Server              string   `json:"server,omitempty"`
+	Token               string   `json:"token,omitempty"`
+	ClusterInit         bool     `json:"cluster-init,omitempty"`
+	NodeIP              string   `json:"node-ip,omitempty"`
+	TLSSAN              []string `json:"tls-san,omitempty"`
+	ClusterCIDR         string   `json:"cluster-cidr,omitempty"`
+	ServiceCIDR         string   `json:"service-cidr,omitempty"`
+	ClusterDomain       string   `json:"cluster-domain,omitempty"`
+	WriteKubeconfigMode string   `json:"write-kubeconfig-mode,omitempty"`
+}
+
+func newK3sRuntime(cluster *v2.Cluster, clusterFileKubeConfig *KubeadmConfig) (Interface, error) {
+	k, err := newKubeadmRuntime(cluster, clusterFileKubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	kr := k.(*KubeadmRuntime)
+	// the hosts run the containerd embedded in k3s, which is not detected from the ClusterImage.
+	kr.containerRuntimeOnce.Do(func() {
+		kr.containerRuntime = &K3sContainerRuntime{}
+	})
+	return &K3sRuntime{Cluster: kr.Cluster, kubeadm: kr}, nil
+}
+
+// K3sContainerRuntime is the containerd embedded in k3s, so the hosts need no docker or nerdctl. The registry runs as
+// a static pod of k3s, and the registries.yaml of k3s trusts and logs in to it. The images dir of rootfs should have
+// the registry image and the pause image of k3s, which are imported by k3s without a registry.
+type K3sContainerRuntime struct{}
+
+func (k *K3sContainerRuntime) Name() string {
+	return string(K3s)
+}
+
+func (k *K3sContainerRuntime) CRISocket() string {
+	return K3sCRISocket
+}
+
+// CgroupDriverCmd k3s sets the cgroup driver of kubelet by itself, it is cgroupfs by default.
+func (k *K3sContainerRuntime) CgroupDriverCmd() string {
+	return "echo cgroupfs"
+}
+
+func (k *K3sContainerRuntime) RegistryCertDir() string {
+	return filepath.Join(K3sConfigDir, "certs.d")
+}
+
+// TrustRegistryCmd the ca_file of registries.yaml is the cert sent to RegistryCertDir.
+func (k *K3sContainerRuntime) TrustRegistryCmd(cf *RegistryConfig) string {
+	return ""
+}
+
+// LoginRegistryCmd the auth of registry is in registries.yaml.
+func (k *K3sContainerRuntime) LoginRegistryCmd(cf *RegistryConfig) string {
+	return ""
+}
+
+// StartRegistryCmd copy the images of rootfs to K3sAgentImagesDir, which k3s imports on start, and import them at once
+// if k3s is running. The registry pod is started by the kubelet of k3s from K3sPodManifestsDir.
+func (k *K3sContainerRuntime) StartRegistryCmd(rootfs string, cf *RegistryConfig) string {
+	var (
+		imageDir = filepath.Join(rootfs, "images")
+		dataDir  = filepath.Join(rootfs, "registry")
+		pod      = fmt.Sprintf(k3sRegistryPod, cf.Port, cf.Domain, cf.Domain,
+			filepath.Join(rootfs, "certs"), dataDir, filepath.Join(rootfs, "etc"))
+	)
+	cmds := []string{
+		fmt.Sprintf("mkdir -p %s %s", dataDir, K3sAgentImagesDir),
+		// k3s only imports the files of tar suffixes.
+		fmt.Sprintf(`for image in %s/*; do if [ -f "$image" ]; then name=$(basename "$image"); case "$name" in *.tar|*.tar.*) ;; *) name="$name.tar";; esac; cp -f "$image" %s/"$name"; fi; done`,
+			imageDir, K3sAgentImagesDir),
+		fmt.Sprintf(`if [ -S %s ]; then for image in %s/*; do if [ -f "$image" ]; then %s ctr -n %s images import "$image"; fi; done; fi`,
+			K3sCRISocket, imageDir, K3sBinPath, ContainerdNamespace),
+		k3sWriteFileCmd(K3sPodManifestsDir, pod, k3sRegistryManifest()),
+	}
+	return strings.Join(cmds, " && ")
+}
+
+// k3sWriteFileCmd return the command writing content to file, the content is quoted for shell so any quote in it is kept,
+// and printf is used since the echo of dash interprets the backslashes.
+func k3sWriteFileCmd(dir, content, file string) string {
+	return fmt.Sprintf(RemoteK3sWriteFile, dir, "'"+strings.ReplaceAll(content, "'", `'\''`)+"'", file)
+}
+
+func (k *K3sContainerRuntime) StopRegistryCmd() string {
+	return fmt.Sprintf("rm -f %s", k3sRegistryManifest())
+}
+
+// GarbageCollectRegistryCmd stop the registry pod by moving its manifest to a hidden file ignored by the kubelet, run
+// the garbage collection by k3s ctr on the registry data dir and config of rootfs, and move the manifest back.
+func (k *K3sContainerRuntime) GarbageCollectRegistryCmd(rootfs string) string {
+	var (
+		manifest = k3sRegistryManifest()
+		hidden   = filepath.Join(K3sPodManifestsDir, "."+RegistryName+".yaml")
+		running  = fmt.Sprintf(`"$(%s crictl ps -q --name '^%s$')"`, K3sBinPath, RegistryName)
+		config   = filepath.Join(rootfs, "etc", RegistryBindConfig)
+	)
+	stop := fmt.Sprintf("mv -f %s %s && n=0 && while [ -n %s ] && [ $n -lt 60 ]; do sleep 1; n=$((n+1)); done && [ -z %s ]",
+		manifest, hidden, running, running)
+	run := fmt.Sprintf(`%s ctr -n %s run --rm --mount type=bind,src=%s,dst=%s,options=rbind:rw $(if [ -f %s ]; then echo "--mount type=bind,src=%s,dst=/etc/docker/registry/config.yml,options=rbind:ro"; fi) docker.io/library/%s %s-gc registry garbage-collect --delete-untagged /etc/docker/registry/config.yml`,
+		K3sBinPath, ContainerdNamespace, filepath.Join(rootfs, "registry"), RegistryBindDest, config, config, RegistryImage, RegistryName)
+	return fmt.Sprintf("%s && %s; rc=$?; mv -f %s %s && [ $rc -eq 0 ]", stop, run, hidden, manifest)
+}
+
+func k3sRegistryManifest() string {
+	return filepath.Join(K3sPodManifestsDir, RegistryName+".yaml")
+}
+
+func (k *K3sRuntime) getServerURL() string {
+	return "https://" + net.JoinHostPort(k.GetMaster0IP(), "6443")
+}
+
+// serverConfig return the config of the server on ip, master0 initializes the embedded etcd and the others join it.
+func (k *K3sRuntime) serverConfig(ip, token string) k3sConfig {
+	config := k3sConfig{
+		NodeIP:              ip,
+		TLSSAN:              k.kubeadm.getCertSANS(),
+		WriteKubeconfigMode: "0600",
+	}
+	if ip == k.GetMaster0IP() {
+		config.ClusterInit = true
+	} else {
+		config.Server, config.Token = k.getServerURL(), token
+	}
+	if c := k.kubeadm.ClusterFileKubeConfig; c != nil {
+		config.ClusterCIDR = c.ClusterConfiguration.Networking.PodSubnet
+		config.ServiceCIDR = c.ClusterConfiguration.Networking.ServiceSubnet
+		config.ClusterDomain = c.ClusterConfiguration.Networking.DNSDomain
+	}
+	return config
+}
+
+func (k *K3sRuntime) agentConfig(ip, token string) k3sConfig {
+	return k3sConfig{Server: k.getServerURL(), Token: token, NodeIP: ip}
+}
+
+// registriesConfig let the containerd of k3s trust the registry of ClusterImage, the cert is sent by sendRegistryCert.
+func (k *K3sRuntime) registriesConfig() ([]byte, error) {
+	cf := GetRegistryConfig(k.kubeadm.getImageMountDir(), k.GetMaster0IP())
+	endpoint := fmt.Sprintf("%s:%s", cf.Domain, cf.Port)
+	cr, err := k.kubeadm.getContainerRuntime()
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]interface{}{
+		"tls": map[string]string{"ca_file": registryCertPath(cr, cf)},
+	}
+	if cf.Username != "" && cf.Password != "" {
+		config["auth"] = map[string]string{"username": cf.Username, "password": cf.Password}
+	}
+	return yaml.Marshal(map[string]interface{}{
+		"mirrors": map[string]interface{}{endpoint: map[string][]string{"endpoint": {"https://" + endpoint}}},
+		"configs": map[string]interface{}{endpoint: config},
+	})
+}
+
+// installCmds return the commands to install k3s service with config on host.
+func (k *K3sRuntime) installCmds(host, service string, config k3sConfig) ([]string, error) {
+	configData, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	registries, err := k.registriesConfig()
+	if err != nil {
+		return nil, err
+	}
+	subcommand := "server"
+	if service == K3sAgentService {
+		subcommand = "agent"
+	}
+	cf := GetRegistryConfig(k.kubeadm.getImageMountDir(), k.GetMaster0IP())
+	cmds := []string{
+		getRegistryHostsCmd(cf.Domain, GetRegistryHosts(k.Cluster, cf)),
+		k3sWriteFileCmd(K3sConfigDir, string(registries), K3sRegistriesFile),
+		k3sWriteFileCmd(K3sConfigDir, string(configData), K3sConfigFile),
+		fmt.Sprintf(RemoteK3sInstall, k.kubeadm.getRootfs(), K3sBinPath, K3sBinPath, K3sBinPath, common.KubectlPath),
+		k3sWriteFileCmd(filepath.Dir(K3sServiceFile), fmt.Sprintf(k3sService, subcommand), fmt.Sprintf(K3sServiceFile, service)),
+		fmt.Sprintf(RemoteK3sStart, service, service),
+	}
+	if service == K3sServerService {
+		apiServerHost := getAPIServerHost(host, k.kubeadm.getAPIServerDomain())
+		cmds = append(cmds,
+			fmt.Sprintf(RemoteAddEtcHosts, apiServerHost, apiServerHost),
+			fmt.Sprintf(RemoteK3sCopyKubeConfig, k.kubeadm.getAPIServerDomain()))
+	}
+	return cmds, nil
+}
+
+func (k *K3sRuntime) installOnHost(host, service string, config k3sConfig) error {
+	cmds, err := k.installCmds(host, service, config)
+	if err != nil {
+		return err
+	}
+	ssh, err := k.kubeadm.getHostSSHClient(host)
+	if err != nil {
+		return err
+	}
+	return ssh.CmdAsync(host, cmds...)
+}
+
+func (k *K3sRuntime) getToken() (string, error) {
+	token, err := k.kubeadm.CmdToString(k.GetMaster0IP(), "cat "+K3sTokenFile, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get k3s token from master0: %v", err)
+	}
+	return strings.TrimSpace(token), nil
+}
+
+func (k *K3sRuntime) Init(cluster *v2.Cluster) error {
+	pipeline := []func() error{
+		k.kubeadm.GenerateRegistryCert,
+		k.kubeadm.ApplyRegistry,
+		k.initServer0,
+		k.kubeadm.GetKubectlAndKubeconfig,
+	}
+	for _, f := range pipeline {
+		if err := f(); err != nil {
+			return fmt.Errorf("failed to init master0 %v", err)
+		}
+	}
+	return nil
+}
+
+func (k *K3sRuntime) initServer0() error {
+	logger.Info("start to init master0 with k3s...")
+	return k.installOnHost(k.GetMaster0IP(), K3sServerService, k.serverConfig(k.GetMaster0IP(), ""))
+}
+
+func (k *K3sRuntime) JoinMasters(newMastersIPList []string) error {
+	if len(newMastersIPList) == 0 {
+		return nil
+	}
+	logger.Info("%s will be added as master", newMastersIPList)
+	if err := k.kubeadm.sendRegistryCert(newMastersIPList); err != nil {
+		return err
+	}
+	if err := k.kubeadm.startNewRegistries(newMastersIPList); err != nil {
+		return err
+	}
+	token, err := k.getToken()
+	if err != nil {
+		return err
+	}
+	// the embedded etcd members are added one by one.
+	for _, master := range newMastersIPList {
+		logger.Info("Start to join %s as master", master)
+		if err := k.installOnHost(master, K3sServerService, k.serverConfig(master, token)); err != nil {
+			return fmt.Errorf("failed to join master %s: %v", master, err)
+		}
+		logger.Info("Succeeded in joining %s as master", master)
+	}
+	return k.kubeadm.resolveNewRegistries(newMastersIPList)
+}
+
+func (k *K3sRuntime) JoinNodes(newNodesIPList []string) error {
+	if len(newNodesIPList) == 0 {
+		return nil
+	}
+	logger.Info("%s will be added as worker", newNodesIPList)
+	token, err := k.getToken()
+	if err != nil {
+		return err
+	}
+	return RunInParallel(newNodesIPList, func(node string) error {
+		logger.Info("Start to join %s as worker", node)
+		if err := k.kubeadm.sendRegistryCert([]string{node}); err != nil {
+			return fmt.Errorf("failed to join node %s %v", node, err)
+		}
+		if err := k.installOnHost(node, K3sAgentService, k.agentConfig(node, token)); err != nil {
+			return fmt.Errorf("failed to join node %s %v", node, err)
+		}
+		logger.Info("Succeeded in joining %s as worker", node)
+		return nil
+	})
+}
+
+func (k *K3sRuntime) DeleteMasters(mastersIPList []string) error {
+	if len(mastersIPList) == 0 {
+		return nil
+	}
+	mastersLeft := utils.RemoveStrSlice(k.GetMasterIPList(), mastersIPList)
+	// the servers and agents left are configured to join the server url of master0.
+	if len(mastersLeft) != 0 && utils.InList(k.GetMaster0IP(), mastersIPList) {
+		return fmt.Errorf("%s runtime does not support deleting master0 %s while other masters are left", K3s, k.GetMaster0IP())
+	}
+	logger.Info("master %s will be deleted", mastersIPList)
+	if err := k.kubeadm.confirmDeleteNodes(); err != nil {
+		return err
+	}
+	if err := k.kubeadm.migrateRegistry(mastersIPList, mastersLeft); err != nil {
+		return err
+	}
+	// k3s removes the etcd member of the deleted server node.
+	for _, master := range mastersIPList {
+		if err := k.deleteHost(master); err != nil {
+			return fmt.Errorf("delete master %s failed %v", master, err)
+		}
+	}
+	return nil
+}
+
+func (k *K3sRuntime) DeleteNodes(nodesIPList []string) error {
+	if len(nodesIPList) == 0 {
+		return nil
+	}
+	logger.Info("worker %s will be deleted", nodesIPList)
+	if err := k.kubeadm.confirmDeleteNodes(); err != nil {
+		return err
+	}
+	return k.deleteHosts(nodesIPList)
+}
+
+func (k *K3sRuntime) deleteHosts(hosts []string) error {
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, host := range hosts {
+		host := host
+		eg.Go(func() error {
+			if err := k.deleteHost(host); err != nil {
+				return fmt.Errorf("delete node %s failed %v", host, err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// deleteHost delete the node of host from the cluster and uninstall k3s on it.
+func (k *K3sRuntime) deleteHost(host string) error {
+	logger.Info("Start to delete %s", host)
+	if host != k.GetMaster0IP() {
+		hostname, err := k.kubeadm.isHostName(k.GetMaster0IP(), host)
+		if err != nil {
+			return err
+		}
+		if hostname = strings.TrimSpace(hostname); hostname != "" {
+			ssh, err := k.kubeadm.getHostSSHClient(k.GetMaster0IP())
+			if err != nil {
+				return err
+			}
+			if err := ssh.CmdAsync(k.GetMaster0IP(), fmt.Sprintf(KubeDeleteNode, hostname)); err != nil {
+				return err
+			}
+		} else {
+			logger.Warn("node %s is not found in the cluster, skip deleting it", host)
+		}
+	}
+	if err := k.resetHost(host); err != nil {
+		return err
+	}
+	logger.Info("Succeeded in deleting %s", host)
+	return nil
+}
+
+func (k *K3sRuntime) resetHost(host string) error {
+	ssh, err := k.kubeadm.getHostSSHClient(host)
+	if err != nil {
+		return err
+	}
+	cr, err := k.kubeadm.getContainerRuntime()
+	if err != nil {
+		return err
+	}
+	return ssh.CmdAsync(host, RemoteK3sClean,
+		cr.StopRegistryCmd(),
+		fmt.Sprintf(RemoteRemoveAPIServerEtcHost, k.kubeadm.getAPIServerDomain()),
+		k.kubeadm.removeRegistryHostsCmd())
+}
+
+func (k *K3sRuntime) JoinEtcds(newEtcdIPList []string) error {
+	if len(newEtcdIPList) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s runtime does not support external etcd hosts %s, the servers run embedded etcd", K3s, newEtcdIPList)
+}
+
+func (k *K3sRuntime) DeleteEtcds(etcdIPList []string) error {
+	return k.JoinEtcds(etcdIPList)
+}
+
+func (k *K3sRuntime) RollbackJoin(masters, nodes, etcds []string) error {
+	if len(masters) == 0 && len(nodes) == 0 {
+		return nil
+	}
+	logger.Info("Start to roll back the joining of master %s, worker %s", masters, nodes)
+	if err := k.deleteHosts(nodes); err != nil {
+		return err
+	}
+	for _, master := range masters {
+		if err := k.deleteHost(master); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *K3sRuntime) Reset() error {
+	logger.Info("Start to delete cluster: master %s, node %s", k.GetMasterIPList(), k.GetNodeIPList())
+	if err := k.kubeadm.confirmDeleteNodes(); err != nil {
+		return err
+	}
+	for _, host := range append(k.GetNodeIPList(), k.GetMasterIPList()...) {
+		if err := k.resetHost(host); err != nil {
+			logger.Error("delete node %s failed %v", host, err)
+		}
+	}
+	return k.kubeadm.DeleteRegistry()
+}
+
+// Upgrade replace the k3s binary with the one of new rootfs and restart k3s, masters first and one by one.
+func (k *K3sRuntime) Upgrade() error {
+	for _, master := range k.GetMasterIPList() {
+		if err := k.reinstall(master, K3sServerService); err != nil {
+			return fmt.Errorf("failed to upgrade master %s: %v", master, err)
+		}
+	}
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, node := range k.GetNodeIPList() {
+		node := node
+		eg.Go(func() error {
+			if err := k.reinstall(node, K3sAgentService); err != nil {
+				return fmt.Errorf("failed to upgrade node %s: %v", node, err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// RollbackUpgrade reinstall the k3s binary of rootfs, which is mounted from the previous ClusterImage, workers first.
+func (k *K3sRuntime) RollbackUpgrade(restoreEtcd bool) error {
+	if restoreEtcd {
+		return fmt.Errorf("restoring etcd is not supported by k3s runtime")
+	}
+	for _, node := range k.GetNodeIPList() {
+		if err := k.reinstall(node, K3sAgentService); err != nil {
+			return fmt.Errorf("failed to roll back node %s: %v", node, err)
+		}
+	}
+	for _, master := range k.GetMasterIPList() {
+		if err := k.reinstall(master, K3sServerService); err != nil {
+			return fmt.Errorf("failed to roll back master %s: %v", master, err)
+		}
+	}
+	return nil
+}
+
+// reinstall replace the k3s binary with the one of rootfs and restart the service.
+func (k *K3sRuntime) reinstall(host, service string) error {
+	ssh, err := k.kubeadm.getHostSSHClient(host)
+	if err != nil {
+		return err
+	}
+	logger.Info("Start to install k3s of %s on %s", k.kubeadm.getRootfs(), host)
+	return ssh.CmdAsync(host,
+		fmt.Sprintf(RemoteK3sInstall, k.kubeadm.getRootfs(), K3sBinPath, K3sBinPath, K3sBinPath, common.KubectlPath),
+		fmt.Sprintf("systemctl restart %s", service))
+}
+
+func (k *K3sRuntime) GetClusterMetadata() (*Metadata, error) {
+	md, err := LoadMetadata(k.kubeadm.getImageMountDir())
+	if err != nil {
+		return nil, err
+	}
+	if md == nil {
+		return nil, fmt.Errorf("no Metadata found in ClusterImage")
+	}
+	return &Metadata{Version: md.Version}, nil
+}
+
+// UpdateCert add the certSANs to the config of servers, k3s regenerates the serving cert on restart.
+func (k *K3sRuntime) UpdateCert(certs []string) error {
+	k.kubeadm.setCertSANS(certs)
+	token, err := k.getToken()
+	if err != nil {
+		return err
+	}
+	for _, master := range k.GetMasterIPList() {
+		configData, err := yaml.Marshal(k.serverConfig(master, token))
+		if err != nil {
+			return err
+		}
+		ssh, err := k.kubeadm.getHostSSHClient(master)
+		if err != nil {
+			return err
+		}
+		if err := ssh.CmdAsync(master, k3sWriteFileCmd(K3sConfigDir, string(configData), K3sConfigFile),
+			fmt.Sprintf("systemctl restart %s", K3sServerService)); err != nil {
+			return fmt.Errorf("failed to update cert of master %s: %v", master, err)
+		}
+	}
+	return nil
+}
+
+func (k *K3sRuntime) ListRegistryImages() ([]RegistryRepository, error) {
+	return k.kubeadm.ListRegistryImages()
+}
+
+func (k *K3sRuntime) PushRegistryImage(src, name string) error {
+	return k.kubeadm.PushRegistryImage(src, name)
+}
+
+func (k *K3sRuntime) RemoveRegistryImage(name string) error {
+	return k.kubeadm.RemoveRegistryImage(name)
+}
+
+func (k *K3sRuntime) GarbageCollectRegistry() error {
+	return k.kubeadm.GarbageCollectRegistry()
+}
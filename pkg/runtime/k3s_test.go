@@ -0,0 +1,129 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/alibaba/sealer/common"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+func TestK3sConfig(t *testing.T) {
+	cluster := &v2.Cluster{}
+	cluster.Name = "k3s-test"
+	cluster.Spec.Hosts = []v2.Host{
+		{IPS: []string{"192.168.0.2", "192.168.0.3"}, Roles: []string{common.MASTER}},
+		{IPS: []string{"192.168.0.4"}, Roles: []string{common.NODE}},
+	}
+	r, err := newK3sRuntime(cluster, nil)
+	if err != nil {
+		t.Fatalf("newK3sRuntime() error = %v", err)
+	}
+	k := r.(*K3sRuntime)
+	sans := []string{"127.0.0.1", DefaultAPIserverDomain, DefaultVIP, "192.168.0.2", "192.168.0.3"}
+
+	tests := []struct {
+		name string
+		got  k3sConfig
+		want k3sConfig
+	}{
+		{
+			name: "master0",
+			got:  k.serverConfig("192.168.0.2", "token"),
+			want: k3sConfig{ClusterInit: true, NodeIP: "192.168.0.2", TLSSAN: sans, WriteKubeconfigMode: "0600"},
+		},
+		{
+			name: "master",
+			got:  k.serverConfig("192.168.0.3", "token"),
+			want: k3sConfig{Server: "https://192.168.0.2:6443", Token: "token", NodeIP: "192.168.0.3", TLSSAN: sans, WriteKubeconfigMode: "0600"},
+		},
+		{
+			name: "node",
+			got:  k.agentConfig("192.168.0.4", "token"),
+			want: k3sConfig{Server: "https://192.168.0.2:6443", Token: "token", NodeIP: "192.168.0.4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("k3s config = %+v, want %+v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetClusterRuntime(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        ClusterRuntime
+		wantErr     bool
+	}{
+		{
+			name:        "recorded k3s",
+			annotations: map[string]string{common.ClusterRuntime: string(K3s)},
+			want:        K3s,
+		},
+		{
+			name:        "recorded kubernetes",
+			annotations: map[string]string{common.ClusterRuntime: string(K8s)},
+			want:        K8s,
+		},
+		{
+			name: "not recorded",
+			want: K8s,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &v2.Cluster{}
+			cluster.Name = "cluster-runtime-test"
+			cluster.Annotations = tt.annotations
+			got, err := getClusterRuntime(cluster)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getClusterRuntime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getClusterRuntime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestK3sWriteFileCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k3s-write-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config", "config.yaml")
+	content := `token: it's "quoted" \n $HOME`
+	if out, err := exec.Command("sh", "-c", k3sWriteFileCmd(filepath.Dir(file), content, file)).CombinedOutput(); err != nil {
+		t.Fatalf("k3sWriteFileCmd() failed: %v, %s", err, out)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content+"\n" {
+		t.Errorf("k3sWriteFileCmd() wrote %q, want %q", data, content+"\n")
+	}
+}
@@ -47,13 +47,11 @@ func (k *KubeadmRuntime) setKubeadmAPIVersion() {
 
 // getCgroupDriverFromShell is get nodes container runtime CGroup by shell.
 func (k *KubeadmRuntime) getCgroupDriverFromShell(node string) (string, error) {
-	var cmd string
-	if k.InitConfiguration.NodeRegistration.CRISocket == DefaultContainerdCRISocket {
-		cmd = ContainerdShell
-	} else {
-		cmd = DockerShell
+	cr, err := k.getContainerRuntime()
+	if err != nil {
+		return "", err
 	}
-	driver, err := k.CmdToString(node, cmd, " ")
+	driver, err := k.CmdToString(node, cr.CgroupDriverCmd(), " ")
 	if err != nil {
 		return "", fmt.Errorf("failed to get nodes [%s] cgroup driver: %v", node, err)
 	}
@@ -79,6 +77,9 @@ func (k *KubeadmRuntime) MergeKubeadmConfig() error {
 	if err := k.Merge(k.getDefaultKubeadmConfig()); err != nil {
 		return fmt.Errorf("failed to merge kubeadm config: %v", err)
 	}
+	if err := k.setIPv6Subnets(); err != nil {
+		return err
+	}
 	k.setKubeadmAPIVersion()
 	return nil
 }
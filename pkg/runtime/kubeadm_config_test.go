@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/alibaba/sealer/logger"
+	v2 "github.com/alibaba/sealer/types/api/v2"
 
 	"github.com/alibaba/sealer/utils"
 )
@@ -429,3 +430,43 @@ func TestKubeadmConfig_Merge(t *testing.T) {
 		})
 	}
 }
+
+func TestSetIPv6Subnets(t *testing.T) {
+	tests := []struct {
+		name              string
+		master0           string
+		podSubnet         string
+		serviceSubnet     string
+		clusterfileSubnet string
+		wantPodSubnet     string
+		wantServiceSubnet string
+		wantErr           bool
+	}{
+		{"ipv4 master", "192.168.0.2", "100.64.0.0/10", "10.96.0.0/22", "", "100.64.0.0/10", "10.96.0.0/22", false},
+		{"ipv6 master with ipv4 defaults", "fd00::2", "100.64.0.0/10", "10.96.0.0/22", "", DefaultPodSubnetV6, DefaultServiceSubnetV6, false},
+		{"ipv6 master with ipv6 subnets", "fd00::2", "fd00:1::/56", "fd00:2::/112", "", "fd00:1::/56", "fd00:2::/112", false},
+		{"ipv6 master with dual-stack subnets", "fd00::2", "100.64.0.0/10,fd00:1::/56", "10.96.0.0/22,fd00:2::/112", "",
+			"100.64.0.0/10,fd00:1::/56", "10.96.0.0/22,fd00:2::/112", false},
+		{"ipv6 master with ipv4 subnet of Clusterfile", "fd00::2", "172.16.0.0/16", "10.96.0.0/22", "172.16.0.0/16", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &KubeadmRuntime{
+				Cluster:       &v2.Cluster{Spec: v2.ClusterSpec{Hosts: []v2.Host{{IPS: []string{tt.master0}}}}},
+				KubeadmConfig: &KubeadmConfig{},
+				Config:        &Config{ClusterFileKubeConfig: &KubeadmConfig{}},
+			}
+			k.Networking.PodSubnet, k.Networking.ServiceSubnet = tt.podSubnet, tt.serviceSubnet
+			k.Config.ClusterFileKubeConfig.Networking.PodSubnet = tt.clusterfileSubnet
+			err := k.setIPv6Subnets()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("setIPv6Subnets() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && (k.Networking.PodSubnet != tt.wantPodSubnet || k.Networking.ServiceSubnet != tt.wantServiceSubnet) {
+				t.Errorf("setIPv6Subnets() = %s %s, want %s %s", k.Networking.PodSubnet, k.Networking.ServiceSubnet,
+					tt.wantPodSubnet, tt.wantServiceSubnet)
+			}
+		})
+	}
+}
@@ -17,9 +17,11 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"net"
 
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -30,6 +32,8 @@ import (
 
 	"github.com/alibaba/sealer/common"
 	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/cert"
+	"github.com/alibaba/sealer/pkg/env"
 	v2 "github.com/alibaba/sealer/types/api/v2"
 	"github.com/alibaba/sealer/utils/ssh"
 )
@@ -41,6 +45,15 @@ type Config struct {
 	// Clusterfile: the absolute path, we need to read kubeadm config from Clusterfile
 	ClusterFileKubeConfig *KubeadmConfig
 	APIServerDomain       string
+	HA                    *HAConfig
+	// the cert options of cluster env, which are passed to seautil on the joining masters.
+	CertCAValidity   string
+	CertValidity     string
+	CertKeyAlgorithm string
+
+	containerRuntime     ContainerRuntime
+	containerRuntimeErr  error
+	containerRuntimeOnce sync.Once
 }
 
 func newKubeadmRuntime(cluster *v2.Cluster, clusterFileKubeConfig *KubeadmConfig) (Interface, error) {
@@ -52,11 +65,21 @@ func newKubeadmRuntime(cluster *v2.Cluster, clusterFileKubeConfig *KubeadmConfig
 		},
 		KubeadmConfig: &KubeadmConfig{},
 	}
-	k.setCertSANS(append([]string{"127.0.0.1", k.getAPIServerDomain(), k.getVIP()}, k.GetMasterIPList()...))
+	if err := k.setHAConfig(); err != nil {
+		return nil, err
+	}
+	certSANs := []string{"127.0.0.1", k.getAPIServerDomain(), k.getVIP()}
+	if utils.IsIPv6(k.GetMaster0IP()) {
+		certSANs = append(certSANs, "::1")
+	}
+	k.setCertSANS(append(certSANs, k.GetMasterIPList()...))
 	// TODO args pre checks
 	if err := k.checkList(); err != nil {
 		return nil, err
 	}
+	if err := k.setCertOptions(); err != nil {
+		return nil, err
+	}
 
 	if logger.IsDebugModel() {
 		k.Vlog = 6
@@ -74,6 +97,30 @@ func (k *KubeadmRuntime) checkList() error {
 	return nil
 }
 
+// setCertOptions set the validity and key algorithm of the certs generated by sealer from the cluster env.
+func (k *KubeadmRuntime) setCertOptions() error {
+	getEnv, err := env.StringGetter(k.Spec.Env)
+	if err != nil {
+		return err
+	}
+	k.Config.CertCAValidity, k.Config.CertValidity, k.Config.CertKeyAlgorithm = getEnv(CertCAValidity), getEnv(CertValidity), getEnv(CertKeyAlgorithm)
+	if err := cert.SetOptions(k.Config.CertCAValidity, k.Config.CertValidity, k.Config.CertKeyAlgorithm); err != nil {
+		return fmt.Errorf("invalid cert options in cluster env: %v", err)
+	}
+	// the options are passed to seautil certs of joining masters only if they are not the defaults,
+	// the seautil of older ClusterImages does not support them.
+	if cert.CAValidity == cert.DefaultValidity {
+		k.Config.CertCAValidity = ""
+	}
+	if cert.CertValidity == cert.DefaultValidity {
+		k.Config.CertValidity = ""
+	}
+	if cert.CertKeyAlgorithm == cert.DefaultKeyAlgorithm {
+		k.Config.CertKeyAlgorithm = ""
+	}
+	return nil
+}
+
 func (k *KubeadmRuntime) getClusterName() string {
 	return k.Cluster.Name
 }
@@ -89,6 +136,38 @@ func (k *KubeadmRuntime) getClusterMetadata() (*Metadata, error) {
 	return metadata, nil
 }
 
+// getContainerRuntime return the container runtime of hosts, it is the ContainerRuntime of ClusterImage Metadata,
+// or decided by the cri socket in kubeadm config, or detected on master0.
+func (k *KubeadmRuntime) getContainerRuntime() (ContainerRuntime, error) {
+	k.containerRuntimeOnce.Do(func() {
+		if k.containerRuntime, k.containerRuntimeErr = k.detectContainerRuntime(); k.containerRuntimeErr == nil {
+			logger.Debug("container runtime of cluster hosts is %s", k.containerRuntime.Name())
+		}
+	})
+	return k.containerRuntime, k.containerRuntimeErr
+}
+
+func (k *KubeadmRuntime) detectContainerRuntime() (ContainerRuntime, error) {
+	md, err := LoadMetadata(k.getImageMountDir())
+	if err != nil {
+		logger.Warn("failed to load ClusterImage metadata: %v", err)
+	}
+	name := ""
+	switch {
+	case md != nil && md.ContainerRuntime != "":
+		name = md.ContainerRuntime
+	case k.InitConfiguration.NodeRegistration.CRISocket == DefaultContainerdCRISocket:
+		name = Containerd
+	case k.InitConfiguration.NodeRegistration.CRISocket == DefaultDockerCRISocket:
+		name = Docker
+	default:
+		if name, err = k.CmdToString(k.GetMaster0IP(), DetectContainerRuntimeCmd, ""); err != nil {
+			return nil, fmt.Errorf("failed to detect container runtime on %s: %v", k.GetMaster0IP(), err)
+		}
+	}
+	return NewContainerRuntime(name)
+}
+
 func (k *KubeadmRuntime) getHostSSHClient(hostIP string) (ssh.Interface, error) {
 	return ssh.NewStdoutSSHClient(hostIP, k.Cluster)
 }
@@ -161,7 +240,10 @@ func (k *KubeadmRuntime) getKubeVersion() string {
 }
 
 func (k *KubeadmRuntime) getVIP() string {
-	return DefaultVIP
+	if k.VIP == "" {
+		return DefaultVIP
+	}
+	return k.VIP
 }
 
 func (k *KubeadmRuntime) getJoinToken() string {
@@ -230,6 +312,11 @@ func (k *KubeadmRuntime) setCgroupDriver(cGroup string) {
 	k.KubeletConfiguration.CgroupDriver = cGroup
 }
 
+func (k *KubeadmRuntime) setCRISocket(socket string) {
+	k.InitConfiguration.NodeRegistration.CRISocket = socket
+	k.JoinConfiguration.NodeRegistration.CRISocket = socket
+}
+
 func (k *KubeadmRuntime) setAPIVersion(apiVersion string) {
 	k.InitConfiguration.APIVersion = apiVersion
 	k.ClusterConfiguration.APIVersion = apiVersion
@@ -238,7 +325,7 @@ func (k *KubeadmRuntime) setAPIVersion(apiVersion string) {
 func getEtcdEndpointsWithHTTPSPrefix(masters []string) string {
 	var tmpSlice []string
 	for _, ip := range masters {
-		tmpSlice = append(tmpSlice, fmt.Sprintf("https://%s:2379", utils.GetHostIP(ip)))
+		tmpSlice = append(tmpSlice, "https://"+net.JoinHostPort(utils.GetHostIP(ip), "2379"))
 	}
 	return strings.Join(tmpSlice, ",")
 }
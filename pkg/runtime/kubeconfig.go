@@ -0,0 +1,75 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/alibaba/sealer/pkg/cert"
+	"github.com/alibaba/sealer/utils"
+)
+
+// KubeConfigOptions are the client cert options of the kubeconfig issued for a user.
+type KubeConfigOptions struct {
+	// User is the common name of client cert.
+	User string
+	// Groups are the organizations of client cert.
+	Groups []string
+	TTL    time.Duration
+	// Server is the apiserver endpoint, https://master0:6443 if it is empty.
+	Server string
+}
+
+// loadKubernetesCA load the kubernetes ca from the pki dir of cluster, or from master0 if it is not found.
+func (k *KubeadmRuntime) loadKubernetesCA() (*x509.Certificate, crypto.Signer, error) {
+	caConfig := cert.Config{Path: k.getPKIPath(), BaseName: "ca"}
+	if utils.IsFileExist(filepath.Join(caConfig.Path, "ca.key")) {
+		return cert.LoadCaCertAndKeyFromDisk(caConfig)
+	}
+	cas, err := k.loadCAs()
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, ok := cas[cert.CAFiles[0].Path]
+	if !ok {
+		return nil, nil, fmt.Errorf("kubernetes ca is not found in %s or on master0", caConfig.Path)
+	}
+	return ca.cert, ca.key, nil
+}
+
+// issueKubeConfig return a kubeconfig with a client cert signed by the kubernetes ca.
+func (k *KubeadmRuntime) issueKubeConfig(opts KubeConfigOptions) ([]byte, error) {
+	if opts.TTL <= 0 {
+		return nil, fmt.Errorf("ttl of kubeconfig should be positive")
+	}
+	caCert, caKey, err := k.loadKubernetesCA()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Server == "" {
+		opts.Server = "https://" + net.JoinHostPort(k.GetMaster0IP(), "6443")
+	}
+	var out bytes.Buffer
+	if err := cert.WriteKubeConfigWithClientCertByCA(&out, caCert, caKey, opts.User, opts.Server, k.getClusterName(), opts.Groups, opts.TTL); err != nil {
+		return nil, fmt.Errorf("failed to create kubeconfig of %s: %v", opts.User, err)
+	}
+	return out.Bytes(), nil
+}
@@ -17,6 +17,7 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"net"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -29,7 +30,6 @@ import (
 	"github.com/alibaba/sealer/logger"
 	"github.com/alibaba/sealer/pkg/cert"
 	"github.com/alibaba/sealer/pkg/command"
-	"github.com/alibaba/sealer/pkg/ipvs"
 	"github.com/alibaba/sealer/utils"
 )
 
@@ -38,6 +38,7 @@ const (
 	V1992 = "v1.19.2"
 	V1150 = "v1.15.0"
 	V1200 = "v1.20.0"
+	V1210 = "v1.21.0"
 	V1230 = "v1.23.0"
 )
 
@@ -48,8 +49,8 @@ const (
 	RemoteReplaceKubeConfig = `grep -qF "apiserver.cluster.local" %s  && sed -i 's/apiserver.cluster.local/%s/' %s && sed -i 's/apiserver.cluster.local/%s/' %s`
 	RemoteJoinMasterConfig  = `echo "%s" > %s/etc/kubeadm.yml`
 	InitMaster115Lower      = `kubeadm init --config=%s/etc/kubeadm.yml --experimental-upload-certs`
-	JoinMaster115Lower      = "kubeadm join %s:6443 --token %s --discovery-token-ca-cert-hash %s --experimental-control-plane --certificate-key %s"
-	JoinNode115Lower        = "kubeadm join %s:6443 --token %s --discovery-token-ca-cert-hash %s"
+	JoinMaster115Lower      = "kubeadm join %s --token %s --discovery-token-ca-cert-hash %s --experimental-control-plane --certificate-key %s"
+	JoinNode115Lower        = "kubeadm join %s --token %s --discovery-token-ca-cert-hash %s"
 	InitMaser115Upper       = `kubeadm init --config=%s/etc/kubeadm.yml --upload-certs`
 	JoinMaster115Upper      = "kubeadm join --config=%s/etc/kubeadm.yml"
 	JoinNode115Upper        = "kubeadm join --config=%s/etc/kubeadm.yml"
@@ -110,6 +111,10 @@ const (
 	CriCGroupDriver      = "CriCGroupDriver"
 	KubeadmAPI           = "KubeadmAPI"
 	TokenDiscoveryCAHash = "TokenDiscoveryCAHash"
+	// the cluster env keys of the validity like 10y, 365d or 8760h and the key algorithm of generated certs.
+	CertCAValidity   = "CertCAValidity"
+	CertValidity     = "CertValidity"
+	CertKeyAlgorithm = "CertKeyAlgorithm"
 )
 
 type CommandType string
@@ -123,21 +128,27 @@ func getAPIServerHost(ipAddr, APIServer string) (host string) {
 	return fmt.Sprintf("%s %s", ipAddr, APIServer)
 }
 
-func (k *KubeadmRuntime) JoinMasterCommands(master, joinCmd, hostname string) []string {
-	registryHost := getRegistryHost(k.getImageMountDir(), k.GetMaster0IP())
+func (k *KubeadmRuntime) JoinMasterCommands(master, joinCmd, hostname string) ([]string, error) {
+	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
 	apiServerHost := getAPIServerHost(k.GetMaster0IP(), k.getAPIServerDomain())
-	cmdAddRegistryHosts := fmt.Sprintf(RemoteAddEtcHosts, registryHost, registryHost)
-	certCMD := command.RemoteCerts(k.getCertSANS(), master, hostname, k.getSvcCIDR(), "")
+	cmdAddRegistryHosts := getRegistryHostsCmd(cf.Domain, GetRegistryHosts(k.Cluster, cf))
+	// the certs of the joining master are generated with the cert options of cluster env like the ones of master0.
+	certCMD := command.RemoteCerts(k.getCertSANS(), master, hostname, k.getSvcCIDR(), "",
+		k.CertCAValidity, k.CertValidity, k.CertKeyAlgorithm)
 	cmdAddHosts := fmt.Sprintf(RemoteAddEtcHosts, apiServerHost, apiServerHost)
 	joinCommands := []string{cmdAddRegistryHosts, certCMD, cmdAddHosts}
-	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
 	if cf.Username != "" && cf.Password != "" {
-		joinCommands = append(joinCommands, fmt.Sprintf(DockerLoginCommand, cf.Domain+":"+cf.Port, cf.Username, cf.Password))
+		cr, err := k.getContainerRuntime()
+		if err != nil {
+			return nil, err
+		}
+		joinCommands = append(joinCommands, cr.LoginRegistryCmd(cf))
 	}
 	cmdUpdateHosts := fmt.Sprintf(RemoteUpdateEtcHosts, apiServerHost,
 		getAPIServerHost(master, k.getAPIServerDomain()))
 
-	return append(joinCommands, joinCmd, cmdUpdateHosts, RemoteCopyKubeConfig)
+	joinCommands = append(joinCommands, joinCmd, cmdUpdateHosts, RemoteCopyKubeConfig)
+	return append(joinCommands, k.getHAMasterCmds()...), nil
 }
 
 func (k *KubeadmRuntime) sendKubeConfigFile(hosts []string, kubeFile string) error {
@@ -150,13 +161,24 @@ func (k *KubeadmRuntime) sendNewCertAndKey(hosts []string) error {
 	return k.sendFileToHosts(hosts, k.getPKIPath(), cert.KubeDefaultCertPath)
 }
 
-func (k *KubeadmRuntime) sendRegistryCertAndKey() error {
-	return k.sendFileToHosts(k.GetMasterIPList()[:1], k.getCertsDir(), filepath.Join(k.getRootfs(), "certs"))
+func (k *KubeadmRuntime) sendRegistryCertAndKey(hosts []string) error {
+	return k.sendFileToHosts(hosts, k.getCertsDir(), filepath.Join(k.getRootfs(), "certs"))
 }
 
+// sendRegistryCert send the registry cert to hosts and let the container runtime trust it.
 func (k *KubeadmRuntime) sendRegistryCert(host []string) error {
 	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
-	return k.sendFileToHosts(host, fmt.Sprintf("%s/%s.crt", k.getCertsDir(), cf.Domain), fmt.Sprintf("%s/%s:%s/%s.crt", DockerCertDir, cf.Domain, cf.Port, cf.Domain))
+	cr, err := k.getContainerRuntime()
+	if err != nil {
+		return err
+	}
+	if err := k.sendFileToHosts(host, fmt.Sprintf("%s/%s.crt", k.getCertsDir(), cf.Domain), registryCertPath(cr, cf)); err != nil {
+		return err
+	}
+	if trustCmd := cr.TrustRegistryCmd(cf); trustCmd != "" {
+		return k.CmdAsyncHosts(host, trustCmd)
+	}
+	return nil
 }
 
 func (k *KubeadmRuntime) sendFileToHosts(Hosts []string, src, dst string) error {
@@ -214,13 +236,18 @@ func (k *KubeadmRuntime) joinMasterConfig(masterIP string) ([]byte, error) {
 	k.Lock()
 	defer k.Unlock()
 	// TODO Using join file instead template
-	k.setAPIServerEndpoint(fmt.Sprintf("%s:6443", k.GetMaster0IP()))
+	k.setAPIServerEndpoint(net.JoinHostPort(k.GetMaster0IP(), "6443"))
 	k.setJoinAdvertiseAddress(masterIP)
 	cGroupDriver, err := k.getCgroupDriverFromShell(masterIP)
 	if err != nil {
 		return nil, err
 	}
 	k.setCgroupDriver(cGroupDriver)
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return nil, err
+	}
+	k.setCRISocket(criSocket)
 	return utils.MarshalYamlConfigs(k.JoinConfiguration, k.KubeletConfiguration)
 }
 
@@ -257,8 +284,9 @@ func (k *KubeadmRuntime) CmdAsyncHosts(hosts []string, cmd string) error {
 			ssh, err := k.getHostSSHClient(host)
 			if err != nil {
 				logger.Error("exec command failed %s %s %v", host, cmd, err)
+				return err
 			}
-			if err := ssh.CmdAsync(host, cmd); err != nil {
+			if err = ssh.CmdAsync(host, cmd); err != nil {
 				logger.Error("exec command failed %s %s %v", host, cmd, err)
 			}
 			return err
@@ -278,8 +306,8 @@ func (k *KubeadmRuntime) Command(version string, name CommandType) (cmd string)
 	// "kubeadm config migrate" command of kubeadm v1.15.x, so v1.14 not support multi network interface.
 	cmds := map[CommandType]string{
 		InitMaster: fmt.Sprintf(InitMaster115Lower, k.getRootfs()),
-		JoinMaster: fmt.Sprintf(JoinMaster115Lower, k.GetMaster0IP(), k.getJoinToken(), k.getTokenCaCertHash(), k.getCertificateKey()),
-		JoinNode:   fmt.Sprintf(JoinNode115Lower, k.getVIP(), k.getJoinToken(), k.getTokenCaCertHash()),
+		JoinMaster: fmt.Sprintf(JoinMaster115Lower, net.JoinHostPort(k.GetMaster0IP(), "6443"), k.getJoinToken(), k.getTokenCaCertHash(), k.getCertificateKey()),
+		JoinNode:   fmt.Sprintf(JoinNode115Lower, net.JoinHostPort(k.getVIP(), "6443"), k.getJoinToken(), k.getTokenCaCertHash()),
 	}
 	//other version >= 1.15.x
 	if VersionCompare(version, V1150) {
@@ -327,6 +355,9 @@ func (k *KubeadmRuntime) joinMasters(masters []string) error {
 	if err := k.sendRegistryCert(masters); err != nil {
 		return err
 	}
+	if err := k.startNewRegistries(masters); err != nil {
+		return err
+	}
 	// TODO only needs send ca?
 	if err := k.sendNewCertAndKey(masters); err != nil {
 		return err
@@ -340,25 +371,42 @@ func (k *KubeadmRuntime) joinMasters(masters []string) error {
 		return fmt.Errorf("get join master command failed, kubernetes version is %s", k.getKubeVersion())
 	}
 
-	for _, master := range masters {
-		logger.Info("Start to join %s as master", master)
-
-		hostname, err := k.getRemoteHostName(master)
-		if err != nil {
-			return err
-		}
-		cmds := k.JoinMasterCommands(master, cmd, hostname)
-		ssh, err := k.getHostSSHClient(master)
-		if err != nil {
-			return err
+	for i, master := range masters {
+		if err := k.joinMaster(master, cmd); err != nil {
+			// masters are joined one by one, the rest are not tried.
+			return &HostsError{Succeeded: masters[:i], Failed: map[string]error{master: err}}
 		}
+	}
+	if err := k.resolveNewRegistries(masters); err != nil {
+		return err
+	}
+	if len(k.GetEtcdIPList()) != 0 {
+		return nil
+	}
+	return k.scheduleEtcdBackupOn(masters)
+}
 
-		if err := ssh.CmdAsync(master, cmds...); err != nil {
-			return fmt.Errorf("exec command failed %s %v %v", master, cmds, err)
-		}
+func (k *KubeadmRuntime) joinMaster(master, cmd string) error {
+	logger.Info("Start to join %s as master", master)
 
-		logger.Info("Succeeded in joining %s as master", master)
+	hostname, err := k.getRemoteHostName(master)
+	if err != nil {
+		return err
 	}
+	cmds, err := k.JoinMasterCommands(master, cmd, hostname)
+	if err != nil {
+		return err
+	}
+	ssh, err := k.getHostSSHClient(master)
+	if err != nil {
+		return err
+	}
+
+	if err := ssh.CmdAsync(master, cmds...); err != nil {
+		return fmt.Errorf("exec command failed %s %v %v", master, cmds, err)
+	}
+
+	logger.Info("Succeeded in joining %s as master", master)
 	return nil
 }
 
@@ -366,13 +414,30 @@ func (k *KubeadmRuntime) deleteMasters(masters []string) error {
 	if len(masters) == 0 {
 		return nil
 	}
+	// the kubeadm config is needed by the lvscare static pods updated on workers.
+	if err := k.MergeKubeadmConfig(); err != nil {
+		return err
+	}
+	mastersLeft := utils.RemoveStrSlice(k.GetMasterIPList(), masters)
+	// the images are pulled from the registries left before the masters are deleted.
+	if err := k.migrateRegistry(masters, mastersLeft); err != nil {
+		return err
+	}
+	if len(mastersLeft) != 0 && utils.InList(k.GetMaster0IP(), masters) {
+		// the kubeconfig of sealer is used through master0, which is the first master left after it is deleted.
+		logger.Info("master0 %s is deleted, %s will be the new master0", k.GetMaster0IP(), mastersLeft[0])
+		if err := updateSealerAPIServer(getAPIServerHost(k.GetMaster0IP(), k.getAPIServerDomain()),
+			getAPIServerHost(mastersLeft[0], k.getAPIServerDomain())); err != nil {
+			return err
+		}
+	}
 	eg, _ := errgroup.WithContext(context.Background())
 	for _, master := range masters {
 		master := master
 		eg.Go(func() error {
 			master := master
 			logger.Info("Start to delete master %s", master)
-			if err := k.deleteMaster(master); err != nil {
+			if err := k.deleteMaster(master, mastersLeft); err != nil {
 				logger.Error("delete master %s failed %v", master, err)
 			} else {
 				logger.Info("Succeeded in deleting master %s", master)
@@ -383,6 +448,23 @@ func (k *KubeadmRuntime) deleteMasters(masters []string) error {
 	return eg.Wait()
 }
 
+// updateSealerAPIServer replace the apiserver host of master0 in the /etc/hosts of sealer with the one of new master0,
+// the host of new master0 is added even if the one of master0 is missing.
+func updateSealerAPIServer(old, new string) error {
+	if _, err := utils.RunSimpleCmd(updateSealerAPIServerCmd(old, new)); err != nil {
+		return fmt.Errorf("failed to update the apiserver of sealer to %s: %v", new, err)
+	}
+	return nil
+}
+
+func updateSealerAPIServerCmd(old, new string) string {
+	escape := func(host string) string {
+		return strings.ReplaceAll(host, ".", `\.`)
+	}
+	return fmt.Sprintf(`sed -i "/^%s\s*$/d" /etc/hosts && (grep -q "^%s\s*$" /etc/hosts || echo "%s" >> /etc/hosts)`,
+		escape(old), escape(new), new)
+}
+
 func SliceRemoveStr(ss []string, s string) (result []string) {
 	for _, v := range ss {
 		if v != s {
@@ -418,21 +500,28 @@ func (k *KubeadmRuntime) isHostName(master, host string) (string, error) {
 	return name, nil
 }
 
-func (k *KubeadmRuntime) deleteMaster(master string) error {
+// deleteMaster reset the master and delete its node through the first of masterIPs, which are the masters left.
+func (k *KubeadmRuntime) deleteMaster(master string, masterIPs []string) error {
 	ssh, err := k.getHostSSHClient(master)
 	if err != nil {
 		return fmt.Errorf("failed to delete master: %v", err)
 	}
+	cr, err := k.getContainerRuntime()
+	if err != nil {
+		return err
+	}
 	remoteCleanCmd := []string{fmt.Sprintf(RemoteCleanMasterOrNode, vlogToStr(k.Vlog)),
-		fmt.Sprintf(RemoteRemoveAPIServerEtcHost, getRegistryHost(k.getRootfs(), k.GetMaster0IP())),
+		RemoteRemoveEtcdBackup,
+		cr.StopRegistryCmd(),
+		k.removeRegistryHostsCmd(),
 		fmt.Sprintf(RemoteRemoveAPIServerEtcHost, k.getAPIServerDomain())}
 
 	//if the master to be removed is the execution machine, kubelet and ~./kube will not be removed and ApiServer host will be added.
 	address, err := utils.GetLocalHostAddresses()
 	if err != nil || !utils.IsLocalIP(master, address) {
 		remoteCleanCmd = append(remoteCleanCmd, RemoveKubeConfig)
-	} else {
-		apiServerHost := getAPIServerHost(k.GetMaster0IP(), k.getAPIServerDomain())
+	} else if len(masterIPs) > 0 {
+		apiServerHost := getAPIServerHost(masterIPs[0], k.getAPIServerDomain())
 		remoteCleanCmd = append(remoteCleanCmd,
 			fmt.Sprintf(RemoteAddEtcHosts, apiServerHost, apiServerHost))
 	}
@@ -441,29 +530,36 @@ func (k *KubeadmRuntime) deleteMaster(master string) error {
 	}
 
 	//remove master
-	masterIPs := SliceRemoveStr(k.GetMasterIPList(), master)
 	if len(masterIPs) > 0 {
-		hostname, err := k.isHostName(k.GetMaster0IP(), master)
+		hostname, err := k.isHostName(masterIPs[0], master)
 		if err != nil {
 			return err
 		}
-		master0SSH, err := k.getHostSSHClient(k.GetMaster0IP())
+		master0SSH, err := k.getHostSSHClient(masterIPs[0])
 		if err != nil {
 			return fmt.Errorf("failed to remove master ip: %v", err)
 		}
 
-		if err := master0SSH.CmdAsync(k.GetMaster0IP(), fmt.Sprintf(KubeDeleteNode, strings.TrimSpace(hostname))); err != nil {
+		if err := master0SSH.CmdAsync(masterIPs[0], fmt.Sprintf(KubeDeleteNode, strings.TrimSpace(hostname))); err != nil {
 			return fmt.Errorf("delete node %s failed %v", hostname, err)
 		}
 	}
-	yaml := ipvs.LvsStaticPodYaml(k.getVIP(), masterIPs, "")
+	if !k.isLvscareMode() {
+		return nil
+	}
 	eg, _ := errgroup.WithContext(context.Background())
 	for _, node := range k.GetNodeIPList() {
 		node := node
 		eg.Go(func() error {
+			yaml, err := k.getLvscareStaticPodYaml(node, masterIPs)
+			if err != nil {
+				logger.Error("update lvscare static pod failed %s %v", node, err)
+				return err
+			}
 			ssh, err := k.getHostSSHClient(node)
 			if err != nil {
 				logger.Error("update lvscare static pod failed %s %v", node, err)
+				return err
 			}
 			if err := ssh.CmdAsync(node, RemoveLvscareStaticPod, fmt.Sprintf(CreateLvscareStaticPod, yaml)); err != nil {
 				logger.Error("update lvscare static pod failed %s %v", node, err)
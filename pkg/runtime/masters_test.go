@@ -0,0 +1,105 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/cert"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+func TestJoinMasterCommands(t *testing.T) {
+	defer func() {
+		if err := cert.SetOptions("100y", "100y", string(cert.RSA2048)); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	tests := []struct {
+		name    string
+		env     []string
+		want    string
+		notWant string
+	}{
+		{
+			name:    "default cert options",
+			notWant: "validity",
+		},
+		{
+			name: "cert options of cluster env",
+			env:  []string{"CertCAValidity=10y", "CertValidity=1y", "CertKeyAlgorithm=ECDSA-P256"},
+			want: "--ca-validity 10y --cert-validity 1y --key-algorithm ECDSA-P256",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &v2.Cluster{}
+			cluster.Name = "join-master-test"
+			cluster.Spec.Env = tt.env
+			cluster.Spec.Hosts = []v2.Host{{IPS: []string{"192.168.0.2", "192.168.0.3"}, Roles: []string{common.MASTER}}}
+			r, err := newKubeadmRuntime(cluster, nil)
+			if err != nil {
+				t.Fatalf("newKubeadmRuntime() error = %v", err)
+			}
+			cmds, err := r.(*KubeadmRuntime).JoinMasterCommands("192.168.0.3", "kubeadm join", "master1")
+			if err != nil {
+				t.Fatalf("JoinMasterCommands() error = %v", err)
+			}
+			cmd := strings.Join(cmds, "\n")
+			if !strings.Contains(cmd, "seautil certs") {
+				t.Fatalf("JoinMasterCommands() = %s, want the command generating certs", cmd)
+			}
+			if tt.want != "" && !strings.Contains(cmd, tt.want) {
+				t.Errorf("JoinMasterCommands() = %s, want %s", cmd, tt.want)
+			}
+			if tt.notWant != "" && strings.Contains(cmd, tt.notWant) {
+				t.Errorf("JoinMasterCommands() = %s, should not contain %s", cmd, tt.notWant)
+			}
+		})
+	}
+}
+
+func TestUpdateSealerAPIServerCmd(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{
+			name: "ipv4",
+			old:  "192.168.0.2 apiserver.cluster.local",
+			new:  "192.168.0.3 apiserver.cluster.local",
+			want: `sed -i "/^192\.168\.0\.2 apiserver\.cluster\.local\s*$/d" /etc/hosts && ` +
+				`(grep -q "^192\.168\.0\.3 apiserver\.cluster\.local\s*$" /etc/hosts || echo "192.168.0.3 apiserver.cluster.local" >> /etc/hosts)`,
+		},
+		{
+			name: "ipv6",
+			old:  "fd00::2 apiserver.cluster.local",
+			new:  "fd00::3 apiserver.cluster.local",
+			want: `sed -i "/^fd00::2 apiserver\.cluster\.local\s*$/d" /etc/hosts && ` +
+				`(grep -q "^fd00::3 apiserver\.cluster\.local\s*$" /etc/hosts || echo "fd00::3 apiserver.cluster.local" >> /etc/hosts)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := updateSealerAPIServerCmd(tt.old, tt.new); got != tt.want {
+				t.Errorf("updateSealerAPIServerCmd() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,7 +17,9 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
+	"sync"
 
 	"github.com/alibaba/sealer/utils"
 	"golang.org/x/sync/errgroup"
@@ -25,11 +27,10 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/alibaba/sealer/logger"
-	"github.com/alibaba/sealer/pkg/ipvs"
 )
 
 const (
-	RemoteAddIPVS                   = "seautil ipvs --vs %s:6443 %s --health-path /healthz --health-schem https --run-once"
+	RemoteAddIPVS                   = "seautil ipvs --vs %s %s --health-path /healthz --health-schem https --run-once"
 	RemoteStaticPodMkdir            = "mkdir -p /etc/kubernetes/manifests"
 	RemoteJoinConfig                = `echo "%s" > %s/etc/kubeadm.yml`
 	LvscareDefaultStaticPodFileName = "/etc/kubernetes/manifests/kube-lvscare.yaml"
@@ -42,12 +43,17 @@ const (
 
 func (k *KubeadmRuntime) joinNodeConfig(nodeIP string) ([]byte, error) {
 	// TODO get join config from config file
-	k.setAPIServerEndpoint(fmt.Sprintf("%s:6443", k.getVIP()))
+	k.setAPIServerEndpoint(net.JoinHostPort(k.getVIP(), "6443"))
 	cGroupDriver, err := k.getCgroupDriverFromShell(nodeIP)
 	if err != nil {
 		return nil, err
 	}
 	k.setCgroupDriver(cGroupDriver)
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return nil, err
+	}
+	k.setCRISocket(criSocket)
 	return utils.MarshalYamlConfigs(k.JoinConfiguration, k.KubeletConfiguration)
 }
 
@@ -58,61 +64,121 @@ func (k *KubeadmRuntime) joinNodes(nodes []string) error {
 	if err := k.MergeKubeadmConfig(); err != nil {
 		return err
 	}
-	if err := k.WaitSSHReady(6, nodes...); err != nil {
-		return errors.Wrap(err, "join nodes wait for ssh ready time out")
-	}
-	if err := k.sendRegistryCert(nodes); err != nil {
-		return err
-	}
 	if err := k.GetJoinTokenHashAndKey(); err != nil {
 		return err
 	}
 	var masters string
-	eg, _ := errgroup.WithContext(context.Background())
 	for _, master := range k.GetMasterIPList() {
-		masters += fmt.Sprintf(" --rs %s:6443", master)
+		masters += fmt.Sprintf(" --rs %s", net.JoinHostPort(master, "6443"))
 	}
-	ipvsCmd := fmt.Sprintf(RemoteAddIPVS, k.getVIP(), masters)
+	ipvsCmd := fmt.Sprintf(RemoteAddIPVS, net.JoinHostPort(k.getVIP(), "6443"), masters)
 
-	k.setAPIServerEndpoint(fmt.Sprintf("%s:6443", k.getVIP()))
+	k.setAPIServerEndpoint(net.JoinHostPort(k.getVIP(), "6443"))
 	k.cleanJoinLocalAPIEndPoint()
 
-	registryHost := getRegistryHost(k.getImageMountDir(), k.GetMaster0IP())
-	addRegistryHostsAndLogin := fmt.Sprintf(RemoteAddEtcHosts, registryHost, registryHost)
-	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
-	if cf.Username != "" && cf.Password != "" {
-		addRegistryHostsAndLogin = fmt.Sprintf("%s && %s", addRegistryHostsAndLogin, fmt.Sprintf(DockerLoginCommand, cf.Domain+":"+cf.Port, cf.Username, cf.Password))
+	addRegistryHostsAndLogin, err := k.getRegistryHostsAndLoginCmd()
+	if err != nil {
+		return err
 	}
-	for _, node := range nodes {
-		node := node
-		eg.Go(func() error {
-			logger.Info("Start to join %s as worker", node)
-			err := k.checkMultiNetworkAddVIPRoute(node)
-			if err != nil {
-				return fmt.Errorf("failed to check multi network: %v", err)
-			}
-			// send join node config, get cgroup driver on every join nodes
-			joinConfig, err := k.joinNodeConfig(node)
-			if err != nil {
-				return fmt.Errorf("failed to join node %s %v", node, err)
-			}
-			cmdWriteJoinConfig := fmt.Sprintf(RemoteJoinConfig, string(joinConfig), k.getRootfs())
-			cmdHosts := fmt.Sprintf(RemoteAddIPVSEtcHosts, k.getVIP(), k.getAPIServerDomain())
-			cmd := k.Command(k.getKubeVersion(), JoinNode)
-			yaml := ipvs.LvsStaticPodYaml(k.getVIP(), k.GetMasterIPList(), "")
-			lvscareStaticCmd := fmt.Sprintf(LvscareStaticPodCmd, yaml, LvscareDefaultStaticPodFileName)
-			ssh, err := k.getHostSSHClient(node)
+	// an unreachable node fails alone, it is counted in the failed nodes instead of failing all of them.
+	return RunInParallel(nodes, func(node string) error {
+		if err := k.WaitSSHReady(6, node); err != nil {
+			return errors.Wrap(err, "join node wait for ssh ready time out")
+		}
+		if err := k.sendRegistryCert([]string{node}); err != nil {
+			return err
+		}
+		return k.joinNode(node, addRegistryHostsAndLogin, ipvsCmd)
+	})
+}
+
+// RunInParallel run f on at most Parallelism hosts at the same time,
+// a HostsError is returned if any of them failed.
+func RunInParallel(hosts []string, f func(host string) error) error {
+	var (
+		mu       sync.Mutex
+		hostsErr = &HostsError{Failed: map[string]error{}}
+		result   = func(host string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
-				return fmt.Errorf("failed to join node %s %v", node, err)
+				hostsErr.Failed[host] = err
+				return
 			}
-			if err := ssh.CmdAsync(node, addRegistryHostsAndLogin, cmdWriteJoinConfig, cmdHosts, ipvsCmd, cmd, RemoteStaticPodMkdir, lvscareStaticCmd); err != nil {
-				return fmt.Errorf("failed to join node %s %v", node, err)
+			hostsErr.Succeeded = append(hostsErr.Succeeded, host)
+		}
+	)
+	var limit chan struct{}
+	if Parallelism > 0 {
+		limit = make(chan struct{}, Parallelism)
+	}
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, host := range hosts {
+		host := host
+		eg.Go(func() error {
+			if limit != nil {
+				limit <- struct{}{}
+				defer func() { <-limit }()
 			}
-			logger.Info("Succeeded in joining %s as worker", node)
+			err := f(host)
+			result(host, err)
 			return err
 		})
 	}
-	return eg.Wait()
+	if err := eg.Wait(); err != nil {
+		return hostsErr
+	}
+	return nil
+}
+
+// getRegistryHostsAndLoginCmd return the command to resolve the registry domain and login to it if auth is enabled.
+func (k *KubeadmRuntime) getRegistryHostsAndLoginCmd() (string, error) {
+	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
+	cmd := getRegistryHostsCmd(cf.Domain, GetRegistryHosts(k.Cluster, cf))
+	if cf.Username != "" && cf.Password != "" {
+		cr, err := k.getContainerRuntime()
+		if err != nil {
+			return "", err
+		}
+		cmd = fmt.Sprintf("%s && %s", cmd, cr.LoginRegistryCmd(cf))
+	}
+	return cmd, nil
+}
+
+func (k *KubeadmRuntime) joinNode(node, addRegistryHostsAndLogin, ipvsCmd string) error {
+	logger.Info("Start to join %s as worker", node)
+	if k.isLvscareMode() {
+		if err := k.checkMultiNetworkAddVIPRoute(node); err != nil {
+			return fmt.Errorf("failed to check multi network: %v", err)
+		}
+	}
+	// send join node config, get cgroup driver on every join nodes
+	joinConfig, err := k.joinNodeConfig(node)
+	if err != nil {
+		return fmt.Errorf("failed to join node %s %v", node, err)
+	}
+	cmdWriteJoinConfig := fmt.Sprintf(RemoteJoinConfig, string(joinConfig), k.getRootfs())
+	cmdHosts := fmt.Sprintf(RemoteAddIPVSEtcHosts, k.getVIP(), k.getAPIServerDomain())
+	cmd := k.Command(k.getKubeVersion(), JoinNode)
+	cmds := []string{addRegistryHostsAndLogin, cmdWriteJoinConfig, cmdHosts, cmd}
+	// the workers reach the vip of kube-vip or the external load balancer directly.
+	if k.isLvscareMode() {
+		yaml, err := k.getLvscareStaticPodYaml(node, k.GetMasterIPList())
+		if err != nil {
+			return fmt.Errorf("failed to join node %s %v", node, err)
+		}
+		lvscareStaticCmd := fmt.Sprintf(LvscareStaticPodCmd, yaml, LvscareDefaultStaticPodFileName)
+		cmds = []string{addRegistryHostsAndLogin, cmdWriteJoinConfig, cmdHosts, ipvsCmd, cmd, RemoteStaticPodMkdir, lvscareStaticCmd}
+	}
+	ssh, err := k.getHostSSHClient(node)
+	if err != nil {
+		return fmt.Errorf("failed to join node %s %v", node, err)
+	}
+	if err := ssh.CmdAsync(node, cmds...); err != nil {
+		return fmt.Errorf("failed to join node %s %v", node, err)
+	}
+	logger.Info("Succeeded in joining %s as worker", node)
+	return nil
 }
 
 func (k *KubeadmRuntime) deleteNodes(nodes []string) error {
@@ -127,9 +193,10 @@ func (k *KubeadmRuntime) deleteNodes(nodes []string) error {
 			if err := k.deleteNode(node); err != nil {
 				return fmt.Errorf("delete node %s failed %v", node, err)
 			}
-			err := k.deleteVIPRouteIfExist(node)
-			if err != nil {
-				return fmt.Errorf("failed to delete %s route: %v", node, err)
+			if k.isLvscareMode() {
+				if err := k.deleteVIPRouteIfExist(node); err != nil {
+					return fmt.Errorf("failed to delete %s route: %v", node, err)
+				}
 			}
 			logger.Info("Succeeded in deleting worker %s", node)
 			return nil
@@ -145,7 +212,8 @@ func (k *KubeadmRuntime) deleteNode(node string) error {
 	}
 
 	remoteCleanCmds := []string{fmt.Sprintf(RemoteCleanMasterOrNode, vlogToStr(k.Vlog)),
-		fmt.Sprintf(RemoteRemoveAPIServerEtcHost, getRegistryHost(k.getRootfs(), k.GetMaster0IP())),
+		RemoteRemoveEtcdBackup,
+		k.removeRegistryHostsCmd(),
 		fmt.Sprintf(RemoteRemoveAPIServerEtcHost, k.getAPIServerDomain())}
 	address, err := utils.GetLocalHostAddresses()
 	//if the node to be removed is the execution machine, kubelet, ~./kube and ApiServer host will be added
@@ -164,6 +232,10 @@ func (k *KubeadmRuntime) deleteNode(node string) error {
 		if err != nil {
 			return err
 		}
+		if strings.TrimSpace(hostname) == "" {
+			logger.Warn("node %s is not found in the cluster, skip deleting it", node)
+			return nil
+		}
 		ssh, err := k.getHostSSHClient(k.GetMaster0IP())
 		if err != nil {
 			return fmt.Errorf("failed to delete node on master0,%v", err)
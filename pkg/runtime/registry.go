@@ -15,14 +15,22 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/alibaba/sealer/common"
 
 	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/env"
+	v2 "github.com/alibaba/sealer/types/api/v2"
 	"github.com/alibaba/sealer/utils"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -32,7 +40,12 @@ const (
 	RegistryCustomConfig        = "registry.yml"
 	SeaHub                      = "sea.hub"
 	DefaultRegistryHtPasswdFile = "registry_htpasswd"
-	DockerLoginCommand          = "docker login %s -u %s -p %s"
+	// RegistryHA is the cluster env key, if it is true a registry runs on every master instead of the ip of registry config.
+	RegistryHA                   = "RegistryHA"
+	RemoteRemoveRegistryEtcHosts = `sed -i "/ %s$/d" /etc/hosts`
+	RemoteArchiveRegistry        = `tar -czf %s -C %s %s`
+	RemoteExtractRegistry        = `mkdir -p %[2]s && tar -xzf %[1]s -C %[2]s && rm -f %[1]s`
+	registryArchive              = "registry.tar.gz"
 )
 
 type RegistryConfig struct {
@@ -43,43 +56,199 @@ type RegistryConfig struct {
 	Password string `json:"password,omitempty"`
 }
 
-func getRegistryHost(rootfs, defaultRegistry string) (host string) {
-	cf := GetRegistryConfig(rootfs, defaultRegistry)
-	ip, _ := utils.GetSSHHostIPAndPort(cf.IP)
-	return fmt.Sprintf("%s %s", ip, cf.Domain)
+// GetRegistryHosts return the hosts running the registry, all the masters if RegistryHA is true in cluster env,
+// each of them has the registry dir of ClusterImage.
+func GetRegistryHosts(cluster *v2.Cluster, config *RegistryConfig) []string {
+	// the env failed to resolve stops the apply by env.Validate.
+	getEnv, _ := env.StringGetter(cluster.Spec.Env)
+	if getEnv(RegistryHA) == "true" {
+		return GetMasterIPList(cluster)
+	}
+	return []string{config.IP}
+}
+
+// getRegistryHostsCmd return the command to resolve the registry domain to all the registries, docker and containerd
+// try them in order, so the images are still pulled if some of them are down.
+func getRegistryHostsCmd(domain string, registries []string) string {
+	cmds := []string{fmt.Sprintf(RemoteRemoveRegistryEtcHosts, domain)}
+	for _, registry := range registries {
+		ip, _ := utils.GetSSHHostIPAndPort(registry)
+		cmds = append(cmds, fmt.Sprintf(RemoteAddIPVSEtcHosts, ip, domain))
+	}
+	return strings.Join(cmds, " && ")
+}
+
+// removeRegistryHostsCmd return the command to remove the registry domain from /etc/hosts.
+func (k *KubeadmRuntime) removeRegistryHostsCmd() string {
+	return fmt.Sprintf(RemoteRemoveRegistryEtcHosts, GetRegistryConfig(k.getRootfs(), k.GetMaster0IP()).Domain)
 }
 
 // ApplyRegistry Only use this for join and init, due to the initiation operations.
 func (k *KubeadmRuntime) ApplyRegistry() error {
 	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
-	ssh, err := k.getHostSSHClient(cf.IP)
+	registries := GetRegistryHosts(k.Cluster, cf)
+	if err := k.startRegistries(cf, registries); err != nil {
+		return err
+	}
+	ssh, err := k.getHostSSHClient(k.GetMaster0IP())
 	if err != nil {
-		return fmt.Errorf("failed to get registry ssh client: %v", err)
+		return fmt.Errorf("failed to get master0 ssh client: %v", err)
+	}
+	if err = ssh.CmdAsync(k.GetMaster0IP(), getRegistryHostsCmd(cf.Domain, registries)); err != nil {
+		return err
+	}
+	cr, err := k.getContainerRuntime()
+	if err != nil {
+		return err
+	}
+	login := cr.LoginRegistryCmd(cf)
+	if cf.Username == "" || cf.Password == "" || login == "" {
+		return nil
 	}
+	return ssh.CmdAsync(k.GetMaster0IP(), login)
+}
 
+// startRegistries start the registry with the cert and key of it on hosts, the registry dir is copied to them
+// when the rootfs is mounted.
+func (k *KubeadmRuntime) startRegistries(cf *RegistryConfig, hosts []string) error {
+	if err := k.sendRegistryCertAndKey(hosts); err != nil {
+		return err
+	}
+	var htpasswd string
 	if cf.Username != "" && cf.Password != "" {
-		htpasswd, err := cf.GenerateHtPasswd()
-		if err != nil {
+		var err error
+		if htpasswd, err = cf.GenerateHtPasswd(); err != nil {
 			return err
 		}
-		err = ssh.CmdAsync(cf.IP, fmt.Sprintf("echo '%s' > %s", htpasswd, filepath.Join(k.getRootfs(), "etc", DefaultRegistryHtPasswdFile)))
-		if err != nil {
+	}
+	cr, err := k.getContainerRuntime()
+	if err != nil {
+		return err
+	}
+	initRegistry := cr.StartRegistryCmd(k.getRootfs(), cf)
+	eg, _ := errgroup.WithContext(context.Background())
+	for _, host := range hosts {
+		host := host
+		eg.Go(func() error {
+			ssh, err := k.getHostSSHClient(host)
+			if err != nil {
+				return fmt.Errorf("failed to get registry ssh client: %v", err)
+			}
+			if htpasswd != "" {
+				err = ssh.CmdAsync(host, fmt.Sprintf("echo '%s' > %s", htpasswd, filepath.Join(k.getRootfs(), "etc", DefaultRegistryHtPasswdFile)))
+				if err != nil {
+					return err
+				}
+			}
+			if err := ssh.CmdAsync(host, initRegistry); err != nil {
+				return fmt.Errorf("failed to start registry on %s: %v", host, err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// startNewRegistries start the registries on the masters to join if RegistryHA is true.
+func (k *KubeadmRuntime) startNewRegistries(masters []string) error {
+	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
+	registries := GetRegistryHosts(k.Cluster, cf)
+	return k.startRegistries(cf, utils.RemoveStrSlice(registries, utils.RemoveStrSlice(registries, masters)))
+}
+
+// resolveNewRegistries let the hosts other than the joined masters pull from the registries on them as well.
+func (k *KubeadmRuntime) resolveNewRegistries(masters []string) error {
+	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
+	registries := GetRegistryHosts(k.Cluster, cf)
+	if len(utils.RemoveStrSlice(registries, masters)) == len(registries) {
+		return nil
+	}
+	hosts := append(append(k.GetMasterIPList(), k.GetNodeIPList()...), k.GetEtcdIPList()...)
+	return k.CmdAsyncHosts(utils.RemoveStrSlice(hosts, masters), getRegistryHostsCmd(cf.Domain, registries))
+}
+
+// migrateRegistry move the registry off the masters to be deleted, the only registry is started again on the first
+// master left with the registry dir copied from the old one, or the one of ClusterImage if the old one is unreachable,
+// and the registry domain of the hosts left is resolved to the registries left.
+func (k *KubeadmRuntime) migrateRegistry(deleted, mastersLeft []string) error {
+	if len(mastersLeft) == 0 {
+		return nil
+	}
+	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
+	registries := GetRegistryHosts(k.Cluster, cf)
+	registriesLeft := utils.RemoveStrSlice(registries, deleted)
+	if len(registriesLeft) == len(registries) {
+		return nil
+	}
+	if len(registriesLeft) == 0 {
+		host := mastersLeft[0]
+		logger.Info("Start to migrate the registry from %s to %s", registries, host)
+		if cf.IP != k.GetMaster0IP() {
+			logger.Warn("the registry is moved to %s, the ip in %s should be updated", host, RegistryCustomConfig)
+		}
+		if err := k.copyRegistryDir(registries[0], host); err != nil {
+			return err
+		}
+		if err := k.startRegistries(cf, []string{host}); err != nil {
 			return err
 		}
+		registriesLeft = []string{host}
 	}
-	initRegistry := fmt.Sprintf("cd %s/scripts && sh init-registry.sh %s %s %s", k.getRootfs(), cf.Port, fmt.Sprintf("%s/registry", k.getRootfs()), cf.Domain)
-	registryHost := getRegistryHost(k.getImageMountDir(), k.GetMaster0IP())
-	addRegistryHosts := fmt.Sprintf(RemoteAddEtcHosts, registryHost, registryHost)
-	if err = ssh.CmdAsync(cf.IP, initRegistry); err != nil {
+	hosts := append(append(k.GetMasterIPList(), k.GetNodeIPList()...), k.GetEtcdIPList()...)
+	return k.CmdAsyncHosts(utils.RemoveStrSlice(hosts, deleted), getRegistryHostsCmd(cf.Domain, registriesLeft))
+}
+
+// copyRegistryDir copy the registry dir of rootfs from the old registry host through local host, so the images pushed
+// after the ClusterImage is applied are kept. The one of ClusterImage is used if the old host is unreachable.
+func (k *KubeadmRuntime) copyRegistryDir(old, host string) error {
+	ssh, err := k.getHostSSHClient(host)
+	if err != nil {
 		return err
 	}
-	if err = ssh.CmdAsync(k.GetMaster0IP(), addRegistryHosts); err != nil {
-		return err
+	dst := filepath.Join(k.getRootfs(), common.RegistryDirName)
+	oldIP, _ := utils.GetSSHHostIPAndPort(old)
+	oldSSH, err := k.getHostSSHClient(oldIP)
+	if err == nil {
+		err = oldSSH.Ping(oldIP)
 	}
-	if cf.Username == "" || cf.Password == "" {
+	if err != nil {
+		logger.Warn("registry host %s is unreachable, the registry dir of ClusterImage is used, the images pushed after apply are lost: %v", oldIP, err)
+		src := filepath.Join(k.getImageMountDir(), common.RegistryDirName)
+		if !utils.IsExist(src) {
+			return fmt.Errorf("failed to migrate registry: %s of ClusterImage is not found", src)
+		}
+		if err := ssh.Copy(host, src, dst); err != nil {
+			return fmt.Errorf("failed to copy registry dir to %s: %v", host, err)
+		}
 		return nil
 	}
-	return ssh.CmdAsync(k.GetMaster0IP(), fmt.Sprintf(DockerLoginCommand, cf.Domain+":"+cf.Port, cf.Username, cf.Password))
+
+	tmpDir, err := ioutil.TempDir("", "sealer-registry")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			logger.Warn("failed to remove %s: %v", tmpDir, err)
+		}
+	}()
+	archive := filepath.Join(k.getBasePath(), registryArchive)
+	local := filepath.Join(tmpDir, registryArchive)
+	if err := oldSSH.CmdAsync(oldIP, fmt.Sprintf(RemoteArchiveRegistry, archive, k.getRootfs(), common.RegistryDirName)); err != nil {
+		return fmt.Errorf("failed to archive registry dir on %s: %v", oldIP, err)
+	}
+	defer func() {
+		if err := oldSSH.CmdAsync(oldIP, "rm -f "+archive); err != nil {
+			logger.Warn("failed to remove %s on %s: %v", archive, oldIP, err)
+		}
+	}()
+	if err := oldSSH.Fetch(oldIP, local, archive); err != nil {
+		return fmt.Errorf("failed to fetch registry dir from %s: %v", oldIP, err)
+	}
+	if err := ssh.Copy(host, local, archive); err != nil {
+		return fmt.Errorf("failed to copy registry dir to %s: %v", host, err)
+	}
+	return ssh.CmdAsync(host, fmt.Sprintf(RemoteExtractRegistry, archive, k.getRootfs()))
 }
 
 func (r *RegistryConfig) GenerateHtPasswd() (string, error) {
@@ -114,7 +283,7 @@ func GetRegistryConfig(rootfs, defaultRegistry string) *RegistryConfig {
 		config.IP = DefaultConfig.IP
 	} else {
 		ip, port := utils.GetSSHHostIPAndPort(config.IP)
-		config.IP = fmt.Sprintf("%s:%s", ip, port)
+		config.IP = net.JoinHostPort(ip, port)
 	}
 	if config.Port == "" {
 		config.Port = DefaultConfig.Port
@@ -128,11 +297,9 @@ func GetRegistryConfig(rootfs, defaultRegistry string) *RegistryConfig {
 
 func (k *KubeadmRuntime) DeleteRegistry() error {
 	cf := GetRegistryConfig(k.getRootfs(), k.GetMaster0IP())
-	ssh, err := k.getHostSSHClient(cf.IP)
+	cr, err := k.getContainerRuntime()
 	if err != nil {
-		return fmt.Errorf("failed to delete registry: %v", err)
+		return err
 	}
-
-	cmd := fmt.Sprintf("if docker inspect %s;then docker rm -f %s;fi", RegistryName, RegistryName)
-	return ssh.CmdAsync(cf.IP, cmd)
+	return k.CmdAsyncHosts(GetRegistryHosts(k.Cluster, cf), cr.StopRegistryCmd())
 }
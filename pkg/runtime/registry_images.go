@@ -0,0 +1,190 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/image/distributionutil"
+	"github.com/alibaba/sealer/utils"
+)
+
+// RegistryRepository is a repository in the registry of cluster and its tags.
+type RegistryRepository struct {
+	Repository string
+	Tags       []string
+}
+
+// getClusterRegistries return the registry config and the registries reached by ip from sealer, their certs are verified
+// by the registry cert in the certs dir of cluster. The auth of the registry config is used, or the auth of local docker
+// if the sealer host has logged in to the registry.
+func (k *KubeadmRuntime) getClusterRegistries() (*RegistryConfig, []distributionutil.ClusterRegistry) {
+	cf := GetRegistryConfig(k.getRootfs(), k.GetMaster0IP())
+	auth := types.AuthConfig{Username: cf.Username, Password: cf.Password}
+	if auth.Username == "" {
+		if dockerAuth, err := utils.GetDockerAuthInfoFromDocker(registryEndpoint(cf)); err == nil {
+			auth = dockerAuth
+		}
+	}
+	var registries []distributionutil.ClusterRegistry
+	for _, host := range GetRegistryHosts(k.Cluster, cf) {
+		ip, _ := utils.GetSSHHostIPAndPort(host)
+		registries = append(registries, distributionutil.ClusterRegistry{
+			Endpoint: net.JoinHostPort(ip, cf.Port),
+			Domain:   cf.Domain,
+			CertFile: filepath.Join(k.getCertsDir(), cf.Domain+".crt"),
+			Auth:     auth,
+		})
+	}
+	return cf, registries
+}
+
+// parseRegistryImageName return the repository and tag of the image name in the registry of cluster, the domain of
+// name is ignored, and the tag is latest if it is not set.
+func parseRegistryImageName(name string) (repo, tag string, err error) {
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid image name %s: %v", name, err)
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+	if !ok {
+		return "", "", fmt.Errorf("image name %s should be with a tag instead of digest", name)
+	}
+	return reference.Path(named), tagged.Tag(), nil
+}
+
+// listRegistryImages list the images in the first registry, the registries have the same images if RegistryHA is true.
+func (k *KubeadmRuntime) listRegistryImages() ([]RegistryRepository, error) {
+	ctx := context.Background()
+	_, registries := k.getClusterRegistries()
+	repos, err := distributionutil.ListRepositories(ctx, registries[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the repositories of registry %s: %v", registries[0].Endpoint, err)
+	}
+	var images []RegistryRepository
+	for _, name := range repos {
+		repo, err := distributionutil.NewClusterRepository(registries[0], name, "pull")
+		if err != nil {
+			return nil, err
+		}
+		tags, err := distributionutil.ListTags(ctx, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the tags of %s: %v", name, err)
+		}
+		// the repositories whose tags are all deleted are left in the catalog.
+		if len(tags) == 0 {
+			continue
+		}
+		sort.Strings(tags)
+		images = append(images, RegistryRepository{Repository: name, Tags: tags})
+	}
+	return images, nil
+}
+
+// pushRegistryImage push the images in the archive of src to all the registries, as name if it is not empty.
+func (k *KubeadmRuntime) pushRegistryImage(src, name string) error {
+	archive, err := distributionutil.OpenArchive(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := archive.Close(); err != nil {
+			logger.Warn("failed to clean the files of %s: %v", src, err)
+		}
+	}()
+	if name != "" && len(archive.Images) != 1 {
+		return fmt.Errorf("%s has %d images, the name could only be set for one image", src, len(archive.Images))
+	}
+
+	ctx := context.Background()
+	cf, registries := k.getClusterRegistries()
+	for i, image := range archive.Images {
+		names := image.Names
+		if name != "" {
+			names = []string{name}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("the image %d of %s has no name, set it by --name", i, src)
+		}
+		for _, n := range names {
+			repoName, tag, err := parseRegistryImageName(n)
+			if err != nil {
+				return err
+			}
+			for _, registry := range registries {
+				repo, err := distributionutil.NewClusterRepository(registry, repoName, "push", "pull")
+				if err != nil {
+					return fmt.Errorf("failed to connect to registry %s: %v", registry.Endpoint, err)
+				}
+				if err := distributionutil.PushArchiveImage(ctx, repo, image, tag); err != nil {
+					return fmt.Errorf("failed to push %s to registry %s: %v", n, registry.Endpoint, err)
+				}
+			}
+			logger.Info("%s is pushed as %s/%s:%s", n, registryEndpoint(cf), repoName, tag)
+		}
+	}
+	return nil
+}
+
+// removeRegistryImage delete the image from all the registries, the blobs are removed by garbageCollectRegistry.
+func (k *KubeadmRuntime) removeRegistryImage(name string) error {
+	repoName, tag, err := parseRegistryImageName(name)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	_, registries := k.getClusterRegistries()
+	for _, registry := range registries {
+		repo, err := distributionutil.NewClusterRepository(registry, repoName, "*")
+		if err != nil {
+			return fmt.Errorf("failed to connect to registry %s: %v", registry.Endpoint, err)
+		}
+		if err := distributionutil.DeleteTag(ctx, repo, tag); err != nil {
+			return fmt.Errorf("failed to delete %s:%s from registry %s: %v", repoName, tag, registry.Endpoint, err)
+		}
+	}
+	logger.Info("%s:%s is deleted, run sealer registry gc to free the disk", repoName, tag)
+	return nil
+}
+
+// garbageCollectRegistry run the garbage collection of registry on the registry hosts one by one, the images could not be
+// pulled from a registry while it is collected, so the others keep serving. It stops at the first failed host.
+func (k *KubeadmRuntime) garbageCollectRegistry() error {
+	cf := GetRegistryConfig(k.getRootfs(), k.GetMaster0IP())
+	cr, err := k.getContainerRuntime()
+	if err != nil {
+		return err
+	}
+	cmd := cr.GarbageCollectRegistryCmd(k.getRootfs())
+	for _, host := range GetRegistryHosts(k.Cluster, cf) {
+		ssh, err := k.getHostSSHClient(host)
+		if err != nil {
+			return err
+		}
+		logger.Info("Start to collect the garbage of registry on %s", host)
+		if err := ssh.CmdAsync(host, cmd); err != nil {
+			return fmt.Errorf("failed to collect the garbage of registry on %s: %v", host, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+func TestGetRegistryHosts(t *testing.T) {
+	hosts := []v2.Host{
+		{IPS: []string{"192.168.0.2", "192.168.0.3"}, Roles: []string{"master"}},
+		{IPS: []string{"192.168.0.4"}, Roles: []string{"node"}},
+	}
+	config := &RegistryConfig{IP: "192.168.0.2", Domain: SeaHub, Port: "5000"}
+	tests := []struct {
+		name string
+		env  []string
+		want []string
+	}{
+		{"default", nil, []string{"192.168.0.2"}},
+		{"disabled", []string{"RegistryHA=false"}, []string{"192.168.0.2"}},
+		{"ha", []string{"RegistryHA=true"}, []string{"192.168.0.2", "192.168.0.3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &v2.Cluster{Spec: v2.ClusterSpec{Hosts: hosts, Env: tt.env}}
+			if got := GetRegistryHosts(cluster, config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRegistryHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRegistryHostsCmd(t *testing.T) {
+	want := `sed -i "/ sea.hub$/d" /etc/hosts && echo 192.168.0.2 sea.hub >> /etc/hosts && echo fd00::3 sea.hub >> /etc/hosts`
+	if got := getRegistryHostsCmd(SeaHub, []string{"192.168.0.2", "[fd00::3]:22"}); got != want {
+		t.Errorf("getRegistryHostsCmd() = %s, want %s", got, want)
+	}
+}
+
+func TestParseRegistryImageName(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    string
+		tag     string
+		wantErr bool
+	}{
+		{"nginx", "library/nginx", "latest", false},
+		{"sea.hub:5000/library/nginx:1.21", "library/nginx", "1.21", false},
+		{"quay.io/coreos/etcd:v3.5.0", "coreos/etcd", "v3.5.0", false},
+		{"nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000", "", "", true},
+		{"Nginx:latest", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, tag, err := parseRegistryImageName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRegistryImageName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if repo != tt.repo || tag != tt.tag {
+				t.Errorf("parseRegistryImageName() = %s, %s, want %s, %s", repo, tag, tt.repo, tt.tag)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/cert"
+	"github.com/alibaba/sealer/pkg/client/k8s"
+	"github.com/alibaba/sealer/utils"
+)
+
+const (
+	RemoteBackupCerts = `mkdir -p %[1]s && cp -rf /etc/kubernetes/pki %[1]s/ && cp -f /etc/kubernetes/*.conf %[1]s/`
+	// the static pod is recreated by moving its manifest out until the container is gone, then moving it back.
+	// The manifest is moved back and the command fails if the container is not gone in time.
+	RemoteRestartStaticPod = `if [ -f /etc/kubernetes/manifests/%[1]s.yaml ]; then mv -f /etc/kubernetes/manifests/%[1]s.yaml %[2]s/ && n=0 && while crictl --runtime-endpoint unix://%[3]s ps -q --name '^%[1]s$' | grep -q .; do if [ $n -ge %[4]d ]; then mv -f %[2]s/%[1]s.yaml /etc/kubernetes/manifests/; echo "timed out waiting for %[1]s to stop" >&2; exit 1; fi; sleep 2; n=$((n+2)); done && mv -f %[2]s/%[1]s.yaml /etc/kubernetes/manifests/; fi`
+
+	renewWaitTimeout = 5 * time.Minute
+)
+
+var (
+	// staticPods are restarted in order after the certs used by them are renewed.
+	staticPods          = []string{"etcd", "kube-apiserver", "kube-controller-manager", "kube-scheduler"}
+	staticPodsUsingCert = map[string]string{
+		"apiserver":                "kube-apiserver",
+		"apiserver-kubelet-client": "kube-apiserver",
+		"front-proxy-client":       "kube-apiserver",
+		"apiserver-etcd-client":    "kube-apiserver",
+		"etcd-server":              "etcd",
+		"etcd-peer":                "etcd",
+		"controller-manager.conf":  "kube-controller-manager",
+		"scheduler.conf":           "kube-scheduler",
+	}
+)
+
+// CertExpiration is the expiration of a cert on a host.
+type CertExpiration struct {
+	Host string
+	Name string
+	// CA is the common name of the signing ca, it is empty for a self-signed cert.
+	CA       string
+	NotAfter time.Time
+}
+
+type caCertAndKey struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// getCertHosts return the masters and the external etcd hosts, which have the certs in /etc/kubernetes.
+func (k *KubeadmRuntime) getCertHosts() []string {
+	return utils.RemoveDuplicate(append(append([]string{}, k.GetMasterIPList()...), k.GetEtcdIPList()...))
+}
+
+// readRemoteFile return the content of file on host, found is false if the file does not exist.
+func (k *KubeadmRuntime) readRemoteFile(host, path string) (data []byte, found bool, err error) {
+	ssh, err := k.getHostSSHClient(host)
+	if err != nil {
+		return nil, false, err
+	}
+	if exist, err := ssh.IsFileExist(host, path); err != nil || !exist {
+		return nil, false, err
+	}
+	data, err = ssh.Cmd(host, fmt.Sprintf("cat %s", path))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s on %s: %v", path, host, err)
+	}
+	return data, true, nil
+}
+
+// checkCertExpiration parse the certs and the client certs of kubeconfigs on every master and etcd host, and the registry cert.
+func (k *KubeadmRuntime) checkCertExpiration() ([]CertExpiration, error) {
+	var expirations []CertExpiration
+	files := append(append([]cert.CertFile{}, cert.CAFiles...), cert.LeafCertFiles...)
+	for _, host := range k.getCertHosts() {
+		for _, f := range files {
+			data, found, err := k.readRemoteFile(host, filepath.Join(cert.KubernetesDir, f.Path))
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				continue
+			}
+			c, err := cert.ParseCertFile(f, data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check %s on %s: %v", f.Name, host, err)
+			}
+			if c == nil {
+				continue
+			}
+			expiration := CertExpiration{Host: host, Name: f.Name, NotAfter: c.NotAfter}
+			if f.CA != "" {
+				expiration.CA = c.Issuer.CommonName
+			}
+			expirations = append(expirations, expiration)
+		}
+	}
+
+	cf := GetRegistryConfig(k.getImageMountDir(), k.GetMaster0IP())
+	data, found, err := k.readRemoteFile(cf.IP, filepath.Join(k.getRootfs(), "certs", cf.Domain+".crt"))
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		c, err := cert.ParseCertFile(cert.CertFile{Name: "registry"}, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check registry cert on %s: %v", cf.IP, err)
+		}
+		expirations = append(expirations, CertExpiration{Host: cf.IP, Name: "registry", NotAfter: c.NotAfter})
+	}
+	return expirations, nil
+}
+
+// selectCertFiles return the leaf cert files of names, all of them if names is empty.
+func selectCertFiles(names []string) ([]cert.CertFile, error) {
+	if len(names) == 0 {
+		return cert.LeafCertFiles, nil
+	}
+	var valid []string
+	for _, f := range cert.LeafCertFiles {
+		valid = append(valid, f.Name)
+	}
+	for _, name := range names {
+		if utils.NotIn(name, valid) {
+			return nil, fmt.Errorf("unknown cert %s, it should be one of %s", name, strings.Join(valid, ", "))
+		}
+	}
+	var files []cert.CertFile
+	for _, f := range cert.LeafCertFiles {
+		if utils.InList(f.Name, names) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// loadCAs load the ca certs and keys on master0, the missing ones are skipped.
+func (k *KubeadmRuntime) loadCAs() (map[string]caCertAndKey, error) {
+	cas := map[string]caCertAndKey{}
+	for _, f := range cert.CAFiles {
+		certData, found, err := k.readRemoteFile(k.GetMaster0IP(), filepath.Join(cert.KubernetesDir, f.Path))
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		keyData, _, err := k.readRemoteFile(k.GetMaster0IP(), filepath.Join(cert.KubernetesDir, f.KeyPath()))
+		if err != nil {
+			return nil, err
+		}
+		caCert, caKey, err := cert.ParseCertAndKey(certData, keyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s on master0: %v", f.Name, err)
+		}
+		cas[f.Path] = caCertAndKey{cert: caCert, key: caKey}
+	}
+	return cas, nil
+}
+
+// renewCerts re-sign the leaf certs of names with the existing cas on every master and etcd host one by one,
+// and restart the static pods using them. The old certs are backed up, and the next host is not renewed until
+// the control plane of the renewed master is Ready.
+func (k *KubeadmRuntime) renewCerts(names []string) error {
+	files, err := selectCertFiles(names)
+	if err != nil {
+		return err
+	}
+	cas, err := k.loadCAs()
+	if err != nil {
+		return err
+	}
+	client, err := k8s.Newk8sClient()
+	if err != nil {
+		return err
+	}
+	backupDir := filepath.Join(k.getBasePath(), "cert-backup", time.Now().Format("20060102150405"))
+	for _, host := range k.getCertHosts() {
+		if err := k.renewHostCerts(client, host, files, cas, backupDir); err != nil {
+			return fmt.Errorf("failed to renew certs of %s, the old certs are backed up in %s: %v", host, backupDir, err)
+		}
+	}
+	return nil
+}
+
+func (k *KubeadmRuntime) renewHostCerts(client *k8s.Client, host string, files []cert.CertFile, cas map[string]caCertAndKey, backupDir string) error {
+	ssh, err := k.getHostSSHClient(host)
+	if err != nil {
+		return err
+	}
+	if err := ssh.CmdAsync(host, fmt.Sprintf(RemoteBackupCerts, backupDir)); err != nil {
+		return fmt.Errorf("failed to back up certs: %v", err)
+	}
+	// the renewed kubeconfigs have the client keys embedded, they are not kept on local host.
+	tmpDir, err := ioutil.TempDir("", "sealer-renew-certs")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			logger.Warn("failed to remove %s: %v", tmpDir, err)
+		}
+	}()
+
+	restart := map[string]bool{}
+	for _, f := range files {
+		remotePath := filepath.Join(cert.KubernetesDir, f.Path)
+		data, found, err := k.readRemoteFile(host, remotePath)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		renewed, err := k.renewCertFile(host, f, data, cas)
+		if err != nil {
+			return fmt.Errorf("failed to renew %s: %v", f.Name, err)
+		}
+		if renewed == nil {
+			logger.Info("skip %s of %s, its client cert is rotated by kubelet", f.Name, host)
+			continue
+		}
+		var mode os.FileMode = 0644
+		if f.IsKubeConfig {
+			mode = 0600
+		}
+		localPath := filepath.Join(tmpDir, f.Path)
+		if err := utils.MkFileFullPathDir(localPath); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(localPath, renewed, mode); err != nil {
+			return err
+		}
+		if err := ssh.Copy(host, localPath, remotePath); err != nil {
+			return fmt.Errorf("failed to send %s: %v", f.Name, err)
+		}
+		// the kubeconfigs in base path are sent to the joined masters.
+		if basePathFile := filepath.Join(k.getBasePath(), f.Path); f.IsKubeConfig && host == k.GetMaster0IP() && utils.IsFileExist(basePathFile) {
+			if err := ioutil.WriteFile(basePathFile, renewed, mode); err != nil {
+				return err
+			}
+		}
+		logger.Info("%s of %s is renewed", f.Name, host)
+		if pod, ok := staticPodsUsingCert[f.Name]; ok {
+			restart[pod] = true
+		}
+		switch f.Name {
+		case AdminConf:
+			err = ssh.CmdAsync(host, RemoteCopyKubeConfig)
+		case KubeletConf:
+			err = ssh.CmdAsync(host, restartCmd)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return err
+	}
+	for _, pod := range staticPods {
+		if !restart[pod] {
+			continue
+		}
+		logger.Info("restart %s of %s", pod, host)
+		if err := ssh.CmdAsync(host, fmt.Sprintf(RemoteRestartStaticPod, pod, backupDir, criSocket, int(staticPodStopTimeout.Seconds()))); err != nil {
+			return fmt.Errorf("failed to restart %s: %v", pod, err)
+		}
+		if pod == "etcd" {
+			if err := k.waitEtcdHealthy(host); err != nil {
+				return err
+			}
+		}
+	}
+	if utils.NotIn(host, k.GetMasterIPList()) {
+		return nil
+	}
+	name, err := getNodeName(client, host)
+	if err != nil {
+		return err
+	}
+	return wait.PollImmediate(5*time.Second, renewWaitTimeout, func() (bool, error) {
+		return isControlPlaneReady(client, name), nil
+	})
+}
+
+// renewCertFile return the renewed content of the cert file, it is nil if the kubeconfig has no embedded client cert.
+func (k *KubeadmRuntime) renewCertFile(host string, f cert.CertFile, data []byte, cas map[string]caCertAndKey) ([]byte, error) {
+	ca, ok := cas[f.CA]
+	if !ok {
+		return nil, fmt.Errorf("ca %s is not found on master0", f.CA)
+	}
+	if f.IsKubeConfig {
+		if c, err := cert.ParseCertFile(f, data); err != nil || c == nil {
+			return nil, err
+		}
+		return cert.RenewKubeConfig(data, ca.cert, ca.key)
+	}
+	keyData, _, err := k.readRemoteFile(host, filepath.Join(cert.KubernetesDir, f.KeyPath()))
+	if err != nil {
+		return nil, err
+	}
+	old, key, err := cert.ParseCertAndKey(data, keyData)
+	if err != nil {
+		return nil, err
+	}
+	renewed, err := cert.RenewCert(old, key, ca.cert, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	return cert.EncodeCertPEM(renewed), nil
+}
@@ -0,0 +1,59 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alibaba/sealer/pkg/cert"
+)
+
+func TestSelectCertFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr bool
+	}{
+		{"all", nil, nil, false},
+		{"some", []string{"admin.conf", "apiserver"}, []string{"apiserver", "admin.conf"}, false},
+		{"unknown", []string{"apiserver", "ca"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := selectCertFiles(tt.names)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectCertFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.want == nil {
+				if len(files) != len(cert.LeafCertFiles) {
+					t.Errorf("selectCertFiles() selected %d files, want all %d", len(files), len(cert.LeafCertFiles))
+				}
+				return
+			}
+			var got []string
+			for _, f := range files {
+				got = append(got, f.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectCertFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/client/k8s"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+	"github.com/alibaba/sealer/utils"
+)
+
+// the labels and taints with these prefixes are managed by kubelet, kubeadm or the node controller.
+var managedNodeKeyPrefixes = []string{"kubernetes.io/", "beta.kubernetes.io/", "node.kubernetes.io/", "node.cloudprovider.kubernetes.io/"}
+
+// NodeMeta is the labels and taints set by users on the node of a host, they are restored after it is repaired.
+type NodeMeta struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Taints []v1.Taint        `json:"taints,omitempty"`
+}
+
+func isManagedNodeKey(key string) bool {
+	for _, prefix := range managedNodeKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeMetaOf return the labels and taints of node except the managed ones.
+func nodeMetaOf(node *v1.Node) *NodeMeta {
+	meta := &NodeMeta{Labels: map[string]string{}}
+	for key, value := range node.Labels {
+		if !isManagedNodeKey(key) {
+			meta.Labels[key] = value
+		}
+	}
+	for _, taint := range node.Spec.Taints {
+		if !isManagedNodeKey(taint.Key) {
+			taint.TimeAdded = nil
+			meta.Taints = append(meta.Taints, taint)
+		}
+	}
+	return meta
+}
+
+// getNodeMeta return the labels and taints of the node of the master or worker, which is found through a healthy master.
+func (k *KubeadmRuntime) getNodeMeta(host string) (*NodeMeta, error) {
+	if utils.NotIn(host, k.GetMasterIPList()) && utils.NotIn(host, k.GetNodeIPList()) {
+		return nil, fmt.Errorf("%s is not a master or worker of the cluster", host)
+	}
+	if err := k.MergeKubeadmConfig(); err != nil {
+		return nil, err
+	}
+	master, err := k.getHealthyMaster(host)
+	if err != nil {
+		return nil, err
+	}
+	node, err := k.findNodeByMaster(master, host)
+	if err != nil || node == nil {
+		return &NodeMeta{}, err
+	}
+	return nodeMetaOf(node), nil
+}
+
+// removeBrokenHost remove the master or worker from the cluster through a healthy master whether it is reachable or not:
+// the stacked etcd member of a master is removed, the node is deleted, and the host is reset if it is reachable.
+// If master0 is broken, the registry is moved off it and the healthy master becomes master0, which joins it again.
+// It could be run again if it failed, the member and node already removed are skipped.
+func (k *KubeadmRuntime) removeBrokenHost(host string) error {
+	isMaster := utils.InList(host, k.GetMasterIPList())
+	if !isMaster && utils.NotIn(host, k.GetNodeIPList()) {
+		return fmt.Errorf("%s is not a master or worker of the cluster", host)
+	}
+	if err := k.MergeKubeadmConfig(); err != nil {
+		return err
+	}
+	master, err := k.getHealthyMaster(host)
+	if err != nil {
+		return err
+	}
+	if host == k.GetMaster0IP() {
+		if err := k.moveMaster0(master); err != nil {
+			return err
+		}
+	}
+	node, err := k.findNodeByMaster(master, host)
+	if err != nil {
+		return err
+	}
+	if isMaster && len(k.GetEtcdIPList()) == 0 {
+		if err := k.removeEtcdMember(master, host); err != nil {
+			return fmt.Errorf("failed to remove etcd member %s: %v", host, err)
+		}
+	}
+	ssh, err := k.getHostSSHClient(host)
+	if err != nil {
+		return err
+	}
+	if err := ssh.Ping(host); err != nil {
+		logger.Warn("%s is unreachable, skip resetting it: %v", host, err)
+	} else {
+		if isMaster {
+			cr, err := k.getContainerRuntime()
+			if err != nil {
+				return err
+			}
+			if err := ssh.CmdAsync(host, cr.StopRegistryCmd()); err != nil {
+				return fmt.Errorf("failed to stop the registry on %s: %v", host, err)
+			}
+		}
+		if err := k.deleteNode(host); err != nil {
+			return fmt.Errorf("failed to reset %s: %v", host, err)
+		}
+	}
+	if node != nil {
+		if err := k.CmdAsyncHosts([]string{master}, fmt.Sprintf(KubeDeleteNode, node.Name)+" --ignore-not-found"); err != nil {
+			return err
+		}
+		logger.Info("node %s of %s is deleted", node.Name, host)
+	}
+	return nil
+}
+
+// getHealthyMaster return master0 or the first master other than host whose apiserver and etcd are working.
+func (k *KubeadmRuntime) getHealthyMaster(host string) (string, error) {
+	masters := utils.RemoveStrSlice(k.GetMasterIPList(), []string{host})
+	for _, master := range masters {
+		if _, err := k.CmdToString(master, "kubectl get nodes", "\n"); err != nil {
+			logger.Warn("master %s is not healthy: %v", master, err)
+			continue
+		}
+		if len(k.GetEtcdIPList()) == 0 {
+			if _, err := k.runEtcdctl(master, EtcdctlMemberList); err != nil {
+				logger.Warn("etcd of master %s is not healthy: %v", master, err)
+				continue
+			}
+		}
+		return master, nil
+	}
+	return "", fmt.Errorf("no healthy master other than %s is found to repair it through", host)
+}
+
+// moveMaster0 move the registry off the broken master0 and let the healthy master be master0, which the
+// kubeconfig of sealer and the joining of the repaired master0 use.
+func (k *KubeadmRuntime) moveMaster0(master string) error {
+	master0 := k.GetMaster0IP()
+	logger.Info("master0 %s is broken, %s will be the new master0", master0, master)
+	if err := k.migrateRegistry([]string{master0}, []string{master}); err != nil {
+		return err
+	}
+	if err := updateSealerAPIServer(getAPIServerHost(master0, k.getAPIServerDomain()), getAPIServerHost(master, k.getAPIServerDomain())); err != nil {
+		return err
+	}
+	setMaster0(k.Cluster, master)
+	return nil
+}
+
+// setMaster0 swap the host group of master with the first one, and the ip of master with the first one in the group,
+// so the ssh and env of the groups are kept.
+func setMaster0(cluster *v2.Cluster, master string) {
+	hosts := cluster.Spec.Hosts
+	for i := range hosts {
+		for j, ip := range hosts[i].IPS {
+			if ip != master {
+				continue
+			}
+			hosts[0], hosts[i] = hosts[i], hosts[0]
+			hosts[0].IPS[0], hosts[0].IPS[j] = hosts[0].IPS[j], hosts[0].IPS[0]
+			return
+		}
+	}
+}
+
+// findNodeByMaster return the node of host listed on master, it is nil if not found.
+func (k *KubeadmRuntime) findNodeByMaster(master, host string) (*v1.Node, error) {
+	out, err := k.CmdToString(master, "kubectl get nodes -o json", "\n")
+	if err != nil {
+		return nil, err
+	}
+	nodes := &v1.NodeList{}
+	if err := json.Unmarshal([]byte(out), nodes); err != nil {
+		return nil, fmt.Errorf("failed to parse the nodes listed on %s: %v", master, err)
+	}
+	return findNodeInList(nodes, host), nil
+}
+
+// removeEtcdMember remove the etcd member on host through the member on master, it is skipped if not found.
+func (k *KubeadmRuntime) removeEtcdMember(member, host string) error {
+	memberList, err := k.runEtcdctl(member, EtcdctlMemberList)
+	if err != nil {
+		return err
+	}
+	id := getEtcdMemberID(memberList, getEtcdPeerURL(host))
+	if id == "" {
+		logger.Info("etcd member %s is not found, skip removing it", host)
+		return nil
+	}
+	_, err = k.runEtcdctl(member, fmt.Sprintf(EtcdctlMemberDel, id))
+	return err
+}
+
+// restoreNodeMeta add the labels and taints back to the node of the host, the ones existing are kept.
+func (k *KubeadmRuntime) restoreNodeMeta(host string, meta *NodeMeta) error {
+	if meta == nil || (len(meta.Labels) == 0 && len(meta.Taints) == 0) {
+		return nil
+	}
+	client, err := k8s.Newk8sClient()
+	if err != nil {
+		return err
+	}
+	// retry on the conflicts with the status updated by kubelet.
+	return utils.Retry(5, 2*time.Second, func() error {
+		name, err := getNodeName(client, host)
+		if err != nil {
+			return err
+		}
+		node, err := client.GetNode(name)
+		if err != nil {
+			return err
+		}
+		mergeNodeMeta(node, meta)
+		_, err = client.UpdateNode(*node)
+		return err
+	})
+}
+
+func mergeNodeMeta(node *v1.Node, meta *NodeMeta) {
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	for key, value := range meta.Labels {
+		node.Labels[key] = value
+	}
+	for _, taint := range meta.Taints {
+		found := false
+		for _, t := range node.Spec.Taints {
+			if t.MatchTaint(&taint) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			node.Spec.Taints = append(node.Spec.Taints, taint)
+		}
+	}
+}
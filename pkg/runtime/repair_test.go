@@ -0,0 +1,117 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/alibaba/sealer/common"
+	typev1 "github.com/alibaba/sealer/types/api/v1"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+func TestNodeMetaOf(t *testing.T) {
+	now := metav1.Now()
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+			"kubernetes.io/hostname":                                  "worker-1",
+			"beta.kubernetes.io/arch":                                 "amd64",
+			"node-role.kubernetes.io/worker":                          "",
+			"node.kubernetes.io/exclude-from-external-load-balancers": "",
+			"app": "db",
+		}},
+		Spec: v1.NodeSpec{Taints: []v1.Taint{
+			{Key: "node.kubernetes.io/unreachable", Effect: v1.TaintEffectNoExecute, TimeAdded: &now},
+			{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoSchedule},
+		}},
+	}
+	want := &NodeMeta{
+		Labels: map[string]string{"node-role.kubernetes.io/worker": "", "app": "db"},
+		Taints: []v1.Taint{{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoSchedule}},
+	}
+	if got := nodeMetaOf(node); !reflect.DeepEqual(got, want) {
+		t.Errorf("nodeMetaOf() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeNodeMeta(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"kubernetes.io/hostname": "worker-1"}},
+		Spec: v1.NodeSpec{Taints: []v1.Taint{
+			{Key: "dedicated", Value: "cache", Effect: v1.TaintEffectNoSchedule},
+		}},
+	}
+	mergeNodeMeta(node, &NodeMeta{
+		Labels: map[string]string{"app": "db"},
+		Taints: []v1.Taint{
+			{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoSchedule},
+			{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoExecute},
+		},
+	})
+	wantLabels := map[string]string{"kubernetes.io/hostname": "worker-1", "app": "db"}
+	if !reflect.DeepEqual(node.Labels, wantLabels) {
+		t.Errorf("labels = %v, want %v", node.Labels, wantLabels)
+	}
+	// the taint with the same key and effect is kept.
+	wantTaints := []v1.Taint{
+		{Key: "dedicated", Value: "cache", Effect: v1.TaintEffectNoSchedule},
+		{Key: "dedicated", Value: "db", Effect: v1.TaintEffectNoExecute},
+	}
+	if !reflect.DeepEqual(node.Spec.Taints, wantTaints) {
+		t.Errorf("taints = %v, want %v", node.Spec.Taints, wantTaints)
+	}
+}
+
+func TestSetMaster0(t *testing.T) {
+	tests := []struct {
+		name   string
+		hosts  []v2.Host
+		master string
+		want   []v2.Host
+	}{
+		{
+			name:   "same group",
+			hosts:  []v2.Host{{IPS: []string{"192.168.0.2", "192.168.0.3", "192.168.0.4"}, Roles: []string{common.MASTER}}},
+			master: "192.168.0.4",
+			want:   []v2.Host{{IPS: []string{"192.168.0.4", "192.168.0.3", "192.168.0.2"}, Roles: []string{common.MASTER}}},
+		},
+		{
+			name: "other group",
+			hosts: []v2.Host{
+				{IPS: []string{"192.168.0.2"}, Roles: []string{common.MASTER}},
+				{IPS: []string{"192.168.0.3", "192.168.0.4"}, Roles: []string{common.MASTER}, SSH: typev1.SSH{Port: "2222"}},
+			},
+			master: "192.168.0.4",
+			want: []v2.Host{
+				{IPS: []string{"192.168.0.4", "192.168.0.3"}, Roles: []string{common.MASTER}, SSH: typev1.SSH{Port: "2222"}},
+				{IPS: []string{"192.168.0.2"}, Roles: []string{common.MASTER}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &v2.Cluster{}
+			cluster.Spec.Hosts = tt.hosts
+			setMaster0(cluster, tt.master)
+			if !reflect.DeepEqual(cluster.Spec.Hosts, tt.want) {
+				t.Errorf("setMaster0() = %+v, want %+v", cluster.Spec.Hosts, tt.want)
+			}
+		})
+	}
+}
@@ -26,14 +26,16 @@ import (
 func (k *KubeadmRuntime) reset() error {
 	k.resetNodes(k.GetNodeIPList())
 	k.resetMasters(k.GetMasterIPList())
+	k.resetNodes(k.GetEtcdIPList())
 	//if the executing machine is not in the cluster
 	if _, err := utils.RunSimpleCmd(fmt.Sprintf(RemoteRemoveAPIServerEtcHost, k.getAPIServerDomain())); err != nil {
 		return err
 	}
-	for _, node := range k.GetNodeIPList() {
-		err := k.deleteVIPRouteIfExist(node)
-		if err != nil {
-			return fmt.Errorf("failed to delete %s route: %v", node, err)
+	if k.isLvscareMode() {
+		for _, node := range k.GetNodeIPList() {
+			if err := k.deleteVIPRouteIfExist(node); err != nil {
+				return fmt.Errorf("failed to delete %s route: %v", node, err)
+			}
 		}
 	}
 	return k.DeleteRegistry()
@@ -70,9 +72,14 @@ func (k *KubeadmRuntime) resetNode(node string) error {
 	}
 	if err := ssh.CmdAsync(node, fmt.Sprintf(RemoteCleanMasterOrNode, vlogToStr(k.Vlog)),
 		RemoveKubeConfig,
+		RemoteRemoveEtcdBackup,
 		fmt.Sprintf(RemoteRemoveAPIServerEtcHost, k.getAPIServerDomain()),
-		fmt.Sprintf(RemoteRemoveAPIServerEtcHost, getRegistryHost(k.getRootfs(), k.GetMaster0IP()))); err != nil {
+		k.removeRegistryHostsCmd()); err != nil {
 		return err
 	}
+	// the standalone kubelet of external etcd member is configured by a drop-in, reload systemd after removing it.
+	if utils.InList(node, k.GetEtcdIPList()) {
+		return ssh.CmdAsync(node, RemoteRemoveEtcdKubeletConf)
+	}
 	return nil
 }
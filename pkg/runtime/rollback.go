@@ -0,0 +1,58 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/alibaba/sealer/logger"
+)
+
+// rollbackJoin undo the joining in the reverse order, the hosts may be half joined,
+// so a host which is not found in the cluster or etcd members is only reset.
+// It is not confirmed like deleting, the hosts have not been serving yet.
+func (k *KubeadmRuntime) rollbackJoin(masters, nodes, etcds []string) error {
+	if err := k.MergeKubeadmConfig(); err != nil {
+		return err
+	}
+	if err := k.deleteNodes(nodes); err != nil {
+		return err
+	}
+	for _, master := range masters {
+		logger.Info("Start to roll back master %s", master)
+		if err := k.rollbackMaster(master); err != nil {
+			return fmt.Errorf("failed to roll back master %s: %v", master, err)
+		}
+	}
+	return k.deleteEtcds(etcds)
+}
+
+func (k *KubeadmRuntime) rollbackMaster(master string) error {
+	// kubeadm reset removes the stacked etcd member only if the etcd of master has been started.
+	if len(k.GetEtcdIPList()) == 0 {
+		if err := k.removeEtcdMember(k.GetMaster0IP(), master); err != nil {
+			return err
+		}
+	}
+	// the registry is started on the master to join if RegistryHA is true.
+	cr, err := k.getContainerRuntime()
+	if err != nil {
+		return err
+	}
+	if err := k.CmdAsyncHosts([]string{master}, cr.StopRegistryCmd()); err != nil {
+		return err
+	}
+	return k.deleteNode(master)
+}
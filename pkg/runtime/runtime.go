@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/alibaba/sealer/common"
 	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/client/etcd"
 	"github.com/alibaba/sealer/utils"
 
 	v2 "github.com/alibaba/sealer/types/api/v2"
@@ -33,10 +35,72 @@ type Interface interface {
 	JoinNodes(newNodesIPList []string) error
 	DeleteMasters(mastersIPList []string) error
 	DeleteNodes(nodesIPList []string) error
+	JoinEtcds(newEtcdIPList []string) error
+	DeleteEtcds(etcdIPList []string) error
+	// RollbackJoin reset the hosts joined by a failed scale up and remove them from the cluster
+	RollbackJoin(masters, nodes, etcds []string) error
 	GetClusterMetadata() (*Metadata, error)
 	UpdateCert(certs []string) error
+	// RollbackUpgrade restore the hosts upgraded from the ClusterImage to it, and etcd if restoreEtcd is true.
+	RollbackUpgrade(restoreEtcd bool) error
 }
 
+// The optional interfaces below are implemented by the runtimes supporting them, the commands assert the runtime of
+// cluster to them and fail if it is not supported.
+
+// CertManager checks and renews the certs of cluster, and issues the kubeconfigs signed by the cluster ca.
+type CertManager interface {
+	// CheckCertExpiration return the expiration of the certs on masters and etcd hosts, and the registry cert.
+	CheckCertExpiration() ([]CertExpiration, error)
+	// RenewCerts re-sign the leaf certs of names with the existing cas, all of them if names is empty.
+	RenewCerts(names []string) error
+	// IssueKubeConfig return a kubeconfig whose client cert is signed by the cluster ca.
+	IssueKubeConfig(opts KubeConfigOptions) ([]byte, error)
+}
+
+// EtcdManager snapshots, restores and schedules the backups of the etcd of cluster.
+type EtcdManager interface {
+	// SnapshotEtcd save a snapshot of etcd to the snapshot dir, return the host and path of it.
+	SnapshotEtcd(name string) (host, path string, err error)
+	ListEtcdSnapshots() ([]EtcdSnapshot, error)
+	EtcdStatus() ([]etcd.MemberStatus, error)
+	// RestoreEtcd restore all the etcd members from the snapshot, a name in the snapshot dir or an absolute local path.
+	RestoreEtcd(snapshot string) error
+	// ScheduleEtcdBackup install, update or remove the scheduled etcd backup by the policy in cluster env.
+	ScheduleEtcdBackup() error
+	ListEtcdBackups() ([]EtcdBackup, error)
+}
+
+// Repairer re-provisions a broken master or worker in place.
+type Repairer interface {
+	Interface
+	// GetNodeMeta return the labels and taints set by users on the node of the host, it is empty if the node is not found.
+	GetNodeMeta(host string) (*NodeMeta, error)
+	// RemoveBrokenHost remove the master or worker from the cluster even if it is unreachable, and reset it if reachable.
+	RemoveBrokenHost(host string) error
+	// RestoreNodeMeta add the labels and taints back to the node of the host joined again.
+	RestoreNodeMeta(host string, meta *NodeMeta) error
+}
+
+// RegistryManager manages the images in the registries of cluster.
+type RegistryManager interface {
+	ListRegistryImages() ([]RegistryRepository, error)
+	// PushRegistryImage push the images of a docker archive, an OCI layout or local docker to the registries of cluster,
+	// as name if it is not empty.
+	PushRegistryImage(src, name string) error
+	// RemoveRegistryImage delete the image from the registries, the blobs are left until they are garbage collected.
+	RemoveRegistryImage(name string) error
+	GarbageCollectRegistry() error
+}
+
+var (
+	_ CertManager     = &KubeadmRuntime{}
+	_ EtcdManager     = &KubeadmRuntime{}
+	_ Repairer        = &KubeadmRuntime{}
+	_ RegistryManager = &KubeadmRuntime{}
+	_ RegistryManager = &K3sRuntime{}
+)
+
 type Metadata struct {
 	Version string `json:"version"`
 	Arch    string `json:"arch"`
@@ -44,8 +108,19 @@ type Metadata struct {
 	//KubeVersion is a SemVer constraint specifying the version of Kubernetes required.
 	KubeVersion string `json:"kubeVersion"`
 	NydusFlag   bool   `json:"NydusFlag"`
+	// ClusterRuntime is the kubernetes distribution of ClusterImage, kubeadm is used if it is empty.
+	ClusterRuntime ClusterRuntime `json:"ClusterRuntime,omitempty"`
+	// ContainerRuntime is docker or containerd, it is detected on master0 if it is empty.
+	ContainerRuntime string `json:"ContainerRuntime,omitempty"`
 }
 
+type ClusterRuntime string
+
+const (
+	K8s ClusterRuntime = "kubernetes"
+	K3s ClusterRuntime = "k3s"
+)
+
 type KubeadmRuntime struct {
 	*sync.Mutex
 	*v2.Cluster
@@ -55,6 +130,9 @@ type KubeadmRuntime struct {
 
 var ForceDelete bool
 
+// Parallelism is the max number of nodes joined at the same time, no limit if it is not positive.
+var Parallelism int
+
 func (k *KubeadmRuntime) Init(cluster *v2.Cluster) error {
 	return k.init(cluster)
 }
@@ -63,8 +141,13 @@ func (k *KubeadmRuntime) Upgrade() error {
 	return k.upgrade()
 }
 
+func (k *KubeadmRuntime) RollbackUpgrade(restoreEtcd bool) error {
+	logger.Info("Start to roll back the upgrade of cluster to %s", k.getKubeVersion())
+	return k.rollbackUpgrade(restoreEtcd)
+}
+
 func (k *KubeadmRuntime) Reset() error {
-	logger.Info("Start to delete cluster: master %s, node %s", k.Cluster.GetMasterIPList(), k.Cluster.GetNodeIPList())
+	logger.Info("Start to delete cluster: master %s, node %s, etcd %s", k.Cluster.GetMasterIPList(), k.Cluster.GetNodeIPList(), k.Cluster.GetEtcdIPList())
 	if err := k.confirmDeleteNodes(); err != nil {
 		return err
 	}
@@ -105,6 +188,31 @@ func (k *KubeadmRuntime) DeleteNodes(nodesIPList []string) error {
 	return k.deleteNodes(nodesIPList)
 }
 
+func (k *KubeadmRuntime) JoinEtcds(newEtcdIPList []string) error {
+	if len(newEtcdIPList) != 0 {
+		logger.Info("%s will be added as etcd member", newEtcdIPList)
+	}
+	return k.joinEtcds(newEtcdIPList)
+}
+
+func (k *KubeadmRuntime) DeleteEtcds(etcdIPList []string) error {
+	if len(etcdIPList) != 0 {
+		logger.Info("etcd member %s will be deleted", etcdIPList)
+		if err := k.confirmDeleteNodes(); err != nil {
+			return err
+		}
+	}
+	return k.deleteEtcds(etcdIPList)
+}
+
+func (k *KubeadmRuntime) RollbackJoin(masters, nodes, etcds []string) error {
+	if len(masters) == 0 && len(nodes) == 0 && len(etcds) == 0 {
+		return nil
+	}
+	logger.Info("Start to roll back the joining of master %s, worker %s, etcd %s", masters, nodes, etcds)
+	return k.rollbackJoin(masters, nodes, etcds)
+}
+
 func (k *KubeadmRuntime) confirmDeleteNodes() error {
 	if !ForceDelete {
 		if pass, err := utils.ConfirmOperation("Are you sure to delete these nodes? "); err != nil {
@@ -124,7 +232,114 @@ func (k *KubeadmRuntime) UpdateCert(certs []string) error {
 	return k.updateCert(certs)
 }
 
+func (k *KubeadmRuntime) CheckCertExpiration() ([]CertExpiration, error) {
+	return k.checkCertExpiration()
+}
+
+func (k *KubeadmRuntime) RenewCerts(names []string) error {
+	return k.renewCerts(names)
+}
+
+func (k *KubeadmRuntime) IssueKubeConfig(opts KubeConfigOptions) ([]byte, error) {
+	return k.issueKubeConfig(opts)
+}
+
+func (k *KubeadmRuntime) SnapshotEtcd(name string) (host, path string, err error) {
+	return k.snapshotEtcd(name)
+}
+
+func (k *KubeadmRuntime) ListEtcdSnapshots() ([]EtcdSnapshot, error) {
+	return k.listEtcdSnapshots()
+}
+
+func (k *KubeadmRuntime) EtcdStatus() ([]etcd.MemberStatus, error) {
+	return k.etcdStatus()
+}
+
+func (k *KubeadmRuntime) RestoreEtcd(snapshot string) error {
+	return k.restoreEtcdFromSnapshot(snapshot)
+}
+
+func (k *KubeadmRuntime) ScheduleEtcdBackup() error {
+	return k.scheduleEtcdBackup()
+}
+
+func (k *KubeadmRuntime) ListEtcdBackups() ([]EtcdBackup, error) {
+	return k.listEtcdBackups()
+}
+
+func (k *KubeadmRuntime) GetNodeMeta(host string) (*NodeMeta, error) {
+	return k.getNodeMeta(host)
+}
+
+func (k *KubeadmRuntime) RemoveBrokenHost(host string) error {
+	logger.Info("Start to remove the broken host %s from the cluster", host)
+	return k.removeBrokenHost(host)
+}
+
+func (k *KubeadmRuntime) RestoreNodeMeta(host string, meta *NodeMeta) error {
+	return k.restoreNodeMeta(host, meta)
+}
+
+func (k *KubeadmRuntime) ListRegistryImages() ([]RegistryRepository, error) {
+	return k.listRegistryImages()
+}
+
+func (k *KubeadmRuntime) PushRegistryImage(src, name string) error {
+	return k.pushRegistryImage(src, name)
+}
+
+func (k *KubeadmRuntime) RemoveRegistryImage(name string) error {
+	return k.removeRegistryImage(name)
+}
+
+func (k *KubeadmRuntime) GarbageCollectRegistry() error {
+	return k.garbageCollectRegistry()
+}
+
 // NewDefaultRuntime arg "clusterfileKubeConfig" is the Clusterfile path/name, runtime need read kubeadm config from it
+// The runtime is chosen by the ClusterRuntime of the Metadata in the mounted ClusterImage.
 func NewDefaultRuntime(cluster *v2.Cluster, clusterfileKubeConfig *KubeadmConfig) (Interface, error) {
-	return newKubeadmRuntime(cluster, clusterfileKubeConfig)
+	clusterRuntime, err := getClusterRuntime(cluster)
+	if err != nil {
+		return nil, err
+	}
+	switch clusterRuntime {
+	case K8s:
+		return newKubeadmRuntime(cluster, clusterfileKubeConfig)
+	case K3s:
+		return newK3sRuntime(cluster, clusterfileKubeConfig)
+	default:
+		return nil, fmt.Errorf("unsupported cluster runtime %s", clusterRuntime)
+	}
+}
+
+// getClusterRuntime get the runtime from the metadata of the mounted ClusterImage and record it in the annotations of cluster,
+// the recorded one is used when the image is not mounted, like delete and the apply of app images.
+func getClusterRuntime(cluster *v2.Cluster) (ClusterRuntime, error) {
+	mountDir := common.DefaultMountCloudImageDir(cluster.Name)
+	if utils.IsExist(mountDir) {
+		md, err := LoadMetadata(mountDir)
+		if err != nil {
+			return "", err
+		}
+		clusterRuntime := K8s
+		if md != nil && md.ClusterRuntime != "" {
+			clusterRuntime = md.ClusterRuntime
+		}
+		cluster.SetAnnotations(common.ClusterRuntime, string(clusterRuntime))
+		return clusterRuntime, nil
+	}
+	if clusterRuntime := cluster.GetAnnotationsByKey(common.ClusterRuntime); clusterRuntime != "" {
+		return ClusterRuntime(clusterRuntime), nil
+	}
+	md, err := LoadMetadata(common.DefaultTheClusterRootfsDir(cluster.Name))
+	if err != nil {
+		return "", err
+	}
+	// the clusters created before the runtime is recorded are kubeadm ones.
+	if md == nil || md.ClusterRuntime == "" {
+		return K8s, nil
+	}
+	return md.ClusterRuntime, nil
 }
@@ -17,94 +17,453 @@ package runtime
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/client/k8s"
+	"github.com/alibaba/sealer/utils"
 )
 
 const (
-	chmodCmd       = `chmod +x %s/*`
-	mvCmd          = `mv %s/* /usr/bin`
-	getNodeNameCmd = `$(uname -n | tr '[A-Z]' '[a-z]')`
-	drainCmd       = `kubectl drain ` + getNodeNameCmd + ` --ignore-daemonsets`
-	upgradeCmd     = `kubeadm upgrade %s`
-	restartCmd     = `systemctl daemon-reload && systemctl restart kubelet`
-	uncordonCmd    = `kubectl uncordon ` + getNodeNameCmd
+	chmodCmd    = `chmod +x %s/*`
+	mvCmd       = `mv %s/* /usr/bin`
+	drainCmd    = `kubectl drain %s --ignore-daemonsets%s`
+	upgradeCmd  = `kubeadm upgrade %s`
+	restartCmd  = `systemctl daemon-reload && systemctl restart kubelet`
+	uncordonCmd = `kubectl uncordon %s`
+	// the binaries, kubelet config and static pod manifests before upgrade are backed up once, it is kept when upgrade is rerun.
+	RemoteBackupUpgrade   = `if [ ! -d %[1]s/bin ]; then mkdir -p %[1]s/bin %[1]s/manifests && cp -f /usr/bin/kubeadm /usr/bin/kubelet /usr/bin/kubectl %[1]s/bin/ && cp -f /var/lib/kubelet/config.yaml %[1]s/ && if [ -d /etc/kubernetes/manifests ]; then cp -rf /etc/kubernetes/manifests/. %[1]s/manifests/; fi; fi`
+	RemoteRollbackUpgrade = `cp -f %[1]s/bin/* /usr/bin/ && cp -f %[1]s/config.yaml /var/lib/kubelet/config.yaml && if [ -n "$(ls -A %[1]s/manifests)" ]; then cp -f %[1]s/manifests/* /etc/kubernetes/manifests/; fi && systemctl daemon-reload && systemctl restart kubelet`
+	// RemoteEtcdUpgraded print true if the image of etcd differs from the one backed up before upgrade.
+	RemoteEtcdUpgraded = `if [ -f %[1]s/manifests/etcd.yaml ] && [ -f /etc/kubernetes/manifests/etcd.yaml ] && [ "$(grep image: %[1]s/manifests/etcd.yaml)" != "$(grep image: /etc/kubernetes/manifests/etcd.yaml)" ]; then echo true; fi`
+	RemoteApplyAddons  = `kubeadm init phase addon all --config=%s/etc/kubeadm.yml`
+	// the prefix of the etcd snapshot saved before upgrade.
+	preUpgradeSnapshot = "pre-upgrade"
+	// the path of the etcd snapshot saved before upgrade is recorded in the backup dir of the snapshot host,
+	// so the rerun upgrade keeps it and the rollback restores exactly it.
+	RemoteRecordUpgradeSnapshot = `mkdir -p %[1]s && echo %[2]s > %[1]s/etcd-snapshot`
+	RemoteGetUpgradeSnapshot    = `if [ -f %[1]s/etcd-snapshot ] && [ -f "$(cat %[1]s/etcd-snapshot)" ]; then cat %[1]s/etcd-snapshot; fi`
+
+	// the label of the control plane static pods created by kubeadm.
+	controlPlaneLabel = "tier=control-plane"
 )
 
-func (k *KubeadmRuntime) upgrade() error {
-	var err error
-	binpath := filepath.Join(k.getRootfs(), `bin`)
+// DrainOptions are the options of kubectl drain before upgrading a node.
+type DrainOptions struct {
+	Timeout time.Duration
+	// GracePeriod is the seconds given to pods to terminate, the one of pod is used if it is negative.
+	GracePeriod int
+	// DeleteEmptyDirData continue even if there are pods using emptyDir, whose data will be deleted.
+	DeleteEmptyDirData bool
+	// Force continue even if there are pods not managed by a controller.
+	Force bool
+}
+
+var (
+	Drain = DrainOptions{Timeout: 5 * time.Minute, GracePeriod: -1}
+	// UpgradeWaitTimeout is the max time to wait for an upgraded node to be Ready and the evicted pods to be scheduled.
+	UpgradeWaitTimeout = 5 * time.Minute
+)
 
-	err = k.upgradeFirstMaster(k.GetMaster0IP(), binpath, k.getKubeVersion())
+// Args return the args of kubectl drain, kubectl before v1.20 uses --delete-local-data instead of --delete-emptydir-data.
+func (d DrainOptions) Args(kubectlVersion string) string {
+	args := fmt.Sprintf(" --timeout=%s --grace-period=%d", d.Timeout, d.GracePeriod)
+	if d.DeleteEmptyDirData {
+		if VersionCompare(kubectlVersion, V1200) {
+			args += " --delete-emptydir-data"
+		} else {
+			args += " --delete-local-data"
+		}
+	}
+	if d.Force {
+		args += " --force"
+	}
+	return args
+}
+
+// ValidateUpgradeVersion check the upgrade is allowed by kubeadm, which does not support downgrade and skipping minor versions.
+func ValidateUpgradeVersion(current, target string) error {
+	if !VersionCompare(target, current) {
+		return fmt.Errorf("downgrade from %s to %s is not supported", current, target)
+	}
+	parts := strings.Split(strings.Split(strings.TrimPrefix(current, "v"), "-")[0], ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid version %s", current)
+	}
+	minor, err := strconv.Atoi(parts[1])
 	if err != nil {
+		return fmt.Errorf("invalid version %s: %v", current, err)
+	}
+	if VersionCompare(target, fmt.Sprintf("v%s.%d.0", parts[0], minor+2)) {
+		return fmt.Errorf("upgrade from %s to %s skips minor versions, upgrade to v%s.%d first", current, target, parts[0], minor+1)
+	}
+	return nil
+}
+
+// GetClusterKubeletVersion return the lowest kubelet version of the nodes. The apiserver of master0 is upgraded first,
+// so the version of a partly upgraded cluster is the one of the nodes not upgraded yet.
+func GetClusterKubeletVersion(client *k8s.Client) (string, error) {
+	nodes, err := client.ListNodes()
+	if err != nil {
+		return "", err
+	}
+	var lowest string
+	for _, node := range nodes.Items {
+		version := node.Status.NodeInfo.KubeletVersion
+		if lowest == "" || !VersionCompare(version, lowest) {
+			lowest = version
+		}
+	}
+	if lowest == "" {
+		return "", fmt.Errorf("no node is found in the cluster")
+	}
+	return lowest, nil
+}
+
+// upgrade the hosts one by one, master0 first. The etcd is snapshotted once before upgrade, every node is drained,
+// upgraded and then uncordoned after it is Ready, and the next node is not upgraded until the evicted pods are scheduled.
+// It stops at the first failed host, the hosts already running the target version are skipped when it is run again.
+func (k *KubeadmRuntime) upgrade() error {
+	if err := k.MergeKubeadmConfig(); err != nil {
 		return err
 	}
-	err = k.upgradeOtherMasters(k.GetMasterIPList()[1:], binpath)
+	version := k.getKubeVersion()
+	client, err := k8s.Newk8sClient()
 	if err != nil {
 		return err
 	}
-	err = k.upgradeNodes(k.GetNodeIPList(), binpath)
+	current, err := GetClusterKubeletVersion(client)
 	if err != nil {
 		return err
 	}
+	backupDir := k.getUpgradeBackupDir(current)
+	criSocket, err := k.getCRISocket()
+	if err != nil {
+		return err
+	}
+	if err := k.CmdAsyncHosts(k.getEtcdHosts(), fmt.Sprintf(RemoteBackupEtcdctl, backupDir, criSocket, k.getRootfs())); err != nil {
+		return fmt.Errorf("failed to back up etcdctl before upgrade: %v", err)
+	}
+	snapshotHost, snapshot, err := k.snapshotEtcdBeforeUpgrade(backupDir)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot etcd before upgrade: %v", err)
+	}
+
+	hostsErr := &HostsError{Failed: map[string]error{}}
+	masters := k.GetMasterIPList()
+	for i, host := range append(masters, k.GetNodeIPList()...) {
+		subcommand := "node"
+		if i == 0 {
+			subcommand = fmt.Sprintf("apply %s -y", version)
+		}
+		if err := k.upgradeHost(client, host, version, subcommand, backupDir, i < len(masters)); err != nil {
+			hostsErr.Failed[host] = err
+			logger.Error("failed to upgrade %s, the hosts after it are not upgraded, etcd snapshot %s on %s could be used to restore etcd", host, snapshot, snapshotHost)
+			return hostsErr
+		}
+		hostsErr.Succeeded = append(hostsErr.Succeeded, host)
+	}
 	return nil
 }
 
-func (k *KubeadmRuntime) upgradeFirstMaster(IP string, binpath, version string) error {
-	var firstMasterCmds = []string{
-		fmt.Sprintf(chmodCmd, binpath),
-		fmt.Sprintf(mvCmd, binpath),
-		drainCmd,
-		fmt.Sprintf(upgradeCmd, strings.Join([]string{`apply`, version, `-y`}, " ")),
-		restartCmd,
-		uncordonCmd,
+func (k *KubeadmRuntime) upgradeHost(client *k8s.Client, host, version, subcommand, backupDir string, isMaster bool) error {
+	name, err := getNodeName(client, host)
+	if err != nil {
+		return err
+	}
+	node, err := client.GetNode(name)
+	if err != nil {
+		return err
+	}
+	if node.Status.NodeInfo.KubeletVersion == version && isNodeReady(node) {
+		logger.Info("%s is already upgraded to %s, skip it", host, version)
+		return nil
 	}
-	ssh, err := k.getHostSSHClient(IP)
+
+	logger.Info("Start to upgrade %s to %s", host, version)
+	ssh, err := k.getHostSSHClient(host)
 	if err != nil {
-		return fmt.Errorf("upgrade master0 failed %v", err)
+		return err
+	}
+	master0SSH, err := k.getHostSSHClient(k.GetMaster0IP())
+	if err != nil {
+		return err
+	}
+	binpath := filepath.Join(k.getRootfs(), `bin`)
+	if err := ssh.CmdAsync(host, fmt.Sprintf(RemoteBackupUpgrade, backupDir)); err != nil {
+		return fmt.Errorf("failed to back up %s before upgrade: %v", host, err)
+	}
+	if err := ssh.CmdAsync(host, fmt.Sprintf(chmodCmd, binpath), fmt.Sprintf(mvCmd, binpath)); err != nil {
+		return err
+	}
+	unscheduled, err := unscheduledPods(client)
+	if err != nil {
+		return err
 	}
-	return ssh.CmdAsync(IP, firstMasterCmds...)
+	if err := master0SSH.CmdAsync(k.GetMaster0IP(), fmt.Sprintf(drainCmd, name, Drain.Args(version))); err != nil {
+		return fmt.Errorf("failed to drain %s: %v", name, err)
+	}
+	if err := ssh.CmdAsync(host, fmt.Sprintf(upgradeCmd, subcommand), restartCmd); err != nil {
+		return err
+	}
+	if err := waitNodeUpgraded(client, name, version, isMaster); err != nil {
+		return err
+	}
+	if err := master0SSH.CmdAsync(k.GetMaster0IP(), fmt.Sprintf(uncordonCmd, name)); err != nil {
+		return fmt.Errorf("failed to uncordon %s: %v", name, err)
+	}
+	return waitPodsScheduled(client, unscheduled)
+}
+
+// snapshotEtcdBeforeUpgrade save the etcd snapshot before upgrade and record it in backupDir,
+// the recorded one is returned if the upgrade is rerun.
+func (k *KubeadmRuntime) snapshotEtcdBeforeUpgrade(backupDir string) (host, snapshot string, err error) {
+	host = k.getEtcdHosts()[0]
+	if snapshot, err = k.getUpgradeSnapshot(host, backupDir); err != nil {
+		return "", "", err
+	}
+	if snapshot != "" {
+		logger.Info("etcd snapshot %s on %s saved by the last upgrade is kept", snapshot, host)
+		return host, snapshot, nil
+	}
+	if host, snapshot, err = k.snapshotEtcd(preUpgradeSnapshot); err != nil {
+		return "", "", err
+	}
+	ssh, err := k.getHostSSHClient(host)
+	if err != nil {
+		return "", "", err
+	}
+	if err := ssh.CmdAsync(host, fmt.Sprintf(RemoteRecordUpgradeSnapshot, backupDir, snapshot)); err != nil {
+		return "", "", err
+	}
+	logger.Info("etcd snapshot %s is saved on %s before upgrade", snapshot, host)
+	return host, snapshot, nil
 }
 
-func (k *KubeadmRuntime) upgradeOtherMasters(IPs []string, binpath string) error {
-	var otherMasterCmds = []string{
-		fmt.Sprintf(chmodCmd, binpath),
-		fmt.Sprintf(mvCmd, binpath),
-		drainCmd,
-		fmt.Sprintf(upgradeCmd, `node`),
-		restartCmd,
-		uncordonCmd,
+// getUpgradeSnapshot return the etcd snapshot recorded in backupDir of host, it is empty if not recorded or removed.
+func (k *KubeadmRuntime) getUpgradeSnapshot(host, backupDir string) (string, error) {
+	snapshot, err := k.CmdToString(host, fmt.Sprintf(RemoteGetUpgradeSnapshot, backupDir), "")
+	if err != nil {
+		return "", err
 	}
-	var err error
-	for _, ip := range IPs {
-		ssh, err := k.getHostSSHClient(ip)
+	return strings.TrimSpace(snapshot), nil
+}
+
+// /var/lib/sealer/data/my-cluster/upgrade-backup/v1.19.8 on hosts, the version is the one before upgrade.
+func (k *KubeadmRuntime) getUpgradeBackupDir(version string) string {
+	return filepath.Join(k.getBasePath(), "upgrade-backup", version)
+}
+
+// rollbackUpgrade restore the hosts upgraded from the version of ClusterImage with the backups saved by upgrade,
+// workers first so that no kubelet is newer than apiserver, the hosts without backup are not upgraded and skipped.
+// The etcd is restored from the snapshot saved before upgrade after all the hosts are rolled back if restoreEtcd is true,
+// it is required if the stacked etcd is upgraded, the old etcd could not run with the data of the new one.
+// The addons upgraded by kubeadm are applied with the config of the version rolled back to at last.
+func (k *KubeadmRuntime) rollbackUpgrade(restoreEtcd bool) error {
+	if err := k.MergeKubeadmConfig(); err != nil {
+		return err
+	}
+	backupDir := k.getUpgradeBackupDir(k.getKubeVersion())
+	if len(k.GetEtcdIPList()) == 0 && !restoreEtcd {
+		upgraded, err := k.CmdToString(k.GetMaster0IP(), fmt.Sprintf(RemoteEtcdUpgraded, backupDir), "")
 		if err != nil {
-			return fmt.Errorf("upgrade other masters failed: %v", err)
+			return err
+		}
+		if strings.TrimSpace(upgraded) == "true" {
+			return fmt.Errorf("etcd is upgraded and could not be rolled back without restoring it from the snapshot saved before upgrade")
 		}
-		err = ssh.CmdAsync(ip, otherMasterCmds...)
+	}
+	hostsErr := &HostsError{Failed: map[string]error{}}
+	for _, host := range append(k.GetNodeIPList(), k.GetMasterIPList()...) {
+		if err := k.rollbackHost(host, backupDir); err != nil {
+			logger.Error("failed to roll back %s: %v", host, err)
+			hostsErr.Failed[host] = err
+			continue
+		}
+		hostsErr.Succeeded = append(hostsErr.Succeeded, host)
+	}
+	if len(hostsErr.Failed) != 0 {
+		return hostsErr
+	}
+	if restoreEtcd {
+		host := k.getEtcdHosts()[0]
+		snapshot, err := k.getUpgradeSnapshot(host, backupDir)
 		if err != nil {
 			return err
 		}
+		if snapshot == "" {
+			return fmt.Errorf("no etcd snapshot saved before upgrade is recorded in %s on %s", backupDir, host)
+		}
+		if err := k.restoreEtcd(snapshot, backupDir); err != nil {
+			return err
+		}
 	}
-	return err
+	return k.applyAddons()
 }
 
-func (k *KubeadmRuntime) upgradeNodes(IPs []string, binpath string) error {
-	var nodeCmds = []string{
-		fmt.Sprintf(chmodCmd, binpath),
-		fmt.Sprintf(mvCmd, binpath),
-		fmt.Sprintf(upgradeCmd, `node`),
-		restartCmd,
+// applyAddons apply coredns and kube-proxy of the kubeadm config on master0, which replaces the ones upgraded by kubeadm.
+func (k *KubeadmRuntime) applyAddons() error {
+	bs, err := k.generateConfigs()
+	if err != nil {
+		return err
 	}
-	var err error
-	for _, ip := range IPs {
-		ssh, err := k.getHostSSHClient(ip)
+	ssh, err := k.getHostSSHClient(k.GetMaster0IP())
+	if err != nil {
+		return err
+	}
+	logger.Info("Start to apply the addons of %s", k.getKubeVersion())
+	return ssh.CmdAsync(k.GetMaster0IP(), fmt.Sprintf(WriteKubeadmConfigCmd, k.getRootfs(), string(bs)), fmt.Sprintf(RemoteApplyAddons, k.getRootfs()))
+}
+
+func (k *KubeadmRuntime) rollbackHost(host, backupDir string) error {
+	ssh, err := k.getHostSSHClient(host)
+	if err != nil {
+		return err
+	}
+	exist, err := ssh.RemoteDirExist(host, filepath.Join(backupDir, "bin"))
+	if err != nil {
+		return err
+	}
+	if !exist {
+		logger.Info("%s has no backup in %s, it is not upgraded, skip it", host, backupDir)
+		return nil
+	}
+	logger.Info("Start to roll back %s to %s", host, k.getKubeVersion())
+	return ssh.CmdAsync(host, fmt.Sprintf(RemoteRollbackUpgrade, backupDir))
+}
+
+// getNodeName find the name of node by its internal ip.
+func getNodeName(client *k8s.Client, host string) (string, error) {
+	node, err := findNode(client, host)
+	if err != nil {
+		return "", err
+	}
+	if node == nil {
+		return "", fmt.Errorf("node of %s is not found in cluster", host)
+	}
+	return node.Name, nil
+}
+
+// findNode return the node whose internal ip is the host, it is nil if not found.
+func findNode(client *k8s.Client, host string) (*v1.Node, error) {
+	nodes, err := client.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+	return findNodeInList(nodes, host), nil
+}
+
+func findNodeInList(nodes *v1.NodeList, host string) *v1.Node {
+	ip := utils.GetHostIP(host)
+	for i, node := range nodes.Items {
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == v1.NodeInternalIP && addr.Address == ip {
+				return &nodes.Items[i]
+			}
+		}
+	}
+	return nil
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == v1.NodeReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func isPodReady(pod v1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// waitNodeUpgraded wait for the kubelet of node running the version to be Ready, and the control plane pods of master to be Ready.
+func waitNodeUpgraded(client *k8s.Client, name, version string, isMaster bool) error {
+	err := wait.PollImmediate(5*time.Second, UpgradeWaitTimeout, func() (bool, error) {
+		node, err := client.GetNode(name)
 		if err != nil {
-			return fmt.Errorf("upgrade node failed: %v", err)
+			logger.Debug("failed to get node %s: %v", name, err)
+			return false, nil
+		}
+		if node.Status.NodeInfo.KubeletVersion != version || !isNodeReady(node) {
+			return false, nil
+		}
+		return !isMaster || isControlPlaneReady(client, name), nil
+	})
+	if err != nil {
+		return fmt.Errorf("node %s is not Ready with %s in %s: %v", name, version, UpgradeWaitTimeout, err)
+	}
+	return nil
+}
+
+// isControlPlaneReady return whether the control plane pods of the master are all Ready.
+func isControlPlaneReady(client *k8s.Client, name string) bool {
+	pods, err := client.ListPods(metav1.NamespaceSystem, metav1.ListOptions{
+		LabelSelector: controlPlaneLabel,
+		FieldSelector: "spec.nodeName=" + name,
+	})
+	if err != nil {
+		logger.Debug("failed to list control plane pods of %s: %v", name, err)
+		return false
+	}
+	if len(pods.Items) == 0 {
+		return false
+	}
+	for _, pod := range pods.Items {
+		if !isPodReady(pod) {
+			return false
+		}
+	}
+	return true
+}
+
+// unscheduledPods return the namespace/name of the pods pending for scheduling.
+func unscheduledPods(client *k8s.Client) (map[string]bool, error) {
+	pods, err := client.ListPods(metav1.NamespaceAll, metav1.ListOptions{FieldSelector: "status.phase=Pending"})
+	if err != nil {
+		return nil, err
+	}
+	unscheduled := map[string]bool{}
+	for _, pod := range pods.Items {
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == v1.PodScheduled && cond.Status != v1.ConditionTrue {
+				unscheduled[pod.Namespace+"/"+pod.Name] = true
+			}
 		}
-		err = ssh.CmdAsync(ip, nodeCmds...)
+	}
+	return unscheduled, nil
+}
+
+// waitPodsScheduled wait for the pods evicted by drain to be scheduled, the pods unscheduled before drain are ignored.
+func waitPodsScheduled(client *k8s.Client, ignored map[string]bool) error {
+	err := wait.PollImmediate(5*time.Second, UpgradeWaitTimeout, func() (bool, error) {
+		unscheduled, err := unscheduledPods(client)
 		if err != nil {
-			return err
+			logger.Debug("failed to list pending pods: %v", err)
+			return false, nil
+		}
+		for pod := range unscheduled {
+			if !ignored[pod] {
+				logger.Debug("pod %s is not scheduled", pod)
+				return false, nil
+			}
 		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("there are pods not scheduled in %s: %v", UpgradeWaitTimeout, err)
 	}
-	return err
+	return nil
 }
@@ -0,0 +1,78 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateUpgradeVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		target  string
+		wantErr bool
+	}{
+		{"patch", "v1.19.8", "v1.19.16", false},
+		{"next minor", "v1.19.8", "v1.20.4", false},
+		{"skip minor", "v1.19.8", "v1.21.1", true},
+		{"downgrade", "v1.20.4", "v1.19.8", true},
+		{"patch downgrade", "v1.19.16", "v1.19.8", true},
+		{"same version", "v1.19.8", "v1.19.8", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateUpgradeVersion(tt.current, tt.target); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpgradeVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		v1   string
+		v2   string
+		want bool
+	}{
+		{"v1.19.16", "v1.19.8", true},
+		{"v1.19.8", "v1.19.16", false},
+		{"v1.9.0", "v1.10.0", false},
+		{"v1.20.0-rc.1", "v1.20.0", true},
+		{"v1.20", "v1.19.8", false},
+	}
+	for _, tt := range tests {
+		if got := VersionCompare(tt.v1, tt.v2); got != tt.want {
+			t.Errorf("VersionCompare(%s, %s) = %v, want %v", tt.v1, tt.v2, got, tt.want)
+		}
+	}
+}
+
+func TestDrainOptions_Args(t *testing.T) {
+	d := DrainOptions{Timeout: 2 * time.Minute, GracePeriod: 30, DeleteEmptyDirData: true, Force: true}
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"v1.19.8", " --timeout=2m0s --grace-period=30 --delete-local-data --force"},
+		{"v1.20.4", " --timeout=2m0s --grace-period=30 --delete-emptydir-data --force"},
+	}
+	for _, tt := range tests {
+		if got := d.Args(tt.version); got != tt.want {
+			t.Errorf("Args(%s) = %s, want %s", tt.version, got, tt.want)
+		}
+	}
+}
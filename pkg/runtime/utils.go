@@ -23,6 +23,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
@@ -57,18 +58,16 @@ func VersionCompare(v1, v2 string) bool {
 		logger.Error("error version format %s %s", v1, v2)
 		return false
 	}
-	if v1List[0] > v2List[0] {
-		return true
-	} else if v1List[0] < v2List[0] {
-		return false
-	}
-	if v1List[1] > v2List[1] {
-		return true
-	} else if v1List[1] < v2List[1] {
-		return false
-	}
-	if v1List[2] > v2List[2] {
-		return true
+	for i := range v1List {
+		n1, err1 := strconv.Atoi(v1List[i])
+		n2, err2 := strconv.Atoi(v2List[i])
+		if err1 != nil || err2 != nil {
+			logger.Error("error version format %s %s", v1, v2)
+			return false
+		}
+		if n1 != n2 {
+			return n1 > n2
+		}
 	}
 	return true
 }
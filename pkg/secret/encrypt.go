@@ -0,0 +1,167 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alibaba/sealer/common"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+const (
+	encryptedPrefix = "ENC["
+	encryptedSuffix = "]"
+	keySize         = 32
+)
+
+// KeyFile is the local AES-256 key, once it exists the passwords and env values of the saved Clusterfiles are encrypted.
+var KeyFile = common.GetSecretKeyFile()
+
+func KeyExists() bool {
+	_, err := os.Stat(KeyFile)
+	return err == nil
+}
+
+// GenerateKey create the local key if not exist.
+func GenerateKey() error {
+	if KeyExists() {
+		return nil
+	}
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return fmt.Errorf("failed to generate secret key: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(KeyFile), 0700); err != nil {
+		return fmt.Errorf("failed to create dir of secret key: %v", err)
+	}
+	return ioutil.WriteFile(KeyFile, []byte(base64.StdEncoding.EncodeToString(key)), 0600)
+}
+
+func loadKey() ([]byte, error) {
+	data, err := ioutil.ReadFile(KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret key %s: %v", KeyFile, err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid secret key %s", KeyFile)
+	}
+	return key, nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+	key, err := loadKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// Encrypt encrypt value with the local key to ENC[base64 of nonce and ciphertext].
+func Encrypt(value string) (string, error) {
+	if value == "" || IsEncrypted(value) {
+		return value, nil
+	}
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + encryptedSuffix, nil
+}
+
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), encryptedSuffix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %v", err)
+	}
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value, the secret key %s may be changed: %v", KeyFile, err)
+	}
+	return string(plain), nil
+}
+
+// EncryptCluster returns a copy of cluster whose ssh passwords and env values are encrypted,
+// references of env values are kept since they are not secrets.
+func EncryptCluster(cluster *v2.Cluster) (*v2.Cluster, error) {
+	c := cluster.DeepCopy()
+	if err := encryptSSHAndEnv(&c.Spec.SSH.Passwd, &c.Spec.SSH.PkPasswd, c.Spec.Env); err != nil {
+		return nil, err
+	}
+	for i := range c.Spec.Hosts {
+		host := &c.Spec.Hosts[i]
+		if err := encryptSSHAndEnv(&host.SSH.Passwd, &host.SSH.PkPasswd, host.Env); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func encryptSSHAndEnv(passwd, pkPasswd *string, env []string) error {
+	var err error
+	if *passwd, err = Encrypt(*passwd); err != nil {
+		return err
+	}
+	if *pkPasswd, err = Encrypt(*pkPasswd); err != nil {
+		return err
+	}
+	for i, e := range env {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if _, ok := ParseRef(kv[1]); ok {
+			continue
+		}
+		if kv[1], err = Encrypt(kv[1]); err != nil {
+			return err
+		}
+		env[i] = kv[0] + "=" + kv[1]
+	}
+	return nil
+}
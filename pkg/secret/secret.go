@@ -0,0 +1,137 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	v1 "github.com/alibaba/sealer/types/api/v1"
+)
+
+// refRegexp matches env values like ${env:REGISTRY_PASSWORD}, ${file:/path/to/passwd} and ${command:pass show registry}.
+var refRegexp = regexp.MustCompile(`^\$\{(env|file|command):(.+)\}$`)
+
+// resolved caches the values of sources, ssh clients are created for every remote command
+// and a command source may be slow or interactive.
+var resolved sync.Map
+
+// Validate check that exactly one field of src is set.
+func Validate(src *v1.ValueSource) error {
+	n := 0
+	for _, v := range []string{src.Env, src.File, src.Command} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("exactly one of env, file and command must be set")
+	}
+	return nil
+}
+
+// Resolve read the value of src.
+func Resolve(src *v1.ValueSource) (string, error) {
+	if err := Validate(src); err != nil {
+		return "", err
+	}
+	if v, ok := resolved.Load(*src); ok {
+		return v.(string), nil
+	}
+
+	var value string
+	switch {
+	case src.Env != "":
+		v, ok := os.LookupEnv(src.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", src.Env)
+		}
+		value = v
+	case src.File != "":
+		data, err := ioutil.ReadFile(src.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file %s: %v", src.File, err)
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+	case src.Command != "":
+		var stderr bytes.Buffer
+		cmd := exec.Command("/bin/sh", "-c", src.Command) // #nosec
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to run secret command %q: %v, %s", src.Command, err, strings.TrimSpace(stderr.String()))
+		}
+		value = strings.TrimSpace(string(out))
+	}
+	resolved.Store(*src, value)
+	return value, nil
+}
+
+// ParseRef parse the value source of env value like ${env:NAME}, ok is false if value is not a reference.
+func ParseRef(value string) (src *v1.ValueSource, ok bool) {
+	m := refRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return nil, false
+	}
+	switch m[1] {
+	case "env":
+		return &v1.ValueSource{Env: m[2]}, true
+	case "file":
+		return &v1.ValueSource{File: m[2]}, true
+	default:
+		return &v1.ValueSource{Command: m[2]}, true
+	}
+}
+
+// ResolveValue decrypt the encrypted value and resolve the reference value, other values are returned as is.
+func ResolveValue(value string) (string, error) {
+	if IsEncrypted(value) {
+		return Decrypt(value)
+	}
+	if src, ok := ParseRef(value); ok {
+		return Resolve(src)
+	}
+	return value, nil
+}
+
+// ResolveSSH replace the passwords of ssh with the values of their sources, and decrypt the encrypted ones.
+// The source takes precedence over the plain password.
+func ResolveSSH(ssh *v1.SSH) error {
+	var err error
+	if ssh.Passwd, err = resolveField(ssh.Passwd, ssh.PasswdFrom); err != nil {
+		return fmt.Errorf("failed to resolve ssh passwd: %v", err)
+	}
+	if ssh.PkPasswd, err = resolveField(ssh.PkPasswd, ssh.PkPasswdFrom); err != nil {
+		return fmt.Errorf("failed to resolve ssh pkPasswd: %v", err)
+	}
+	ssh.PasswdFrom, ssh.PkPasswdFrom = nil, nil
+	return nil
+}
+
+func resolveField(value string, src *v1.ValueSource) (string, error) {
+	if src != nil {
+		return Resolve(src)
+	}
+	if IsEncrypted(value) {
+		return Decrypt(value)
+	}
+	return value, nil
+}
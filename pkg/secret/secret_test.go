@@ -0,0 +1,124 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secret
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/alibaba/sealer/types/api/v1"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+func TestResolveValue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	passwdFile := filepath.Join(dir, "passwd")
+	if err := ioutil.WriteFile(passwdFile, []byte("file-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("SEALER_TEST_SECRET", "env-secret"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("SEALER_TEST_SECRET")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "a;b", "a;b", false},
+		{"env", "${env:SEALER_TEST_SECRET}", "env-secret", false},
+		{"env not set", "${env:SEALER_TEST_NOT_SET}", "", true},
+		{"file", "${file:" + passwdFile + "}", "file-secret", false},
+		{"command", "${command:echo command-secret}", "command-secret", false},
+		{"command failed", "${command:exit 1}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveValue() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSSH(t *testing.T) {
+	ssh := &v1.SSH{Passwd: "ignored", PasswdFrom: &v1.ValueSource{Command: "echo passwd"}, PkPasswd: "pk"}
+	if err := ResolveSSH(ssh); err != nil {
+		t.Fatalf("ResolveSSH() error = %v", err)
+	}
+	if ssh.Passwd != "passwd" || ssh.PkPasswd != "pk" || ssh.PasswdFrom != nil {
+		t.Errorf("ResolveSSH() = %+v", ssh)
+	}
+	if err := ResolveSSH(&v1.SSH{PasswdFrom: &v1.ValueSource{Env: "A", File: "b"}}); err == nil {
+		t.Errorf("ResolveSSH() expect error for the source with two fields")
+	}
+}
+
+func TestEncryptCluster(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(keyFile string) { KeyFile = keyFile }(KeyFile)
+	KeyFile = filepath.Join(dir, "secret.key")
+	if err := GenerateKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := &v2.Cluster{}
+	cluster.Spec.SSH.Passwd = "passwd"
+	cluster.Spec.Env = []string{"IP=192.168.0.2;192.168.0.3", "Token=${env:TOKEN}"}
+	cluster.Spec.Hosts = []v2.Host{{IPS: []string{"192.168.0.2"}, SSH: v1.SSH{PkPasswd: "pk"}}}
+	encrypted, err := EncryptCluster(cluster)
+	if err != nil {
+		t.Fatalf("EncryptCluster() error = %v", err)
+	}
+	if cluster.Spec.SSH.Passwd != "passwd" {
+		t.Errorf("EncryptCluster() modified the origin cluster")
+	}
+	if encrypted.Spec.Env[1] != "Token=${env:TOKEN}" {
+		t.Errorf("EncryptCluster() encrypted the reference %s", encrypted.Spec.Env[1])
+	}
+	for _, c := range []struct{ encrypted, want string }{
+		{encrypted.Spec.SSH.Passwd, "passwd"},
+		{encrypted.Spec.Env[0][len("IP="):], "192.168.0.2;192.168.0.3"},
+		{encrypted.Spec.Hosts[0].SSH.PkPasswd, "pk"},
+	} {
+		if !IsEncrypted(c.encrypted) {
+			t.Errorf("%s is not encrypted", c.encrypted)
+			continue
+		}
+		got, err := Decrypt(c.encrypted)
+		if err != nil {
+			t.Fatalf("Decrypt() error = %v", err)
+		}
+		if got != c.want {
+			t.Errorf("Decrypt() = %s, want %s", got, c.want)
+		}
+	}
+}
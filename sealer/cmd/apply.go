@@ -15,31 +15,83 @@
 package cmd
 
 import (
+	"github.com/alibaba/sealer/apply/processor"
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/clusterfile"
+	"github.com/alibaba/sealer/pkg/lint"
 	"github.com/alibaba/sealer/pkg/runtime"
+	"github.com/alibaba/sealer/pkg/secret"
 	"github.com/spf13/cobra"
 
 	"github.com/alibaba/sealer/apply"
 )
 
-var clusterFile string
+var (
+	clusterFiles        []string
+	applyDryRun         bool
+	applyEncryptSecrets bool
+)
 
 // applyCmd represents the apply command
 var applyCmd = &cobra.Command{
-	Use:     "apply",
-	Short:   "apply a kubernetes cluster",
-	Example: `sealer apply -f Clusterfile`,
-	Args:    cobra.NoArgs,
+	Use:   "apply",
+	Short: "apply a kubernetes cluster",
+	Example: `sealer apply -f Clusterfile
+print the hosts to join or delete, upgrade, Config files and plugins to run, without touching any host:
+	sealer apply -f Clusterfile --dry-run
+resume an unfinished cluster creation from the given phase:
+	sealer apply -f Clusterfile --from-phase Join
+discard the checkpoints of an unfinished cluster creation and start over:
+	sealer apply -f Clusterfile --restart
+encrypt the ssh passwords and env values of the Clusterfile saved in ~/.sealer with a local key:
+	sealer apply -f Clusterfile --encrypt-secrets
+apply the overlay of prod environment to the base Clusterfile:
+	sealer apply -f Clusterfile -f overlays/prod.yaml`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		clusterFile := clusterFiles[0]
+		if len(clusterFiles) > 1 {
+			rendered, err := clusterfile.RenderToWorkDir(clusterFiles[0], clusterFiles[1:]...)
+			if err != nil {
+				return err
+			}
+			logger.Info("rendered Clusterfile with overlays is saved to %s", rendered)
+			clusterFile = rendered
+		}
+		if err := lint.Check(clusterFile); err != nil {
+			return err
+		}
 		applier, err := apply.NewApplierFromFile(clusterFile)
 		if err != nil {
 			return err
 		}
+		if applyDryRun {
+			plan, err := applier.Plan()
+			if err != nil {
+				return err
+			}
+			plan.Print(common.StdOut)
+			return nil
+		}
+		if applyEncryptSecrets {
+			if err := secret.GenerateKey(); err != nil {
+				return err
+			}
+		}
 		return applier.Apply()
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(applyCmd)
-	applyCmd.Flags().StringVarP(&clusterFile, "Clusterfile", "f", "Clusterfile", "apply a kubernetes cluster")
+	applyCmd.Flags().StringArrayVarP(&clusterFiles, "Clusterfile", "f", []string{"Clusterfile"}, "apply a kubernetes cluster, the files after the first one are overlays applied to it in order")
 	applyCmd.Flags().BoolVar(&runtime.ForceDelete, "force", false, "We also can input an --force flag to delete cluster by force")
+	applyCmd.Flags().StringVar(&processor.FromPhase, "from-phase", "", "resume the unfinished cluster creation from the given phase, one of Originally,MountRootfs,PreInit,Init,Join,PreGuest,RunGuest,PostInstall")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "print the execution plan of the difference between current and desired cluster and exit")
+	applyCmd.Flags().BoolVar(&processor.Restart, "restart", false, "discard the checkpoints of the unfinished cluster creation and run it from the beginning")
+	applyCmd.Flags().BoolVar(&processor.NoRollback, "no-rollback", false, "keep the hosts of a failed scale up as they are for debugging, instead of resetting them and removing them from the cluster")
+	applyCmd.Flags().IntVar(&runtime.Parallelism, "parallelism", 0, "the max number of nodes joined at the same time, no limit by default")
+	applyCmd.Flags().StringVar(&processor.MaxFailedNodes, "max-failed-nodes", "", "the number like 3 or the percentage like 10% of joining nodes allowed to fail, the failed nodes are skipped and left out of the cluster")
+	applyCmd.Flags().BoolVar(&applyEncryptSecrets, "encrypt-secrets", false, "generate the local key ~/.sealer/secret.key if not exist, the ssh passwords and env values of saved Clusterfiles are encrypted with it once it exists")
 }
@@ -18,16 +18,19 @@ package cmd
 import (
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/alibaba/sealer/pkg/clusterfile"
-
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 
 	"github.com/alibaba/sealer/common"
-	"github.com/alibaba/sealer/pkg/runtime"
+	"github.com/alibaba/sealer/pkg/cert"
 )
 
-var altNames string
+var (
+	altNames string
+	renewAll bool
+)
 
 // certCmd represents the cert command
 var certCmd = &cobra.Command{
@@ -46,27 +49,79 @@ var certCmd = &cobra.Command{
     4. kubectl get pod, to check it works or not
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cluster, err := clusterfile.GetDefaultCluster()
+		r, err := getClusterRuntime("")
 		if err != nil {
-			return fmt.Errorf("get default cluster failed, %v", err)
+			return err
 		}
-		clusterFile := clusterfile.NewClusterFile(cluster.GetAnnotationsByKey(common.ClusterfileName))
-		if cluster.GetAnnotationsByKey(common.ClusterfileName) != "" {
-			err = clusterFile.Process()
-			if err != nil {
-				return err
-			}
+		return r.UpdateCert(strings.Split(altNames, ","))
+	},
+}
+
+var certCheckExpirationCmd = &cobra.Command{
+	Use:   "check-expiration",
+	Short: "Check the expiration of the certs on masters and etcd hosts",
+	Long: `check the certs in /etc/kubernetes/pki, the client certs of kubeconfigs in /etc/kubernetes on every master and etcd host,
+and the registry cert, the kubelet.conf is not listed if its client cert is rotated by kubelet.`,
+	Example: `sealer cert check-expiration`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := getCertManager("")
+		if err != nil {
+			return err
 		}
-		r, err := runtime.NewDefaultRuntime(cluster, clusterFile.GetKubeadmConfig())
+		expirations, err := r.CheckCertExpiration()
 		if err != nil {
-			return fmt.Errorf("get default runtime failed, %v", err)
+			return err
 		}
-		return r.UpdateCert(strings.Split(altNames, ","))
+		now := time.Now()
+		table := tablewriter.NewWriter(common.StdOut)
+		table.SetHeader([]string{"HOST", "CERTIFICATE", "EXPIRES", "RESIDUAL TIME", "CERTIFICATE AUTHORITY"})
+		for _, e := range expirations {
+			table.Append([]string{e.Host, e.Name, e.NotAfter.Local().Format(timeDefaultFormat), cert.ResidualTime(e.NotAfter, now), e.CA})
+		}
+		table.Render()
+		return nil
 	},
 }
 
+var certRenewCmd = &cobra.Command{
+	Use:   "renew [--all|name...]",
+	Short: "Renew the certs of the cluster with the existing CAs",
+	Long: fmt.Sprintf(`re-sign the certs with the existing CAs on master0, keeping their subjects, alt names and keys, then distribute them
+to every master and etcd host one by one and restart the static pods using them. The next host is not renewed until the control
+plane of the renewed master is Ready. The old certs are backed up in the cert-backup dir of the cluster on the hosts.
+
+the certs could be renewed: %s`, strings.Join(leafCertNames(), ", ")),
+	Example: `renew all the certs:
+	sealer cert renew --all
+
+renew the apiserver cert and the admin kubeconfig:
+	sealer cert renew apiserver admin.conf`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if renewAll == (len(args) != 0) {
+			return fmt.Errorf("specify either --all or the names of certs to renew")
+		}
+		r, err := getCertManager("")
+		if err != nil {
+			return err
+		}
+		return r.RenewCerts(args)
+	},
+}
+
+func leafCertNames() []string {
+	var names []string
+	for _, f := range cert.LeafCertFiles {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
 func init() {
 	rootCmd.AddCommand(certCmd)
+	certCmd.AddCommand(certCheckExpirationCmd)
+	certCmd.AddCommand(certRenewCmd)
 
 	certCmd.Flags().StringVar(&altNames, "alt-names", "", "add domain or ip in certs, sealer.cool or 10.103.97.2")
+	certRenewCmd.Flags().BoolVar(&renewAll, "all", false, "renew all the certs")
 }
@@ -0,0 +1,71 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/clusterfile"
+	"github.com/alibaba/sealer/utils"
+)
+
+var (
+	convertClusterFile string
+	convertOutput      string
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "convert v1 Clusterfile to v2 format",
+	Long: `convert the v1 Cluster (masters, nodes, provider, network and certSANS) of Clusterfile to v2 hosts format,
+Config, Plugin and kubeadm documents are kept, network and certSANS are moved to ClusterConfiguration and env`,
+	Args: cobra.NoArgs,
+	Example: `
+print the converted Clusterfile:
+	sealer convert -f Clusterfile
+write the converted Clusterfile to a new file:
+	sealer convert -f Clusterfile -o Clusterfile.v2
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := ioutil.ReadFile(filepath.Clean(convertClusterFile))
+		if err != nil {
+			return err
+		}
+		converted, warnings, err := clusterfile.ConvertV1ToV2(data)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s: %v", convertClusterFile, err)
+		}
+		for _, w := range warnings {
+			logger.Warn(w)
+		}
+		if convertOutput == "" {
+			_, err = common.StdOut.Write(converted)
+			return err
+		}
+		return utils.WriteFile(convertOutput, converted)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().StringVarP(&convertClusterFile, "Clusterfile", "f", "Clusterfile", "the v1 Clusterfile to convert")
+	convertCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "the file to write the converted Clusterfile, print to stdout if not set")
+}
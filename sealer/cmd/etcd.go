@@ -0,0 +1,211 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/utils"
+)
+
+var (
+	etcdClusterName  string
+	etcdSnapshotName string
+	etcdForceRestore bool
+)
+
+var etcdCmd = &cobra.Command{
+	Use:   "etcd",
+	Short: "Snapshot, list, check and restore the etcd of cluster",
+	Long: `manage the etcd members on masters, or the external etcd hosts. The snapshots are saved in the etcd-snapshots dir
+of the cluster on the first etcd host, which is master0 if etcd is stacked.`,
+}
+
+var etcdSnapshotCmd = &cobra.Command{
+	Use:     "snapshot",
+	Short:   "Save a snapshot of etcd",
+	Args:    cobra.NoArgs,
+	Example: `sealer etcd snapshot --name before-migration`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := getEtcdManager(etcdClusterName)
+		if err != nil {
+			return err
+		}
+		host, path, err := r.SnapshotEtcd(etcdSnapshotName)
+		if err != nil {
+			return err
+		}
+		logger.Info("etcd snapshot %s is saved on %s", path, host)
+		return nil
+	},
+}
+
+var etcdListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the etcd snapshots",
+	Args:    cobra.NoArgs,
+	Example: `sealer etcd list`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := getEtcdManager(etcdClusterName)
+		if err != nil {
+			return err
+		}
+		snapshots, err := r.ListEtcdSnapshots()
+		if err != nil {
+			return err
+		}
+		table := tablewriter.NewWriter(common.StdOut)
+		table.SetHeader([]string{"NAME", "SIZE", "CREATED"})
+		for _, s := range snapshots {
+			table.Append([]string{s.Name, formatSize(s.Size), s.ModTime.Format(timeDefaultFormat)})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+var etcdStatusCmd = &cobra.Command{
+	Use:     "status",
+	Short:   "Show the status of every etcd member",
+	Args:    cobra.NoArgs,
+	Example: `sealer etcd status`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := getEtcdManager(etcdClusterName)
+		if err != nil {
+			return err
+		}
+		statuses, err := r.EtcdStatus()
+		if err != nil {
+			return err
+		}
+		table := tablewriter.NewWriter(common.StdOut)
+		table.SetHeader([]string{"ENDPOINT", "VERSION", "DB SIZE", "LEADER", "RAFT TERM", "RAFT INDEX", "ERRORS"})
+		for _, s := range statuses {
+			if s.Err != nil {
+				table.Append([]string{s.Endpoint, "", "", "", "", "", s.Err.Error()})
+				continue
+			}
+			table.Append([]string{s.Endpoint, s.Version, formatSize(s.DBSize), strconv.FormatBool(s.IsLeader),
+				strconv.FormatUint(s.RaftTerm, 10), strconv.FormatUint(s.RaftIndex, 10), strings.Join(s.Errors, ", ")})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+var etcdRestoreCmd = &cobra.Command{
+	Use:   "restore <snapshot>",
+	Short: "Restore etcd from a snapshot",
+	Long: `restore every etcd member from the snapshot, which is a name listed by "sealer etcd list", or an absolute path of
+a local file like the one saved by the etcd backup plugin. The kube-apiserver, kube-controller-manager and kube-scheduler
+on all masters and etcd on all etcd hosts are stopped, then etcd is restored with the membership of the etcd hosts and
+started. The control plane is started after etcd is healthy. The data dir before restoring is kept with a suffix.`,
+	Args: cobra.ExactArgs(1),
+	Example: `restore from a snapshot listed by "sealer etcd list":
+	sealer etcd restore snapshot-20211201120000.db
+
+restore from a local snapshot:
+	sealer etcd restore /root/etcd-backup.db --force`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !etcdForceRestore {
+			pass, err := utils.ConfirmOperation(fmt.Sprintf("All the data written after the snapshot %s will be lost, are you sure to restore etcd? ", args[0]))
+			if err != nil {
+				return err
+			}
+			if !pass {
+				return fmt.Errorf("exit the operation of restoring etcd")
+			}
+		}
+		r, err := getEtcdManager(etcdClusterName)
+		if err != nil {
+			return err
+		}
+		if err := r.RestoreEtcd(args[0]); err != nil {
+			return err
+		}
+		logger.Info("etcd is restored from %s", args[0])
+		return nil
+	},
+}
+
+var etcdScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Apply the scheduled etcd backup policy in cluster env to the etcd hosts",
+	Long: `install or update a systemd timer on every etcd host saving a snapshot of its member every EtcdBackupInterval
+to EtcdBackupDir, and removing the oldest ones more than EtcdBackupRetain, by the env of the Clusterfile.
+The timer is removed if EtcdBackupInterval is not set. The policy is also applied on init and to the joined etcd hosts.`,
+	Args: cobra.NoArgs,
+	Example: `add the env to the Clusterfile and apply it:
+	EtcdBackupInterval=6h
+	EtcdBackupRetain=28
+	EtcdBackupDir=/data/etcd-backups
+
+	sealer etcd schedule`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := getEtcdManager(etcdClusterName)
+		if err != nil {
+			return err
+		}
+		return r.ScheduleEtcdBackup()
+	},
+}
+
+var etcdBackupsCmd = &cobra.Command{
+	Use:     "backups",
+	Short:   "List the scheduled etcd backups on every etcd host",
+	Args:    cobra.NoArgs,
+	Example: `sealer etcd backups`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := getEtcdManager(etcdClusterName)
+		if err != nil {
+			return err
+		}
+		backups, err := r.ListEtcdBackups()
+		if err != nil {
+			return err
+		}
+		table := tablewriter.NewWriter(common.StdOut)
+		table.SetHeader([]string{"HOST", "NAME", "SIZE", "REVISION", "CREATED"})
+		for _, b := range backups {
+			revision := "<unknown>"
+			if b.Revision != 0 {
+				revision = strconv.FormatInt(b.Revision, 10)
+			}
+			table.Append([]string{b.Host, b.Name, formatSize(b.Size), revision, b.ModTime.Format(timeDefaultFormat)})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(etcdCmd)
+	etcdCmd.AddCommand(etcdSnapshotCmd)
+	etcdCmd.AddCommand(etcdListCmd)
+	etcdCmd.AddCommand(etcdStatusCmd)
+	etcdCmd.AddCommand(etcdRestoreCmd)
+	etcdCmd.AddCommand(etcdScheduleCmd)
+	etcdCmd.AddCommand(etcdBackupsCmd)
+	etcdCmd.PersistentFlags().StringVarP(&etcdClusterName, "cluster", "c", "", "the name of cluster, the default cluster is used if it is empty")
+	etcdSnapshotCmd.Flags().StringVar(&etcdSnapshotName, "name", "snapshot", "the prefix of snapshot name, followed by the time")
+	etcdRestoreCmd.Flags().BoolVarP(&etcdForceRestore, "force", "f", false, "restore without confirmation")
+}
@@ -19,7 +19,9 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/alibaba/sealer/apply"
+	"github.com/alibaba/sealer/apply/processor"
 	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/runtime"
 )
 
 var clusterName string
@@ -61,4 +63,7 @@ func init() {
 	joinCmd.Flags().StringVarP(&joinArgs.Masters, "masters", "m", "", "set Count or IPList to masters")
 	joinCmd.Flags().StringVarP(&joinArgs.Nodes, "nodes", "n", "", "set Count or IPList to nodes")
 	joinCmd.Flags().StringVarP(&clusterName, "cluster-name", "c", "", "submit one cluster name")
+	joinCmd.Flags().BoolVar(&processor.NoRollback, "no-rollback", false, "keep the hosts which failed to join as they are for debugging, instead of resetting them and removing them from the cluster")
+	joinCmd.Flags().IntVar(&runtime.Parallelism, "parallelism", 0, "the max number of nodes joined at the same time, no limit by default")
+	joinCmd.Flags().StringVar(&processor.MaxFailedNodes, "max-failed-nodes", "", "the number like 3 or the percentage like 10% of joining nodes allowed to fail, the failed nodes are skipped and left out of the cluster")
 }
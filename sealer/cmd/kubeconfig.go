@@ -0,0 +1,137 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/cert"
+	"github.com/alibaba/sealer/pkg/client/k8s"
+	"github.com/alibaba/sealer/pkg/runtime"
+)
+
+var (
+	kubeconfigClusterName string
+	kubeconfigUser        string
+	kubeconfigGroups      []string
+	kubeconfigTTL         string
+	kubeconfigServer      string
+	kubeconfigClusterRole string
+	kubeconfigNamespace   string
+	kubeconfigOutput      string
+)
+
+var kubeconfigCmd = &cobra.Command{
+	Use:   "kubeconfig",
+	Short: "Manage the kubeconfigs of cluster users",
+}
+
+var kubeconfigCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a kubeconfig for a user with a client cert signed by the cluster CA",
+	Long: `create a kubeconfig whose client cert is signed by the kubernetes CA of the cluster, its common name is the user and
+its organizations are the groups. The CA is loaded from the pki dir of the cluster, or from master0 if it is not found.
+With --cluster-role, the cluster role is bound to the user by a ClusterRoleBinding, or a RoleBinding if --namespace is set.
+The client cert can not be revoked before it expires, keep the ttl short.`,
+	Args: cobra.NoArgs,
+	Example: `create a kubeconfig valid for 30 days which could view the resources of namespace dev:
+	sealer kubeconfig create --user alice --group dev --ttl 30d --cluster-role view --namespace dev -o alice.kubeconfig
+
+create a kubeconfig using the VIP or load balancer of apiserver:
+	sealer kubeconfig create --user bob --server https://192.168.0.100:6443`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if kubeconfigUser == "" {
+			return fmt.Errorf("--user is required")
+		}
+		if kubeconfigNamespace != "" && kubeconfigClusterRole == "" {
+			return fmt.Errorf("--namespace should be used with --cluster-role")
+		}
+		ttl, err := cert.ParseValidity(kubeconfigTTL)
+		if err != nil {
+			return err
+		}
+		// the kubeconfig printed to stdout should not be mixed with the logs, which are written to stderr instead.
+		out := common.StdOut
+		if kubeconfigOutput == "" {
+			common.StdOut = common.StdErr
+			defer func() {
+				common.StdOut = out
+			}()
+		}
+		cluster, err := getCluster(kubeconfigClusterName)
+		if err != nil {
+			return err
+		}
+		r, err := getCertManager(cluster.Name)
+		if err != nil {
+			return err
+		}
+		data, err := r.IssueKubeConfig(runtime.KubeConfigOptions{
+			User:   kubeconfigUser,
+			Groups: kubeconfigGroups,
+			TTL:    ttl,
+			Server: kubeconfigServer,
+		})
+		if err != nil {
+			return err
+		}
+
+		if kubeconfigClusterRole != "" {
+			// the admin kubeconfig of the cluster is used through master0, the one of ~/.kube may be of another cluster.
+			client, err := k8s.NewK8sClientFromKubeconfig(filepath.Join(common.DefaultClusterBaseDir(cluster.Name), runtime.AdminConf),
+				"https://"+net.JoinHostPort(cluster.GetMaster0IP(), "6443"))
+			if err != nil {
+				return err
+			}
+			name := fmt.Sprintf("%s:%s:%s", common.ExecBinaryFileName, kubeconfigUser, kubeconfigClusterRole)
+			subjects := []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: kubeconfigUser}}
+			if err := client.ApplyRoleBinding(name, kubeconfigNamespace, kubeconfigClusterRole, subjects); err != nil {
+				return err
+			}
+			logger.Info("cluster role %s is bound to user %s by %s", kubeconfigClusterRole, kubeconfigUser, name)
+		}
+
+		if kubeconfigOutput == "" {
+			_, err = out.Write(data)
+			return err
+		}
+		if err := ioutil.WriteFile(kubeconfigOutput, data, 0600); err != nil {
+			return fmt.Errorf("failed to write kubeconfig to %s: %v", kubeconfigOutput, err)
+		}
+		logger.Info("kubeconfig of user %s is written to %s", kubeconfigUser, kubeconfigOutput)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(kubeconfigCmd)
+	kubeconfigCmd.AddCommand(kubeconfigCreateCmd)
+	kubeconfigCreateCmd.Flags().StringVarP(&kubeconfigClusterName, "cluster", "c", "", "the name of cluster, the default cluster is used if it is empty")
+	kubeconfigCreateCmd.Flags().StringVar(&kubeconfigUser, "user", "", "the user name, which is the common name of client cert")
+	kubeconfigCreateCmd.Flags().StringSliceVar(&kubeconfigGroups, "group", nil, "the groups of user, which are the organizations of client cert")
+	kubeconfigCreateCmd.Flags().StringVar(&kubeconfigTTL, "ttl", "30d", "the validity of client cert, like 1y, 30d or 12h")
+	kubeconfigCreateCmd.Flags().StringVar(&kubeconfigServer, "server", "", "the apiserver endpoint, https://<master0>:6443 by default")
+	kubeconfigCreateCmd.Flags().StringVar(&kubeconfigClusterRole, "cluster-role", "", "bind the cluster role like view or edit to the user")
+	kubeconfigCreateCmd.Flags().StringVarP(&kubeconfigNamespace, "namespace", "n", "", "bind the cluster role in the namespace instead of the whole cluster")
+	kubeconfigCreateCmd.Flags().StringVarP(&kubeconfigOutput, "output", "o", "", "the file to write kubeconfig, it is printed if empty")
+}
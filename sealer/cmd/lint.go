@@ -0,0 +1,54 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/lint"
+)
+
+var lintClusterFile string
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "check the Clusterfile before applying it",
+	Long:  `check every Cluster, Config, Plugin and kubeadm document of the Clusterfile against its schema and semantic rules, report problems as file:line:column`,
+	Args:  cobra.NoArgs,
+	Example: `
+	sealer lint -f Clusterfile
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		diagnostics, err := lint.Lint(lintClusterFile)
+		if err != nil {
+			return err
+		}
+		for _, d := range diagnostics {
+			fmt.Fprintln(common.StdOut, d.String())
+		}
+		if lint.HasError(diagnostics) {
+			return fmt.Errorf("%s is invalid", lintClusterFile)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().StringVarP(&lintClusterFile, "Clusterfile", "f", "Clusterfile", "the Clusterfile to check")
+}
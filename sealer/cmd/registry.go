@@ -0,0 +1,145 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/utils"
+)
+
+var (
+	registryClusterName string
+	registryImageName   string
+	registryForceRemove bool
+)
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "List, push, remove and garbage collect the images in the registry of cluster",
+	Long: `manage the images in the registry of ClusterImage, which is sea.hub:5000 on master0 by default, or on every master
+if RegistryHA is true. The registries are reached by the ip of registry hosts from the sealer host, with the username
+and password in etc/registry.yml of ClusterImage or the auth of local docker. The images are pushed to and removed from
+all the registries.`,
+}
+
+var registryListCmd = &cobra.Command{
+	Use:     "ls",
+	Short:   "List the repositories and tags in the registry",
+	Args:    cobra.NoArgs,
+	Example: `sealer registry ls`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := getRegistryManager(registryClusterName)
+		if err != nil {
+			return err
+		}
+		repos, err := r.ListRegistryImages()
+		if err != nil {
+			return err
+		}
+		table := tablewriter.NewWriter(common.StdOut)
+		table.SetHeader([]string{"REPOSITORY", "TAGS"})
+		for _, repo := range repos {
+			table.Append([]string{repo.Repository, strings.Join(repo.Tags, ", ")})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+var registryPushCmd = &cobra.Command{
+	Use:   "push <image|tar|dir>",
+	Short: "Push an image from a docker archive, an OCI layout or local docker into the registry",
+	Long: `push the images of a docker archive saved by "docker save", an OCI layout dir or tar, or an image of local docker
+which is saved by docker first, to the registry. The images are pushed with their repo tags or ref names without the
+domain, like sea.hub:5000/library/nginx:1.21 for docker.io/library/nginx:1.21, set --name if there is no name or
+another name is wanted.`,
+	Args: cobra.ExactArgs(1),
+	Example: `push an image of local docker:
+	sealer registry push nginx:1.21
+
+push a docker archive as another name:
+	sealer registry push /root/nginx.tar --name sea.hub:5000/app/nginx:1.21
+
+push an OCI layout:
+	sealer registry push /root/nginx-oci --name nginx:1.21`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := getRegistryManager(registryClusterName)
+		if err != nil {
+			return err
+		}
+		return r.PushRegistryImage(args[0], registryImageName)
+	},
+}
+
+var registryRemoveCmd = &cobra.Command{
+	Use:   "rm <image>",
+	Short: "Remove an image from the registry",
+	Long: `remove the manifest of the image from the registry, the other tags of the same manifest are removed as well.
+The deletion should be enabled by storage.delete.enabled in the registry config, and the disk is freed by
+"sealer registry gc".`,
+	Args: cobra.ExactArgs(1),
+	Example: `sealer registry rm sea.hub:5000/library/nginx:1.21
+sealer registry gc`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !registryForceRemove {
+			pass, err := utils.ConfirmOperation(fmt.Sprintf("The pods using %s could not be started on new nodes, are you sure to remove it? ", args[0]))
+			if err != nil {
+				return err
+			}
+			if !pass {
+				return fmt.Errorf("exit the operation of removing %s", args[0])
+			}
+		}
+		r, err := getRegistryManager(registryClusterName)
+		if err != nil {
+			return err
+		}
+		return r.RemoveRegistryImage(args[0])
+	},
+}
+
+var registryGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Run the garbage collection of registry on the registry hosts",
+	Long: `remove the blobs not referenced by any manifest and the untagged manifests from the registry on every registry host.
+The registry is stopped during the garbage collection and started again after it, so the images could not be pulled from
+it at the same time.`,
+	Args:    cobra.NoArgs,
+	Example: `sealer registry gc`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := getRegistryManager(registryClusterName)
+		if err != nil {
+			return err
+		}
+		return r.GarbageCollectRegistry()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(registryCmd)
+	registryCmd.AddCommand(registryListCmd)
+	registryCmd.AddCommand(registryPushCmd)
+	registryCmd.AddCommand(registryRemoveCmd)
+	registryCmd.AddCommand(registryGCCmd)
+	registryCmd.PersistentFlags().StringVarP(&registryClusterName, "cluster", "c", "", "the name of cluster, the default cluster is used if it is empty")
+	registryPushCmd.Flags().StringVar(&registryImageName, "name", "", "the name of image in the registry, only for the archive of one image")
+	registryRemoveCmd.Flags().BoolVarP(&registryForceRemove, "force", "f", false, "remove without confirmation")
+}
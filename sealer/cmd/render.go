@@ -0,0 +1,60 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/clusterfile"
+	"github.com/alibaba/sealer/utils"
+)
+
+var (
+	renderClusterFiles []string
+	renderOutput       string
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "print the Clusterfile with overlays applied",
+	Long: `apply the overlays to the base Clusterfile in order and print the result, which is what sealer apply uses.
+Overlay documents are merged into the base documents of the same kind and metadata.name as JSON merge patches,
+documents of kind JSONPatch apply the JSON patch operations to their target, other documents are added`,
+	Args: cobra.NoArgs,
+	Example: `
+print the Clusterfile of prod environment:
+	sealer render -f Clusterfile -f overlays/prod.yaml
+write it to a file:
+	sealer render -f Clusterfile -f overlays/prod.yaml -o Clusterfile.prod
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := clusterfile.Render(renderClusterFiles[0], renderClusterFiles[1:]...)
+		if err != nil {
+			return err
+		}
+		if renderOutput == "" {
+			_, err = common.StdOut.Write(data)
+			return err
+		}
+		return utils.WriteFile(renderOutput, data)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	renderCmd.Flags().StringArrayVarP(&renderClusterFiles, "Clusterfile", "f", []string{"Clusterfile"}, "the base Clusterfile followed by the overlays")
+	renderCmd.Flags().StringVarP(&renderOutput, "output", "o", "", "the file to write the rendered Clusterfile, print to stdout if not set")
+}
@@ -0,0 +1,72 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/alibaba/sealer/apply"
+	"github.com/alibaba/sealer/utils"
+)
+
+var (
+	repairClusterName string
+	repairForce       bool
+)
+
+var repairCmd = &cobra.Command{
+	Use:   "repair <ip>",
+	Short: "re-provision a broken master or worker in place",
+	Long: `remove the broken master or worker from the cluster and join it again with the same role, labels and taints.
+The etcd member of the master and the node are removed through a healthy master even if the host is unreachable, and the
+host is reset if it is reachable, then the rootfs is mounted and the host is joined again. The host could be a new machine
+with the same ip and ssh config. If master0 is repaired, the registry is moved off it and the healthy master becomes
+master0. Run it again if it failed, the removed parts are skipped.`,
+	Args: cobra.ExactArgs(1),
+	Example: `
+repair the worker of default cluster:
+	sealer repair 192.168.0.5
+repair the master of my-cluster without confirmation:
+	sealer repair 192.168.0.3 -c my-cluster --force
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, err := getCluster(repairClusterName)
+		if err != nil {
+			return err
+		}
+		if !repairForce {
+			pass, err := utils.ConfirmOperation(fmt.Sprintf("%s will be removed from the cluster, reset and joined again, are you sure to repair it? ", args[0]))
+			if err != nil {
+				return err
+			}
+			if !pass {
+				return fmt.Errorf("exit the operation of repairing %s", args[0])
+			}
+		}
+		applier, err := apply.NewApplier(cluster)
+		if err != nil {
+			return err
+		}
+		return applier.Repair(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(repairCmd)
+	repairCmd.Flags().StringVarP(&repairClusterName, "cluster", "c", "", "the name of cluster")
+	repairCmd.Flags().BoolVarP(&repairForce, "force", "f", false, "repair without confirmation")
+}
@@ -0,0 +1,59 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/alibaba/sealer/apply"
+)
+
+var (
+	rollbackClusterName string
+	rollbackRestoreEtcd bool
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "roll back the upgrade of cluster to the previous ClusterImage",
+	Long: `roll back the hosts upgraded by sealer upgrade with the kubelet, kubeadm, kubectl, kubelet config and static pod manifests
+backed up before upgrade, workers first. The rootfs of the previous ClusterImage recorded in the saved Clusterfile is mounted on hosts again.
+With --restore-etcd, etcd is restored from the snapshot saved before upgrade after all the hosts are rolled back,
+it is required if the stacked etcd has been upgraded. The addons are applied with the kubeadm config of the previous version at last.`,
+	Args: cobra.NoArgs,
+	Example: `
+roll back the default cluster:
+	sealer rollback
+roll back the cluster and restore etcd:
+	sealer rollback -c my-cluster --restore-etcd
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, err := getCluster(rollbackClusterName)
+		if err != nil {
+			return err
+		}
+		applier, err := apply.NewApplier(cluster)
+		if err != nil {
+			return err
+		}
+		return applier.Rollback(rollbackRestoreEtcd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+	rollbackCmd.Flags().StringVarP(&rollbackClusterName, "cluster", "c", "", "the name of cluster to roll back")
+	rollbackCmd.Flags().BoolVar(&rollbackRestoreEtcd, "restore-etcd", false, "restore etcd from the snapshot saved before upgrade, the data written after upgrade is lost")
+}
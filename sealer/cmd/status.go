@@ -0,0 +1,110 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/alibaba/sealer/common"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+var (
+	statusClusterName string
+	statusOutput      string
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "show the apply status of the cluster",
+	Long:  `show the phase of every host and the conditions of the cluster recorded by sealer apply, join, delete and upgrade`,
+	Args:  cobra.NoArgs,
+	Example: `
+show the status of default cluster:
+	sealer status
+show the status of specify cluster as json:
+	sealer status -c my-cluster -o json
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cluster, err := getCluster(statusClusterName)
+		if err != nil {
+			return err
+		}
+
+		switch statusOutput {
+		case "json":
+			data, err := json.MarshalIndent(cluster.Status, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(common.StdOut, string(data))
+		case "table":
+			printHostStatus(cluster)
+			printClusterConditions(cluster)
+		default:
+			return fmt.Errorf("unsupported output format %s, only table and json are supported", statusOutput)
+		}
+		return nil
+	},
+}
+
+func printHostStatus(cluster *v2.Cluster) {
+	table := tablewriter.NewWriter(common.StdOut)
+	table.SetHeader([]string{"HOST", "ROLES", "PHASE", "LAST PHASE", "UPDATED", "ERROR"})
+	listed := map[string]bool{}
+	appendHost := func(ip string, roles []string) {
+		listed[ip] = true
+		hs := cluster.Status.GetHostStatus(ip)
+		if hs == nil {
+			table.Append([]string{ip, strings.Join(roles, ","), string(v2.HostPhasePending), "", "", ""})
+			return
+		}
+		table.Append([]string{ip, strings.Join(roles, ","), string(hs.Phase), string(hs.LastPhase),
+			hs.LastUpdateTime.Format(timeDefaultFormat), hs.LastError})
+	}
+	for _, host := range cluster.Spec.Hosts {
+		for _, ip := range host.IPS {
+			appendHost(ip, host.Roles)
+		}
+	}
+	// hosts which are not in spec, like the registry host.
+	for _, hs := range cluster.Status.Hosts {
+		if !listed[hs.IP] {
+			appendHost(hs.IP, nil)
+		}
+	}
+	table.Render()
+}
+
+func printClusterConditions(cluster *v2.Cluster) {
+	table := tablewriter.NewWriter(common.StdOut)
+	table.SetHeader([]string{"CONDITION", "STATUS", "REASON", "UPDATED", "MESSAGE"})
+	for _, cond := range cluster.Status.Conditions {
+		table.Append([]string{string(cond.Type), string(cond.Status), cond.Reason,
+			cond.LastUpdateTime.Format(timeDefaultFormat), cond.Message})
+	}
+	table.Render()
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVarP(&statusClusterName, "cluster", "c", "", "the name of cluster to show status")
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "table", "output format, table or json")
+}
@@ -20,13 +20,19 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/alibaba/sealer/common"
 	"github.com/alibaba/sealer/pkg/clusterfile"
+	"github.com/alibaba/sealer/pkg/runtime"
+	v2 "github.com/alibaba/sealer/types/api/v2"
 
 	"github.com/alibaba/sealer/apply"
 	"github.com/spf13/cobra"
 )
 
-var upgradeClusterName string
+var (
+	upgradeClusterName string
+	upgradePlan        bool
+)
 
 const (
 	clusterfilepath = `%s/.sealer/%s/Clusterfile`
@@ -34,11 +40,13 @@ const (
 
 // upgradeCmd represents the upgrade command
 var upgradeCmd = &cobra.Command{
-	Use:     "upgrade",
-	Short:   "upgrade your kubernetes cluster",
-	Long:    `sealer upgrade imagename --cluster clustername`,
-	Example: `sealer upgrade kubernetes:v1.19.9 --cluster my-cluster`,
-	Args:    cobra.ExactArgs(1),
+	Use:   "upgrade",
+	Short: "upgrade your kubernetes cluster",
+	Long:  `sealer upgrade imagename --cluster clustername`,
+	Example: `sealer upgrade kubernetes:v1.19.9 --cluster my-cluster
+preview the upgrade steps:
+	sealer upgrade kubernetes:v1.19.9 --cluster my-cluster --plan`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var err error
 		//get clustername
@@ -55,7 +63,9 @@ var upgradeCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		if desiredCluster.Spec.Image == args[0] {
+		// the image of a failed upgrade is saved already, it is upgraded again to finish the rest hosts.
+		if cond := desiredCluster.Status.GetCondition(v2.ConditionUpgraded); desiredCluster.Spec.Image == args[0] &&
+			(cond == nil || cond.Status != v2.ConditionFalse) {
 			return fmt.Errorf("the cluster current image is already %s,choose another one to upgrade", args[0])
 		}
 		desiredCluster.Spec.Image = args[0]
@@ -63,6 +73,14 @@ var upgradeCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if upgradePlan {
+			plan, err := applier.Plan()
+			if err != nil {
+				return err
+			}
+			plan.Print(common.StdOut)
+			return nil
+		}
 		return applier.Apply()
 	},
 }
@@ -70,4 +88,10 @@ var upgradeCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(upgradeCmd)
 	upgradeCmd.Flags().StringVarP(&upgradeClusterName, "cluster", "c", "", "The name of your cluster to upgrade")
+	upgradeCmd.Flags().BoolVar(&upgradePlan, "plan", false, "validate the target version and print the upgrade steps of every host and exit")
+	upgradeCmd.Flags().DurationVar(&runtime.Drain.Timeout, "drain-timeout", runtime.Drain.Timeout, "the timeout of draining a node before upgrading it")
+	upgradeCmd.Flags().IntVar(&runtime.Drain.GracePeriod, "drain-grace-period", runtime.Drain.GracePeriod, "the seconds given to the pods to terminate when draining, the one of pod is used if it is negative")
+	upgradeCmd.Flags().BoolVar(&runtime.Drain.DeleteEmptyDirData, "delete-emptydir-data", false, "drain the nodes even if there are pods using emptyDir, whose data will be deleted")
+	upgradeCmd.Flags().BoolVar(&runtime.Drain.Force, "force-drain", false, "drain the nodes even if there are pods not managed by a controller")
+	upgradeCmd.Flags().DurationVar(&runtime.UpgradeWaitTimeout, "wait-timeout", runtime.UpgradeWaitTimeout, "the max time to wait for an upgraded node to be Ready and the evicted pods to be scheduled")
 }
@@ -0,0 +1,97 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/clusterfile"
+	"github.com/alibaba/sealer/pkg/runtime"
+	v2 "github.com/alibaba/sealer/types/api/v2"
+)
+
+// getCluster return the saved cluster, the default cluster is used if clusterName is empty.
+func getCluster(clusterName string) (*v2.Cluster, error) {
+	var (
+		cluster *v2.Cluster
+		err     error
+	)
+	if clusterName == "" {
+		cluster, err = clusterfile.GetDefaultCluster()
+	} else {
+		cluster, err = clusterfile.GetClusterFromFile(common.GetClusterWorkClusterfile(clusterName))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster: %v", err)
+	}
+	return cluster, nil
+}
+
+// getClusterRuntime return the runtime of the cluster, the default cluster is used if clusterName is empty.
+func getClusterRuntime(clusterName string) (runtime.Interface, error) {
+	cluster, err := getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	clusterFile := clusterfile.NewClusterFile(cluster.GetAnnotationsByKey(common.ClusterfileName))
+	if cluster.GetAnnotationsByKey(common.ClusterfileName) != "" {
+		err = clusterFile.Process()
+		if err != nil {
+			return nil, err
+		}
+	}
+	r, err := runtime.NewDefaultRuntime(cluster, clusterFile.GetKubeadmConfig())
+	if err != nil {
+		return nil, fmt.Errorf("get default runtime failed, %v", err)
+	}
+	return r, nil
+}
+
+// getCertManager return the runtime of the cluster if it manages the certs of cluster.
+func getCertManager(clusterName string) (runtime.CertManager, error) {
+	r, err := getClusterRuntime(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := r.(runtime.CertManager); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("the certs are not managed by the runtime of cluster")
+}
+
+// getEtcdManager return the runtime of the cluster if it manages the etcd of cluster.
+func getEtcdManager(clusterName string) (runtime.EtcdManager, error) {
+	r, err := getClusterRuntime(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := r.(runtime.EtcdManager); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("the etcd is not managed by the runtime of cluster, use the etcd tools of the runtime instead")
+}
+
+// getRegistryManager return the runtime of the cluster if it manages the registries of cluster.
+func getRegistryManager(clusterName string) (runtime.RegistryManager, error) {
+	r, err := getClusterRuntime(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := r.(runtime.RegistryManager); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("the registries are not managed by the runtime of cluster")
+}
@@ -31,6 +31,9 @@ type Flag struct {
 	DNSDomain    string
 	CertPath     string
 	CertEtcdPath string
+	CAValidity   string
+	CertValidity string
+	KeyAlgorithm string
 }
 
 var config *Flag
@@ -41,6 +44,10 @@ var certsCmd = &cobra.Command{
 	Short: "generate kubernetes certes",
 	Long:  `seautil cert --node-ip 192.168.0.2 --node-name master1 --dns-domain aliyun.com --alt-names aliyun.local`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := cert.SetOptions(config.CAValidity, config.CertValidity, config.KeyAlgorithm); err != nil {
+			logger.Error(err)
+			os.Exit(-1)
+		}
 		err := cert.GenerateCert(config.CertPath, config.CertEtcdPath, config.AltNames, config.NodeIP, config.NodeName, config.ServiceCIDR, config.DNSDomain)
 		if err != nil {
 			logger.Error(err)
@@ -60,4 +67,7 @@ func init() {
 	certsCmd.Flags().StringVar(&config.DNSDomain, "dns-domain", "cluster.local", "cluster dns domain")
 	certsCmd.Flags().StringVar(&config.CertPath, "cert-path", "/etc/kubernetes/pki", "kubernetes cert file path")
 	certsCmd.Flags().StringVar(&config.CertEtcdPath, "cert-etcd-path", "/etc/kubernetes/pki/etcd", "kubernetes etcd cert file path")
+	certsCmd.Flags().StringVar(&config.CAValidity, "ca-validity", "", "validity of the generated cas, like 10y, 365d or 8760h, 100y by default")
+	certsCmd.Flags().StringVar(&config.CertValidity, "cert-validity", "", "validity of the generated certs, like 1y, 365d or 8760h, 100y by default")
+	certsCmd.Flags().StringVar(&config.KeyAlgorithm, "key-algorithm", "", "algorithm of the generated keys, RSA-2048, RSA-4096 or ECDSA-P256, RSA-2048 by default")
 }
@@ -41,7 +41,11 @@ var execCmd = &cobra.Command{
 			os.Exit(-1)
 		}
 		hosts := append(cluster.Spec.Masters.IPList, cluster.Spec.Nodes.IPList...)
-		SSH := ssh.NewSSHByCluster(cluster)
+		SSH, err := ssh.NewSSHByCluster(cluster)
+		if err != nil {
+			logger.Error(err)
+			os.Exit(-1)
+		}
 		var wg sync.WaitGroup
 		for _, host := range hosts {
 			wg.Add(1)
@@ -15,20 +15,42 @@
 package cmd
 
 import (
-	"github.com/sealyun/lvscare/care"
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/spf13/cobra"
+
+	"github.com/alibaba/sealer/pkg/ipvs/care"
 )
 
-var Ipvs care.LvsCare
+var (
+	Ipvs         care.LvsCare
+	ipvsInterval int32
+	ipvsTimeout  int32
+)
 
 // ipvsCmd represents the ipvs command
 var ipvsCmd = &cobra.Command{
 	Use:   "ipvs",
 	Short: "seautil create or care local ipvs LB",
 	Long: `create ipvs rules: seautil ipvs --vs 10.1.1.2:6443 --rs 192.168.0.2:6443 --rs 192.168.0.3:6443 --health-path /healthz --health-schem https --run-once
-clean ipvs rules: seautil ipvs clean`,
-	Run: func(cmd *cobra.Command, args []string) {
-		Ipvs.VsAndRsCare()
+care ipvs rules: seautil ipvs --vs 10.1.1.2:6443 --rs 192.168.0.2:6443 --rs 192.168.0.3:6443 --status-addr 127.0.0.1:9081
+the real servers are removed from the rules when their health checks fail and added back after they recover,
+the status of real servers is served on /status and the metrics on /metrics of the status address.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		Ipvs.Interval = time.Duration(ipvsInterval) * time.Second
+		Ipvs.Timeout = time.Duration(ipvsTimeout) * time.Second
+		ctx, cancel := context.WithCancel(context.Background())
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigs
+			cancel()
+		}()
+		return Ipvs.Run(ctx)
 	},
 }
 
@@ -37,9 +59,11 @@ func init() {
 	ipvsCmd.Flags().BoolVar(&Ipvs.RunOnce, "run-once", false, "run once mode")
 	ipvsCmd.Flags().BoolVarP(&Ipvs.Clean, "clean", "c", true, " clean Vip ipvs rule before join node, if Vip has no ipvs rule do nothing.")
 	ipvsCmd.Flags().StringVar(&Ipvs.VirtualServer, "vs", "", "virturl server like 10.54.0.2:6443")
-	ipvsCmd.Flags().StringSliceVar(&Ipvs.RealServer, "rs", []string{}, "virturl server like 192.168.0.2:6443")
+	ipvsCmd.Flags().StringSliceVar(&Ipvs.RealServers, "rs", []string{}, "virturl server like 192.168.0.2:6443")
 
 	ipvsCmd.Flags().StringVar(&Ipvs.HealthPath, "health-path", "/healthz", "health check path")
-	ipvsCmd.Flags().StringVar(&Ipvs.HealthSchem, "health-schem", "https", "health check schem")
-	ipvsCmd.Flags().Int32Var(&Ipvs.Interval, "interval", 5, "health check interval, unit is sec.")
+	ipvsCmd.Flags().StringVar(&Ipvs.HealthScheme, "health-schem", "https", "health check scheme")
+	ipvsCmd.Flags().Int32Var(&ipvsInterval, "interval", 5, "health check interval, unit is sec.")
+	ipvsCmd.Flags().Int32Var(&ipvsTimeout, "timeout", 3, "health check timeout, unit is sec.")
+	ipvsCmd.Flags().StringVar(&Ipvs.StatusAddr, "status-addr", "", "the address serving the status and metrics, like 127.0.0.1:9081, not served if empty.")
 }
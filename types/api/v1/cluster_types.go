@@ -19,11 +19,24 @@ import (
 )
 
 type SSH struct {
-	User     string `json:"user,omitempty"`
-	Passwd   string `json:"passwd,omitempty"`
-	Pk       string `json:"pk,omitempty"`
-	PkPasswd string `json:"pkPasswd,omitempty"`
-	Port     string `json:"port,omitempty"`
+	User         string       `json:"user,omitempty"`
+	Passwd       string       `json:"passwd,omitempty"`
+	PasswdFrom   *ValueSource `json:"passwdFrom,omitempty"`
+	Pk           string       `json:"pk,omitempty"`
+	PkPasswd     string       `json:"pkPasswd,omitempty"`
+	PkPasswdFrom *ValueSource `json:"pkPasswdFrom,omitempty"`
+	Port         string       `json:"port,omitempty"`
+}
+
+// ValueSource reads a secret value from somewhere else instead of writing it in the Clusterfile,
+// only one of the fields can be set.
+type ValueSource struct {
+	// Env is the name of a local environment variable.
+	Env string `json:"env,omitempty"`
+	// File is the path of a local file, trailing newlines are trimmed.
+	File string `json:"file,omitempty"`
+	// Command is a local shell command, its trimmed stdout is the value.
+	Command string `json:"command,omitempty"`
 }
 
 type Network struct {
@@ -92,7 +92,7 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	out.SSH = in.SSH
+	in.SSH.DeepCopyInto(&out.SSH)
 	out.Network = in.Network
 	if in.CertSANS != nil {
 		in, out := &in.CertSANS, &out.CertSANS
@@ -493,6 +493,16 @@ func (in *PluginStatus) DeepCopy() *PluginStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SSH) DeepCopyInto(out *SSH) {
 	*out = *in
+	if in.PasswdFrom != nil {
+		in, out := &in.PasswdFrom, &out.PasswdFrom
+		*out = new(ValueSource)
+		**out = **in
+	}
+	if in.PkPasswdFrom != nil {
+		in, out := &in.PkPasswdFrom, &out.PkPasswdFrom
+		*out = new(ValueSource)
+		**out = **in
+	}
 	return
 }
 
@@ -505,3 +515,19 @@ func (in *SSH) DeepCopy() *SSH {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValueSource) DeepCopyInto(out *ValueSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValueSource.
+func (in *ValueSource) DeepCopy() *ValueSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ValueSource)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 alibaba.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ReasonSucceeded = "Succeeded"
+	ReasonFailed    = "Failed"
+)
+
+func (in *ClusterStatus) GetHostStatus(ip string) *HostStatus {
+	for i := range in.Hosts {
+		if in.Hosts[i].IP == ip {
+			return &in.Hosts[i]
+		}
+	}
+	return nil
+}
+
+func (in *ClusterStatus) getOrAddHostStatus(ip string) *HostStatus {
+	if hs := in.GetHostStatus(ip); hs != nil {
+		return hs
+	}
+	in.Hosts = append(in.Hosts, HostStatus{IP: ip, Phase: HostPhasePending})
+	return &in.Hosts[len(in.Hosts)-1]
+}
+
+// SetHostsPhase mark hosts as finished the given phase and clean the last error of them.
+func (in *ClusterStatus) SetHostsPhase(ips []string, phase HostPhase) {
+	now := metav1.Now()
+	for _, ip := range ips {
+		hs := in.getOrAddHostStatus(ip)
+		hs.Phase = phase
+		hs.LastPhase = phase
+		hs.LastError = ""
+		hs.LastUpdateTime = now
+	}
+}
+
+// SetHostsFailed mark hosts as failed, the last succeeded phase of them is kept.
+func (in *ClusterStatus) SetHostsFailed(ips []string, err error) {
+	if err == nil {
+		return
+	}
+	now := metav1.Now()
+	for _, ip := range ips {
+		hs := in.getOrAddHostStatus(ip)
+		hs.Phase = HostPhaseFailed
+		hs.LastError = err.Error()
+		hs.LastUpdateTime = now
+	}
+}
+
+// RemoveHosts remove the status of hosts which are deleted from cluster.
+func (in *ClusterStatus) RemoveHosts(ips []string) {
+	var hosts []HostStatus
+	for _, hs := range in.Hosts {
+		deleted := false
+		for _, ip := range ips {
+			if hs.IP == ip {
+				deleted = true
+				break
+			}
+		}
+		if !deleted {
+			hosts = append(hosts, hs)
+		}
+	}
+	in.Hosts = hosts
+}
+
+func (in *ClusterStatus) GetCondition(condType ConditionType) *ClusterCondition {
+	for i := range in.Conditions {
+		if in.Conditions[i].Type == condType {
+			return &in.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition set condition status to true if err is nil, otherwise set it to false with err as message.
+func (in *ClusterStatus) SetCondition(condType ConditionType, err error) {
+	cond := in.GetCondition(condType)
+	if cond == nil {
+		in.Conditions = append(in.Conditions, ClusterCondition{Type: condType})
+		cond = &in.Conditions[len(in.Conditions)-1]
+	}
+	cond.LastUpdateTime = metav1.Now()
+	if err != nil {
+		cond.Status = ConditionFalse
+		cond.Reason = ReasonFailed
+		cond.Message = err.Error()
+		return
+	}
+	cond.Status = ConditionTrue
+	cond.Reason = ReasonSucceeded
+	cond.Message = ""
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 alibaba.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestClusterStatus_SetHostsPhase(t *testing.T) {
+	status := &ClusterStatus{}
+	status.SetHostsPhase([]string{"192.168.0.2", "192.168.0.3"}, HostPhaseRootfsMounted)
+	status.SetHostsPhase([]string{"192.168.0.2"}, HostPhaseInitialized)
+	status.SetHostsFailed([]string{"192.168.0.3"}, fmt.Errorf("join failed"))
+
+	tests := []struct {
+		ip        string
+		phase     HostPhase
+		lastPhase HostPhase
+		lastError string
+	}{
+		{"192.168.0.2", HostPhaseInitialized, HostPhaseInitialized, ""},
+		{"192.168.0.3", HostPhaseFailed, HostPhaseRootfsMounted, "join failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			hs := status.GetHostStatus(tt.ip)
+			if hs == nil {
+				t.Fatalf("status of %s not found", tt.ip)
+			}
+			if hs.Phase != tt.phase || hs.LastPhase != tt.lastPhase || hs.LastError != tt.lastError {
+				t.Errorf("GetHostStatus() = %+v, want phase %s last phase %s error %s", hs, tt.phase, tt.lastPhase, tt.lastError)
+			}
+		})
+	}
+
+	status.RemoveHosts([]string{"192.168.0.3"})
+	if len(status.Hosts) != 1 || status.GetHostStatus("192.168.0.3") != nil {
+		t.Errorf("RemoveHosts() = %+v, want only 192.168.0.2 left", status.Hosts)
+	}
+}
+
+func TestClusterStatus_SetCondition(t *testing.T) {
+	status := &ClusterStatus{}
+	status.SetCondition(ConditionJoined, fmt.Errorf("node not ready"))
+	status.SetCondition(ConditionJoined, nil)
+	if len(status.Conditions) != 1 {
+		t.Fatalf("SetCondition() got %d conditions, want 1", len(status.Conditions))
+	}
+	cond := status.GetCondition(ConditionJoined)
+	if cond.Status != ConditionTrue || cond.Reason != ReasonSucceeded || cond.Message != "" {
+		t.Errorf("GetCondition() = %+v, want succeeded condition", cond)
+	}
+}
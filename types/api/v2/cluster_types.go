@@ -50,10 +50,65 @@ type Host struct {
 	Env []string `json:"env,omitempty"`
 }
 
+type HostPhase string
+
+const (
+	HostPhasePending       HostPhase = "Pending"
+	HostPhaseRootfsMounted HostPhase = "RootfsMounted"
+	HostPhaseInitialized   HostPhase = "Initialized"
+	HostPhaseJoined        HostPhase = "Joined"
+	HostPhaseGuestApplied  HostPhase = "GuestApplied"
+	HostPhaseUpgraded      HostPhase = "Upgraded"
+	HostPhaseRolledBack    HostPhase = "RolledBack"
+	HostPhaseFailed        HostPhase = "Failed"
+)
+
+type ConditionType string
+
+const (
+	ConditionImageMounted  ConditionType = "ImageMounted"
+	ConditionConfigApplied ConditionType = "ConfigApplied"
+	ConditionRootfsMounted ConditionType = "RootfsMounted"
+	ConditionInitialized   ConditionType = "Initialized"
+	ConditionJoined        ConditionType = "Joined"
+	ConditionGuestApplied  ConditionType = "GuestApplied"
+	ConditionScaled        ConditionType = "Scaled"
+	ConditionUpgraded      ConditionType = "Upgraded"
+	ConditionRolledBack    ConditionType = "RolledBack"
+	ConditionRepaired      ConditionType = "Repaired"
+	ConditionDeleted       ConditionType = "Deleted"
+)
+
+type ConditionStatus string
+
+const (
+	ConditionTrue  ConditionStatus = "True"
+	ConditionFalse ConditionStatus = "False"
+)
+
+// HostStatus records the last phase a host reached during apply.
+type HostStatus struct {
+	IP    string    `json:"ip"`
+	Phase HostPhase `json:"phase,omitempty"`
+	// LastPhase is the last succeeded phase, it will not be overwritten by a failure.
+	LastPhase      HostPhase   `json:"lastPhase,omitempty"`
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	LastError      string      `json:"lastError,omitempty"`
+}
+
+// ClusterCondition records the result of a cluster level apply step.
+type ClusterCondition struct {
+	Type           ConditionType   `json:"type"`
+	Status         ConditionStatus `json:"status"`
+	LastUpdateTime metav1.Time     `json:"lastUpdateTime,omitempty"`
+	Reason         string          `json:"reason,omitempty"`
+	Message        string          `json:"message,omitempty"`
+}
+
 // ClusterStatus defines the observed state of Cluster
 type ClusterStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	Hosts      []HostStatus       `json:"hosts,omitempty"`
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -77,6 +132,11 @@ func (in *Cluster) GetNodeIPList() []string {
 	return in.GetIPSByRole(common.NODE)
 }
 
+// GetEtcdIPList return the hosts of external etcd cluster, it is empty if etcd is stacked on masters.
+func (in *Cluster) GetEtcdIPList() []string {
+	return in.GetIPSByRole(common.ETCD)
+}
+
 func (in *Cluster) GetMaster0IP() string {
 	if len(in.Spec.Hosts) == 0 {
 		return ""
@@ -17,6 +17,7 @@ package utils
 import (
 	"encoding/hex"
 	"net"
+	"strings"
 )
 
 /*
@@ -86,3 +87,31 @@ func (c CIDR) Mask() string {
 func (c CIDR) CIDR() string {
 	return c.ipnet.String()
 }
+
+// IsDualStackCIDRs return whether the comma separated cidrs have both ipv4 and ipv6 ones,
+// like 100.64.0.0/10,fd00:100:64::/56.
+func IsDualStackCIDRs(cidrs string) bool {
+	var v4, v6 bool
+	for _, s := range strings.Split(cidrs, ",") {
+		c, err := ParseCIDR(strings.TrimSpace(s))
+		if err != nil {
+			return false
+		}
+		if c.IsIPv6() {
+			v6 = true
+		} else {
+			v4 = true
+		}
+	}
+	return v4 && v6
+}
+
+// HasIPv6CIDR return whether any of the comma separated cidrs is an ipv6 one.
+func HasIPv6CIDR(cidrs string) bool {
+	for _, s := range strings.Split(cidrs, ",") {
+		if c, err := ParseCIDR(strings.TrimSpace(s)); err == nil && c.IsIPv6() {
+			return true
+		}
+	}
+	return false
+}
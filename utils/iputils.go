@@ -23,24 +23,41 @@ import (
 
 //use only one
 func GetHostIP(host string) string {
-	if !strings.ContainsRune(host, ':') {
-		return host
-	}
-	return strings.Split(host, ":")[0]
+	ip, _ := GetHostIPAndPortOrDefault(host, "")
+	return ip
 }
 
+// GetHostIPAndPortOrDefault split the host like 192.168.0.2:22, [fd00::2]:22 or fd00::2,
+// the default port is returned if the host has no port.
 func GetHostIPAndPortOrDefault(host, Default string) (string, string) {
-	if !strings.ContainsRune(host, ':') {
+	if net.ParseIP(host) != nil {
 		return host, Default
 	}
-	split := strings.Split(host, ":")
-	return split[0], split[1]
+	ip, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return strings.Trim(host, "[]"), Default
+	}
+	return ip, port
 }
 
 func GetSSHHostIPAndPort(host string) (string, string) {
 	return GetHostIPAndPortOrDefault(host, "22")
 }
 
+// IsIPv6 return whether the ip is an ipv6 address.
+func IsIPv6(ip string) bool {
+	i := net.ParseIP(ip)
+	return i != nil && i.To4() == nil
+}
+
+// GetHostMaskCIDR return the cidr of the single ip, like 10.103.97.2/32 or fd00::2/128.
+func GetHostMaskCIDR(ip string) string {
+	if IsIPv6(ip) {
+		return ip + "/128"
+	}
+	return ip + "/32"
+}
+
 func GetHostIPSlice(hosts []string) (res []string) {
 	for _, ip := range hosts {
 		res = append(res, GetHostIP(ip))
@@ -92,7 +109,7 @@ func GetLocalHostAddresses() (*[]net.Addr, error) {
 
 func IsLocalIP(ip string, addrs *[]net.Addr) bool {
 	for _, address := range *addrs {
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil && ipnet.IP.String() == ip {
+		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.Equal(net.ParseIP(ip)) {
 			return true
 		}
 	}
@@ -105,7 +122,8 @@ func GetLocalIP(master0IP string) (string, error) {
 		return "", err
 	}
 	localAddr := conn.LocalAddr().String()
-	return strings.Split(localAddr, ":")[0], err
+	ip, _, err := net.SplitHostPort(localAddr)
+	return ip, err
 }
 
 func AssemblyIPList(args *string) error {
@@ -134,8 +152,8 @@ func AssemblyIPList(args *string) error {
 }
 
 func CheckIP(i string) bool {
-	if !strings.Contains(i, ":") {
-		return net.ParseIP(i) != nil
+	if net.ParseIP(i) != nil {
+		return true
 	}
 	if _, err := net.ResolveTCPAddr("tcp", i); err != nil {
 		return false
@@ -160,7 +178,10 @@ func DisassembleIPList(arg string) (res []string) {
 }
 
 func IPToInt(v string) *big.Int {
-	ip := net.ParseIP(v).To4()
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return nil
+	}
 	if val := ip.To4(); val != nil {
 		return big.NewInt(0).SetBytes(val)
 	}
@@ -179,5 +200,14 @@ func CompareIP(v1, v2 string) (int, error) {
 
 func NextIP(ip string) net.IP {
 	i := IPToInt(ip)
-	return i.Add(i, big.NewInt(1)).Bytes()
+	b := i.Add(i, big.NewInt(1)).Bytes()
+	// pad the leading zeros dropped by big.Int, like the ones of ::1.
+	size := net.IPv4len
+	if IsIPv6(ip) {
+		size = net.IPv6len
+	}
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return b
 }
@@ -0,0 +1,80 @@
+// Copyright © 2021 Alibaba Group Holding Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+)
+
+func TestGetHostIPAndPortOrDefault(t *testing.T) {
+	tests := []struct {
+		host     string
+		wantIP   string
+		wantPort string
+	}{
+		{"192.168.0.2", "192.168.0.2", "22"},
+		{"192.168.0.2:2222", "192.168.0.2", "2222"},
+		{"fd00::2", "fd00::2", "22"},
+		{"[fd00::2]", "fd00::2", "22"},
+		{"[fd00::2]:2222", "fd00::2", "2222"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			ip, port := GetHostIPAndPortOrDefault(tt.host, "22")
+			if ip != tt.wantIP || port != tt.wantPort {
+				t.Errorf("GetHostIPAndPortOrDefault() = %s, %s, want %s, %s", ip, port, tt.wantIP, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestNextIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.168.0.2", "192.168.0.3"},
+		{"192.168.0.255", "192.168.1.0"},
+		{"::1", "::2"},
+		{"fd00::ffff", "fd00::1:0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := NextIP(tt.ip).String(); got != tt.want {
+				t.Errorf("NextIP() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDualStackCIDRs(t *testing.T) {
+	tests := []struct {
+		cidrs string
+		want  bool
+	}{
+		{"100.64.0.0/10", false},
+		{"fd00:100:64::/56", false},
+		{"100.64.0.0/10,fd00:100:64::/56", true},
+		{"fd00:100:64::/56, 100.64.0.0/10", true},
+		{"100.64.0.0/10,invalid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidrs, func(t *testing.T) {
+			if got := IsDualStackCIDRs(tt.cidrs); got != tt.want {
+				t.Errorf("IsDualStackCIDRs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -45,7 +45,7 @@ const (
 	RouteFailed = "failed"
 )
 
-var ErrNotIPV4 = errors.New("IP addresses are not IPV4 rules")
+var ErrNotSameIPFamily = errors.New("host and gateway are not IP addresses of the same family")
 
 type Route struct {
 	Host    string
@@ -72,8 +72,8 @@ func CheckIsDefaultRoute(host string) error {
 
 // SetRoute ip route add $route
 func (r *Route) SetRoute() error {
-	if !IsIpv4(r.Gateway) || !IsIpv4(r.Host) {
-		return ErrNotIPV4
+	if !isSameIPFamily(r.Host, r.Gateway) {
+		return ErrNotSameIPFamily
 	}
 	err := addRouteGatewayViaHost(r.Host, r.Gateway, 50)
 	if err != nil && !errors.Is(err, os.ErrExist) /* return if route already exist */ {
@@ -97,8 +97,8 @@ func (r *Route) SetRoute() error {
 
 // DelRoute ip route del $route
 func (r *Route) DelRoute() error {
-	if !IsIpv4(r.Gateway) || !IsIpv4(r.Host) {
-		return ErrNotIPV4
+	if !isSameIPFamily(r.Host, r.Gateway) {
+		return ErrNotSameIPFamily
 	}
 	err := delRouteGatewayViaHost(r.Host, r.Gateway)
 	if err != nil && !errors.Is(err, syscall.ESRCH) /* return if route does not exist */ {
@@ -129,11 +129,24 @@ func isDefaultRouteIP(host string) (bool, error) {
 	return netIP.String() == host, nil
 }
 
-func addRouteGatewayViaHost(host, gateway string, priority int) error {
-	Dst := &net.IPNet{
-		IP:   net.ParseIP(host),
-		Mask: net.CIDRMask(32, 32),
+// hostIPNet return the ipnet of the single host, like 10.103.97.2/32 or fd00::2/128.
+func hostIPNet(host string) *net.IPNet {
+	ip := net.ParseIP(host)
+	if ip.To4() != nil {
+		return &net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+func isSameIPFamily(host, gateway string) bool {
+	if IsIpv4(host) && IsIpv4(gateway) {
+		return true
 	}
+	return IsIPv6(host) && IsIPv6(gateway)
+}
+
+func addRouteGatewayViaHost(host, gateway string, priority int) error {
+	Dst := hostIPNet(host)
 	r := &netlink.Route{
 		Dst:      Dst,
 		Gw:       net.ParseIP(gateway),
@@ -143,10 +156,7 @@ func addRouteGatewayViaHost(host, gateway string, priority int) error {
 }
 
 func delRouteGatewayViaHost(host, gateway string) error {
-	Dst := &net.IPNet{
-		IP:   net.ParseIP(host),
-		Mask: net.CIDRMask(32, 32),
-	}
+	Dst := hostIPNet(host)
 	r := &netlink.Route{
 		Dst: Dst,
 		Gw:  net.ParseIP(gateway),
@@ -26,6 +26,7 @@ import (
 
 	"github.com/alibaba/sealer/common"
 	"github.com/alibaba/sealer/logger"
+	"github.com/alibaba/sealer/pkg/secret"
 	v1 "github.com/alibaba/sealer/types/api/v1"
 	v2 "github.com/alibaba/sealer/types/api/v2"
 	"github.com/alibaba/sealer/utils"
@@ -62,7 +63,7 @@ type SSH struct {
 	LocalAddress *[]net.Addr
 }
 
-func NewSSHByCluster(cluster *v1.Cluster) Interface {
+func NewSSHByCluster(cluster *v1.Cluster) (Interface, error) {
 	if cluster.Spec.SSH.User == "" {
 		cluster.Spec.SSH.User = common.ROOT
 	}
@@ -70,14 +71,18 @@ func NewSSHByCluster(cluster *v1.Cluster) Interface {
 	if err != nil {
 		logger.Warn("failed to get local address, %v", err)
 	}
+	sshConfig := cluster.Spec.SSH
+	if err := secret.ResolveSSH(&sshConfig); err != nil {
+		return nil, err
+	}
 	return &SSH{
-		User:         cluster.Spec.SSH.User,
-		Password:     cluster.Spec.SSH.Passwd,
-		Port:         cluster.Spec.SSH.Port,
-		PkFile:       cluster.Spec.SSH.Pk,
-		PkPassword:   cluster.Spec.SSH.PkPasswd,
+		User:         sshConfig.User,
+		Password:     sshConfig.Passwd,
+		Port:         sshConfig.Port,
+		PkFile:       sshConfig.Pk,
+		PkPassword:   sshConfig.PkPasswd,
 		LocalAddress: address,
-	}
+	}, nil
 }
 
 func NewSSHClient(ssh *v1.SSH, isStdout bool) Interface {
@@ -104,16 +109,39 @@ func GetHostSSHClient(hostIP string, cluster *v2.Cluster) (Interface, error) {
 	for _, host := range cluster.Spec.Hosts {
 		for _, ip := range host.IPS {
 			if hostIP == ip {
-				if err := mergo.Merge(&host.SSH, &cluster.Spec.SSH); err != nil {
+				hostSSH, err := mergeHostSSH(host.SSH, cluster.Spec.SSH)
+				if err != nil {
 					return nil, err
 				}
-				return NewSSHClient(&host.SSH, false), nil
+				return NewSSHClient(hostSSH, false), nil
 			}
 		}
 	}
 	return nil, fmt.Errorf("get host ssh client failed, host ip %s not in hosts ip list", hostIP)
 }
 
+// mergeHostSSH resolve the passwords of host and cluster before merging them,
+// so that the password source of host overrides the plain password of cluster.
+func mergeHostSSH(hostSSH, clusterSSH v1.SSH) (*v1.SSH, error) {
+	if err := secret.ResolveSSH(&hostSSH); err != nil {
+		return nil, err
+	}
+	// don't resolve the cluster passwords which are overridden by host.
+	if hostSSH.Passwd != "" {
+		clusterSSH.Passwd, clusterSSH.PasswdFrom = "", nil
+	}
+	if hostSSH.PkPasswd != "" {
+		clusterSSH.PkPasswd, clusterSSH.PkPasswdFrom = "", nil
+	}
+	if err := secret.ResolveSSH(&clusterSSH); err != nil {
+		return nil, err
+	}
+	if err := mergo.Merge(&hostSSH, &clusterSSH); err != nil {
+		return nil, err
+	}
+	return &hostSSH, nil
+}
+
 type Client struct {
 	SSH  Interface
 	Host string
@@ -124,7 +152,10 @@ func NewSSHClientWithCluster(cluster *v1.Cluster) (*Client, error) {
 		ipList []string
 		host   string
 	)
-	sshClient := NewSSHByCluster(cluster)
+	sshClient, err := NewSSHByCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
 	if cluster.Spec.Provider == common.AliCloud {
 		host = cluster.GetAnnotationsByKey(common.Eip)
 		if host == "" {
@@ -135,7 +166,7 @@ func NewSSHClientWithCluster(cluster *v1.Cluster) (*Client, error) {
 		host = cluster.Spec.Masters.IPList[0]
 		ipList = append(ipList, append(cluster.Spec.Masters.IPList, cluster.Spec.Nodes.IPList...)...)
 	}
-	err := WaitSSHReady(sshClient, 6, ipList...)
+	err = WaitSSHReady(sshClient, 6, ipList...)
 	if err != nil {
 		return nil, err
 	}
@@ -172,10 +203,11 @@ func NewStdoutSSHClient(hostIP string, cluster *v2.Cluster) (Interface, error) {
 	for _, host := range cluster.Spec.Hosts {
 		for _, ip := range host.IPS {
 			if hostIP == ip {
-				if err := mergo.Merge(&host.SSH, &cluster.Spec.SSH); err != nil {
+				hostSSH, err := mergeHostSSH(host.SSH, cluster.Spec.SSH)
+				if err != nil {
 					return nil, err
 				}
-				return NewSSHClient(&host.SSH, true), nil
+				return NewSSHClient(hostSSH, true), nil
 			}
 		}
 	}
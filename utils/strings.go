@@ -39,7 +39,7 @@ func NotInIPList(key string, slice []string) bool {
 		if s == "" {
 			continue
 		}
-		if key == strings.Split(s, ":")[0] {
+		if key == GetHostIP(s) {
 			return false
 		}
 	}
@@ -27,6 +27,8 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"github.com/alibaba/sealer/common"
+	"github.com/alibaba/sealer/pkg/secret"
+	v2 "github.com/alibaba/sealer/types/api/v2"
 )
 
 func UnmarshalYamlFile(file string, obj interface{}) error {
@@ -60,6 +62,12 @@ func SaveClusterInfoToFile(cluster runtime.Object, clusterName string) error {
 	if err != nil {
 		return fmt.Errorf("mkdir failed %s %v", fileName, err)
 	}
+	// encrypt the secrets at rest once the local secret key exists.
+	if c, ok := cluster.(*v2.Cluster); ok && secret.KeyExists() {
+		if cluster, err = secret.EncryptCluster(c); err != nil {
+			return fmt.Errorf("failed to encrypt cluster file: %v", err)
+		}
+	}
 	err = MarshalYamlToFile(fileName, cluster)
 	if err != nil {
 		return fmt.Errorf("marshal cluster file failed %v", err)